@@ -0,0 +1,204 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/archive"
+	"github.com/eiffel-community/eiffel-goer/internal/integrity"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/pkg/application"
+)
+
+// Exit codes of the subcommands.
+const (
+	exitOK       = 0
+	exitProblems = 1
+	exitFailed   = 2
+)
+
+// command is a subcommand run instead of serving the API. It defines the
+// subcommand's flags on its own flag set and returns the function running it.
+type command func(flags *flag.FlagSet) runner
+
+// runner runs a subcommand and returns the exit code.
+type runner func(ctx context.Context, app *application.Application, logger *log.Entry) int
+
+// commands are the subcommands by name.
+var commands = map[string]command{
+	"check-links": checkLinks,
+	"dedup":       dedup,
+	"export":      exportEvents,
+	"import":      importEvents,
+}
+
+// runCommand runs a subcommand and stops the application afterwards.
+// All subcommands need a database.
+func runCommand(ctx context.Context, run runner, app *application.Application, logger *log.Entry) int {
+	if app.Database == nil {
+		logger.Error("No database to run the command against, set -connectionstring or CONNECTION_STRING")
+		return exitFailed
+	}
+	defer func() {
+		if err := app.Stop(ctx); err != nil {
+			logger.Errorf("Error stopping application: %s", err)
+		}
+	}()
+	return run(ctx, app, logger)
+}
+
+// writeReport writes a report to stdout as JSON.
+func writeReport(report interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
+
+// checkLinks checks the integrity of all events in the database and writes
+// the report, listing every problem found, to stdout.
+func checkLinks(*flag.FlagSet) runner {
+	return func(ctx context.Context, app *application.Application, logger *log.Entry) int {
+		report, err := integrity.Check(ctx, app.Database, -1)
+		if err == nil {
+			err = writeReport(report)
+		}
+		if err != nil {
+			logger.Error(err)
+			return exitFailed
+		}
+		if !report.OK() {
+			return exitProblems
+		}
+		return exitOK
+	}
+}
+
+// dedup reports the events stored more than once, deleting the identical
+// copies if -clean is given, and writes the report to stdout.
+func dedup(flags *flag.FlagSet) runner {
+	clean := flags.Bool("clean", false, "Delete the copies of events identical to the first stored copy.")
+	return func(ctx context.Context, app *application.Application, logger *log.Entry) int {
+		report, err := integrity.Deduplicate(ctx, app.Database, *clean)
+		if err == nil {
+			err = writeReport(report)
+		}
+		if err != nil {
+			logger.Error(err)
+			return exitFailed
+		}
+		if !report.OK() {
+			return exitProblems
+		}
+		return exitOK
+	}
+}
+
+// exportEvents writes the events matching -filter, -since and -until to -file as NDJSON.
+func exportEvents(flags *flag.FlagSet) runner {
+	file := flags.String("file", "-", "The NDJSON file to write, - for stdout.")
+	compress := flags.Bool("gzip", false, "Gzip the events. Implied by a -file ending with .gz.")
+	filter := flags.String("filter", "", "Only export the events matching the conditions, e.g. meta.type=EiffelArtifactCreatedEvent.")
+	since := flags.String("since", "", "Only export events from this time on, as RFC 3339, a date or milliseconds since the epoch.")
+	until := flags.String("until", "", "Only export events from before this time.")
+	return func(ctx context.Context, app *application.Application, logger *log.Entry) int {
+		conditions, err := query.ParseConditions(*filter)
+		if err != nil {
+			logger.Errorf("Invalid filter: %s", err)
+			return exitFailed
+		}
+		timeConditions, err := archive.TimeConditions(*since, *until)
+		if err != nil {
+			logger.Error(err)
+			return exitFailed
+		}
+		conditions = append(conditions, timeConditions...)
+
+		var output io.Writer = os.Stdout
+		if *file != "-" {
+			f, err := os.Create(filepath.Clean(*file))
+			if err != nil {
+				logger.Error(err)
+				return exitFailed
+			}
+			defer func() {
+				if err := f.Close(); err != nil {
+					logger.Error(err)
+				}
+			}()
+			output = f
+		}
+		writer := archive.NewWriter(output, *compress || strings.HasSuffix(*file, ".gz"))
+		err = archive.Export(ctx, app.Database, conditions, writer)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			logger.Error(err)
+			return exitFailed
+		}
+		logger.Infof("Exported %d events", writer.Count)
+		return exitOK
+	}
+}
+
+// importEvents ingests the events in the NDJSON -file, which may be gzipped,
+// and writes the report to stdout. Events whose IDs are already stored are
+// handled according to the duplicate policy.
+func importEvents(flags *flag.FlagSet) runner {
+	file := flags.String("file", "-", "The NDJSON file to read, - for stdin. It may be gzipped.")
+	return func(ctx context.Context, app *application.Application, logger *log.Entry) int {
+		ingester, err := app.NewIngester()
+		if err != nil {
+			logger.Error(err)
+			return exitFailed
+		}
+		var input io.Reader = os.Stdin
+		if *file != "-" {
+			f, err := os.Open(filepath.Clean(*file))
+			if err != nil {
+				logger.Error(err)
+				return exitFailed
+			}
+			defer func() {
+				if err := f.Close(); err != nil {
+					logger.Error(err)
+				}
+			}()
+			input = f
+		}
+		report, err := archive.Import(ctx, ingester, input)
+		if reportErr := writeReport(report); err == nil {
+			err = reportErr
+		}
+		if err != nil {
+			logger.Error(err)
+			return exitFailed
+		}
+		if !report.OK() {
+			return exitProblems
+		}
+		return exitOK
+	}
+}
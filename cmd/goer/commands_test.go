@@ -0,0 +1,117 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/pkg/application"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+const (
+	build = `{"meta": {"id": "71000000-0000-4000-8000-000000000001", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650361}, "data": {"name": "build"}, "links": []}`
+	check = `{"meta": {"id": "71000000-0000-4000-8000-000000000002", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650362}, "data": {"name": "test"}, "links": []}`
+)
+
+// Test that each subcommand only accepts its own flags and the configuration flags.
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		command    bool
+		connection string
+		err        bool
+	}{
+		{name: "Serve", args: []string{"-connectionstring=mongodb://db/goer"}, connection: "mongodb://db/goer"},
+		{name: "ServeWithCommandFlag", args: []string{"-clean"}, err: true},
+		{name: "Dedup", args: []string{"dedup", "-clean", "-connectionstring=mongodb://db/goer"}, command: true, connection: "mongodb://db/goer"},
+		{name: "ExportFlags", args: []string{"export", "-file=events.ndjson", "-gzip", "-filter=meta.type=EiffelArtifactCreatedEvent", "-since=2021-08-20", "-until=1629449650362"}, command: true},
+		{name: "ImportWithExportFlag", args: []string{"import", "-gzip"}, err: true},
+		{name: "ExportWithDedupFlag", args: []string{"export", "-clean"}, err: true},
+		{name: "UnknownCommand", args: []string{"frobnicate"}, err: true},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg, run, err := parseArgs(testCase.args)
+			if testCase.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.command, run != nil)
+			assert.Equal(t, testCase.connection, cfg.DBConnectionString())
+		})
+	}
+	_, _, err := parseArgs([]string{"export", "-h"})
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+}
+
+// runArgs parses the arguments of a subcommand and runs it against the database.
+func runArgs(t *testing.T, db drivers.Database, args ...string) int {
+	t.Helper()
+	cfg, run, err := parseArgs(args)
+	require.NoError(t, err)
+	require.NotNil(t, run)
+	logger := &log.Entry{Logger: log.New()}
+	app := &application.Application{Database: db, Config: cfg, Logger: logger}
+	return run(context.Background(), app, logger)
+}
+
+// Test that the export flags select the events and file that import reads.
+func TestExportImport(t *testing.T) {
+	source := test.NewMemoryDatabase()
+	for _, data := range []string{build, check} {
+		event, err := ingest.Decode([]byte(data))
+		require.NoError(t, err)
+		require.NoError(t, source.InsertEvent(context.Background(), event))
+	}
+	file := filepath.Join(t.TempDir(), "events.ndjson.gz")
+	assert.Equal(t, exitOK, runArgs(t, source, "export", "-file="+file, "-since=1629449650362"))
+
+	target := test.NewMemoryDatabase()
+	assert.Equal(t, exitOK, runArgs(t, target, "import", "-file", file))
+	_, err := target.GetEventByID(context.Background(), "71000000-0000-4000-8000-000000000002")
+	assert.NoError(t, err)
+	_, err = target.GetEventByID(context.Background(), "71000000-0000-4000-8000-000000000001")
+	assert.True(t, errors.Is(err, drivers.ErrNotFound))
+
+	assert.Equal(t, exitFailed, runArgs(t, target, "import", "-file", filepath.Join(t.TempDir(), "missing.ndjson")))
+	assert.Equal(t, exitFailed, runArgs(t, source, "export", "-since=yesterday"))
+}
+
+// Test that subcommands fail instead of running without a database.
+func TestRunCommandWithoutDatabase(t *testing.T) {
+	for name := range commands {
+		t.Run(name, func(t *testing.T) {
+			cfg, run, err := parseArgs([]string{name})
+			require.NoError(t, err)
+			logger := &log.Entry{Logger: log.New()}
+			app := &application.Application{Config: cfg, Logger: logger}
+			assert.Equal(t, exitFailed, runCommand(context.Background(), run, app, logger))
+		})
+	}
+}
@@ -17,7 +17,11 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/eiffel-community/eiffel-goer/internal/config"
 	"github.com/eiffel-community/eiffel-goer/internal/logger"
@@ -29,9 +33,53 @@ import (
 // populated via linker options when building with govvv.
 var GitSummary = "(unknown)"
 
-// Start up the Goer application.
+// popCommand splits the program arguments into the subcommand, if any,
+// and the flags following it.
+func popCommand(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "", args
+	}
+	return args[0], args[1:]
+}
+
+// parseArgs parses the program arguments, which are either the configuration
+// flags to serve the API or a subcommand followed by its own flags and the
+// configuration flags. The runner is nil unless a subcommand is given.
+func parseArgs(args []string) (config.Config, runner, error) {
+	name, args := popCommand(args)
+	var run runner
+	flags := flag.NewFlagSet("goer", flag.ContinueOnError)
+	if name != "" {
+		cmd, ok := commands[name]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown command %q", name)
+		}
+		flags = flag.NewFlagSet("goer "+name, flag.ContinueOnError)
+		run = cmd(flags)
+	}
+	cfg, err := config.Parse(flags, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, run, nil
+}
+
+// Start up the Goer application, or run one of its subcommands:
+//
+//	check-links  Check the links and IDs of all events and report any problems.
+//	dedup        Report the events stored more than once, with -clean deleting
+//	             the copies identical to the first stored one.
+//	export       Write the events matching -filter, -since and -until to
+//	             -file as NDJSON, gzipped with -gzip.
+//	import       Store the events in the NDJSON -file, which may be gzipped.
 func main() {
-	cfg := config.Get()
+	cfg, run, err := parseArgs(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(exitOK)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
 	ctx := context.Background()
 	if err := logger.Setup(cfg); err != nil {
 		log.Fatal(err)
@@ -49,8 +97,27 @@ func main() {
 	if err != nil {
 		log.Panic(err)
 	}
+	if err := app.LoadVerifier(); err != nil {
+		log.Panic(err)
+	}
+	if run != nil {
+		os.Exit(runCommand(ctx, run, app, log))
+	}
 
+	if err := app.LoadWebhook(); err != nil {
+		log.Panic(err)
+	}
 	app.LoadV1Routes()
+	if err := app.LoadGraphQLRoutes(); err != nil {
+		log.Panic(err)
+	}
+
+	if err := app.StartConsumer(ctx); err != nil {
+		log.Panic(err)
+	}
+	if err := app.StartRetention(ctx); err != nil {
+		log.Panic(err)
+	}
 
 	log.Debug("Starting up.")
 	err = app.Start(ctx)
@@ -0,0 +1,215 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// archive exports events to, and imports events from, newline delimited
+// JSON (NDJSON) with one event per line, optionally gzipped, for backups
+// and migrations between event repositories.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// MaxLineSize is the maximum size in bytes of an imported event.
+const MaxLineSize = 16 << 20
+
+// gzipMagic starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// Writer writes events as NDJSON.
+type Writer struct {
+	gzip    *gzip.Writer
+	encoder *json.Encoder
+	// Count is the number of events written.
+	Count int
+}
+
+// NewWriter returns a writer of events to w, gzipping them if compress is true.
+func NewWriter(w io.Writer, compress bool) *Writer {
+	writer := &Writer{}
+	if compress {
+		writer.gzip = gzip.NewWriter(w)
+		w = writer.gzip
+	}
+	writer.encoder = json.NewEncoder(w)
+	writer.encoder.SetEscapeHTML(false)
+	return writer
+}
+
+// Write writes an event on a line of its own.
+func (w *Writer) Write(event drivers.EiffelEvent) error {
+	if err := w.encoder.Encode(event); err != nil {
+		return err
+	}
+	w.Count++
+	return nil
+}
+
+// Close flushes the gzip stream, if any. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	if w.gzip == nil {
+		return nil
+	}
+	return w.gzip.Close()
+}
+
+// Export writes the events matching the conditions to the writer.
+func Export(ctx context.Context, db drivers.Database, conditions []query.Condition, w *Writer) error {
+	stream, _, err := db.GetEvents(ctx, requests.MultipleEventsRequest{
+		Unpaged:    true,
+		Count:      requests.CountNone,
+		Conditions: conditions,
+	})
+	if err != nil {
+		return err
+	}
+	for stream.Next(ctx) {
+		if err = w.Write(stream.Event()); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = stream.Err()
+	}
+	if closeErr := stream.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// TimeConditions returns the conditions selecting events with meta.time in
+// [since, until). Both are optional and are either RFC 3339 timestamps, dates
+// like 2006-01-02 in UTC or milliseconds since the epoch like meta.time.
+func TimeConditions(since string, until string) ([]query.Condition, error) {
+	var conditions []query.Condition
+	for _, bound := range []struct {
+		value string
+		op    string
+	}{{since, ">="}, {until, "<"}} {
+		if bound.value == "" {
+			continue
+		}
+		millis, err := parseTime(bound.value)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, query.Condition{
+			Field:    "meta.time",
+			Op:       bound.op,
+			Value:    strconv.FormatInt(millis, 10),
+			TypeConv: "int",
+		})
+	}
+	return conditions, nil
+}
+
+// parseTime parses a time for TimeConditions into milliseconds since the epoch.
+func parseTime(value string) (int64, error) {
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return millis, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UnixNano() / int64(time.Millisecond), nil
+		}
+	}
+	return 0, fmt.Errorf("invalid time %q, expected RFC 3339, a date or milliseconds since the epoch", value)
+}
+
+// NewReader returns a reader of the content of r, decompressing it if it's gzipped.
+func NewReader(r io.Reader) (io.Reader, error) {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return buffered, nil
+	}
+	decompressed, err := gzip.NewReader(buffered)
+	if err != nil {
+		return nil, err
+	}
+	return decompressed, nil
+}
+
+// ImportReport is the result of importing events.
+type ImportReport struct {
+	Events     int         `json:"events"`
+	Stored     int         `json:"stored"`
+	Duplicates int         `json:"duplicates"`
+	Conflicts  int         `json:"conflicts"`
+	Rejected   []Rejection `json:"rejected"`
+}
+
+// Rejection is an event that the ingester rejected.
+type Rejection struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// OK returns true if no events were rejected.
+func (r ImportReport) OK() bool {
+	return len(r.Rejected) == 0
+}
+
+// Import ingests the events read from NDJSON, which may be gzipped. Events
+// that are invalid or rejected by the ingester are reported while other
+// errors, e.g. failing to store an event, stop the import.
+func Import(ctx context.Context, ingester *ingest.Ingester, r io.Reader) (ImportReport, error) {
+	report := ImportReport{Rejected: []Rejection{}}
+	reader, err := NewReader(r)
+	if err != nil {
+		return report, err
+	}
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, MaxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		report.Events++
+		result, err := ingester.IngestJSON(ctx, data)
+		switch {
+		case ingest.Rejected(err):
+			report.Rejected = append(report.Rejected, Rejection{Line: line, Error: err.Error()})
+		case err != nil:
+			return report, fmt.Errorf("line %d: %w", line, err)
+		case result == ingest.ResultStored:
+			report.Stored++
+		case result == ingest.ResultDuplicate:
+			report.Duplicates++
+		case result == ingest.ResultConflict:
+			report.Conflicts++
+		}
+	}
+	return report, scanner.Err()
+}
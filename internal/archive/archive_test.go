@@ -0,0 +1,122 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package archive
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+const (
+	build = `{"meta": {"id": "71000000-0000-4000-8000-000000000001", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650361}, "data": {"name": "build"}, "links": []}`
+	check = `{"meta": {"id": "71000000-0000-4000-8000-000000000002", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650362}, "data": {"name": "test"}, "links": []}`
+)
+
+// newIngester returns an ingester for a new in-memory database.
+func newIngester(t *testing.T) (*ingest.Ingester, *test.MemoryDatabase) {
+	t.Helper()
+	db := test.NewMemoryDatabase()
+	ingester, err := ingest.New(db, ingest.PolicyIgnoreIdentical, &log.Entry{Logger: log.New()})
+	require.NoError(t, err)
+	return ingester, db
+}
+
+// Test that exported events, compressed or not, are imported unchanged.
+func TestExportImport(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		source, sourceDB := newIngester(t)
+		report, err := Import(context.Background(), source, strings.NewReader(build+"\n"+check+"\n"))
+		require.NoError(t, err)
+		require.Equal(t, 2, report.Stored)
+
+		var buf bytes.Buffer
+		writer := NewWriter(&buf, compress)
+		require.NoError(t, Export(context.Background(), sourceDB, nil, writer))
+		require.NoError(t, writer.Close())
+		assert.Equal(t, 2, writer.Count)
+
+		target, targetDB := newIngester(t)
+		report, err = Import(context.Background(), target, &buf)
+		require.NoError(t, err)
+		assert.Equal(t, ImportReport{Events: 2, Stored: 2, Rejected: []Rejection{}}, report)
+		assert.Equal(t, sourceDB.Events, targetDB.Events)
+	}
+}
+
+// Test that only the events matching the conditions are exported.
+func TestExportConditions(t *testing.T) {
+	ingester, db := newIngester(t)
+	_, err := Import(context.Background(), ingester, strings.NewReader(build+"\n"+check))
+	require.NoError(t, err)
+
+	conditions, err := TimeConditions("1629449650362", "")
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, Export(context.Background(), db, conditions, NewWriter(&buf, false)))
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Contains(t, buf.String(), "71000000-0000-4000-8000-000000000002")
+
+	buf.Reset()
+	conditions = []query.Condition{{Field: "data.name", Op: "=", Value: "build"}}
+	require.NoError(t, Export(context.Background(), db, conditions, NewWriter(&buf, false)))
+	assert.Contains(t, buf.String(), "71000000-0000-4000-8000-000000000001")
+	assert.NotContains(t, buf.String(), "71000000-0000-4000-8000-000000000002")
+}
+
+// Test that rejected events are reported by line and that duplicates and blank lines are skipped.
+func TestImportReport(t *testing.T) {
+	ingester, db := newIngester(t)
+	input := build + "\n\n" + `{"meta": {"type": "EiffelUnknownEvent"}}` + "\n" + build + "\n"
+	report, err := Import(context.Background(), ingester, strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Events)
+	assert.Equal(t, 1, report.Stored)
+	assert.Equal(t, 1, report.Duplicates)
+	require.Len(t, report.Rejected, 1)
+	assert.Equal(t, 3, report.Rejected[0].Line)
+	assert.False(t, report.OK())
+	assert.Len(t, db.Events, 1)
+}
+
+// Test that times are parsed as milliseconds, RFC 3339 timestamps or dates.
+func TestTimeConditions(t *testing.T) {
+	conditions, err := TimeConditions("2021-08-20", "2021-08-20T10:00:00Z")
+	require.NoError(t, err)
+	assert.Equal(t, []query.Condition{
+		{Field: "meta.time", Op: ">=", Value: "1629417600000", TypeConv: "int"},
+		{Field: "meta.time", Op: "<", Value: "1629453600000", TypeConv: "int"},
+	}, conditions)
+	event := drivers.EiffelEvent{"meta": map[string]interface{}{"time": int64(1629449650361)}}
+	assert.True(t, event.Matches(conditions))
+
+	conditions, err = TimeConditions("", "")
+	assert.NoError(t, err)
+	assert.Empty(t, conditions)
+
+	_, err = TimeConditions("yesterday", "")
+	assert.Error(t, err)
+}
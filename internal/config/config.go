@@ -18,6 +18,29 @@ package config
 import (
 	"flag"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDuplicatePolicy is the default policy for ingested events whose
+// IDs are already stored.
+const defaultDuplicatePolicy = "ignore-identical"
+
+// defaultDBWorkers is the default number of concurrent database requests
+// to run when a query spans multiple collections.
+const defaultDBWorkers = 8
+
+// defaultRetentionInterval is the default time between applying the
+// retention policy.
+const defaultRetentionInterval = 24 * time.Hour
+
+// Defaults for consuming events from an AMQP broker. The amq.topic exchange
+// exists on every RabbitMQ broker.
+const (
+	defaultAMQPExchange = "amq.topic"
+	defaultAMQPQueue    = "goer"
+	defaultAMQPBindings = "#"
 )
 
 type Config interface {
@@ -25,26 +48,134 @@ type Config interface {
 	APIPort() string
 	LogLevel() string
 	LogFilePath() string
+	IDIndexCollection() string
+	DBWorkers() int
+	EnableGraphQL() bool
+	CreateIndexes() bool
+	EnableAdmin() bool
+	AdminToken() string
+	DuplicatePolicy() string
+	PublicKeyFiles() []string
+	JWKSFile() string
+	RequireSignatures() bool
+	AMQPURL() string
+	AMQPExchange() string
+	AMQPQueue() string
+	AMQPBindings() []string
+	WebhookSecretsFile() string
+	RetentionPolicy() string
+	ArchiveDir() string
+	RetentionInterval() time.Duration
+	AllowedOrigins() []string
 }
 
 type Cfg struct {
-	connectionString string
-	apiPort          string
-	logLevel         string
-	logFilePath      string
+	connectionString  string
+	apiPort           string
+	logLevel          string
+	logFilePath       string
+	idIndexCollection string
+	dbWorkers         int
+	enableGraphQL     bool
+	createIndexes     bool
+	enableAdmin       bool
+	adminToken        string
+	duplicatePolicy   string
+	publicKeyFiles    string
+	jwksFile          string
+	requireSignatures bool
+	amqpURL           string
+	amqpExchange      string
+	amqpQueue         string
+	amqpBindings      string
+	webhookSecrets    string
+	retentionPolicy   string
+	archiveDir        string
+	retentionInterval time.Duration
+	allowedOrigins    string
 }
 
 // Get parses input parameters to program and return a config with them set.
 func Get() Config {
+	conf, _ := Parse(flag.CommandLine, os.Args[1:])
+	return conf
+}
+
+// Parse defines the configuration flags on the flag set, along with any
+// flags already defined there, and parses the arguments into a config.
+func Parse(flags *flag.FlagSet, args []string) (Config, error) {
 	conf := &Cfg{}
 
-	flag.StringVar(&conf.connectionString, "connectionstring", os.Getenv("CONNECTION_STRING"), "Database connection string.")
-	flag.StringVar(&conf.apiPort, "apiport", os.Getenv("API_PORT"), "API port.")
-	flag.StringVar(&conf.logLevel, "loglevel", os.Getenv("LOGLEVEL"), "Log level (TRACE, DEBUG, INFO, WARNING, ERROR, FATAL, PANIC).")
-	flag.StringVar(&conf.logFilePath, "logfilepath", os.Getenv("LOG_FILE_PATH"), "Path, including filename, for the log files to create.")
+	flags.StringVar(&conf.connectionString, "connectionstring", os.Getenv("CONNECTION_STRING"), "Database connection string.")
+	flags.StringVar(&conf.apiPort, "apiport", os.Getenv("API_PORT"), "API port.")
+	flags.StringVar(&conf.logLevel, "loglevel", os.Getenv("LOGLEVEL"), "Log level (TRACE, DEBUG, INFO, WARNING, ERROR, FATAL, PANIC).")
+	flags.StringVar(&conf.logFilePath, "logfilepath", os.Getenv("LOG_FILE_PATH"), "Path, including filename, for the log files to create.")
+	flags.StringVar(&conf.idIndexCollection, "idindexcollection", os.Getenv("ID_INDEX_COLLECTION"), "Name of the collection mapping event IDs to the collections they are stored in.")
+	flags.IntVar(&conf.dbWorkers, "dbworkers", intFromEnv("DB_WORKERS", defaultDBWorkers), "Maximum number of concurrent database requests per API request.")
+	flags.BoolVar(&conf.enableGraphQL, "enablegraphql", boolFromEnv("ENABLE_GRAPHQL", false), "Serve the GraphQL API on /graphql.")
+	flags.BoolVar(&conf.createIndexes, "createindexes", boolFromEnv("CREATE_INDEXES", false), "Create the database indexes needed for efficient link lookups.")
+	flags.BoolVar(&conf.enableAdmin, "enableadmin", boolFromEnv("ENABLE_ADMIN", false), "Serve the administrative endpoints under /v1/admin.")
+	flags.StringVar(&conf.allowedOrigins, "allowedorigins", os.Getenv("ALLOWED_ORIGINS"), "Comma separated origins of web pages allowed to subscribe to events over WebSocket, or * for any.")
+	flags.StringVar(&conf.adminToken, "admintoken", os.Getenv("ADMIN_TOKEN"), "Bearer token required by the administrative endpoints that delete events. They're not served if empty.")
+	flags.StringVar(&conf.duplicatePolicy, "duplicatepolicy", os.Getenv("DUPLICATE_POLICY"), "What to do with ingested events whose IDs are already stored (reject, ignore-identical, flag-conflicts).")
+	flags.StringVar(&conf.publicKeyFiles, "publickeys", os.Getenv("PUBLIC_KEYS"), "Comma separated PEM files with public keys to verify event signatures against.")
+	flags.StringVar(&conf.jwksFile, "jwksfile", os.Getenv("JWKS_FILE"), "JSON Web Key Set file with public keys to verify event signatures against.")
+	flags.BoolVar(&conf.requireSignatures, "requiresignatures", boolFromEnv("REQUIRE_SIGNATURES", false), "Reject ingested events whose signatures can't be verified.")
 
-	flag.Parse()
-	return conf
+	flags.StringVar(&conf.amqpURL, "amqpurl", os.Getenv("AMQP_URL"), "URL of an AMQP broker to consume events from. Consuming is disabled if empty.")
+	flags.StringVar(&conf.amqpExchange, "amqpexchange", os.Getenv("AMQP_EXCHANGE"), "Exchange to consume events from.")
+	flags.StringVar(&conf.amqpQueue, "amqpqueue", os.Getenv("AMQP_QUEUE"), "Durable queue to consume events through.")
+	flags.StringVar(&conf.amqpBindings, "amqpbindings", os.Getenv("AMQP_BINDINGS"), "Comma separated routing keys to bind the queue to the exchange with.")
+	flags.StringVar(&conf.webhookSecrets, "webhooksecretsfile", os.Getenv("WEBHOOK_SECRETS_FILE"), "JSON file mapping producers to the secrets their webhook requests are signed with. The webhook is disabled if empty.")
+	flags.StringVar(&conf.retentionPolicy, "retention", os.Getenv("RETENTION"), "Comma separated rules for how long to keep events of each type, e.g. EiffelTestCaseStartedEvent=90d,*=forever. Events are kept forever if empty.")
+	flags.StringVar(&conf.archiveDir, "archivedir", os.Getenv("ARCHIVE_DIR"), "Directory to archive expired events in before they're deleted.")
+	flags.DurationVar(&conf.retentionInterval, "retentioninterval", durationFromEnv("RETENTION_INTERVAL", defaultRetentionInterval), "Time between applying the retention rules.")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	return conf, nil
+}
+
+// intFromEnv returns the integer value of an environment variable, or
+// a default value if the variable is unset or not a valid integer.
+func intFromEnv(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// boolFromEnv returns the boolean value of an environment variable, or
+// a default value if the variable is unset or not a valid boolean.
+func boolFromEnv(name string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// durationFromEnv returns the duration value of an environment variable, or
+// a default value if the variable is unset or not a valid duration.
+func durationFromEnv(name string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// splitList splits a comma separated list, ignoring empty entries.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // DBConnectionString returns the connection string for a database.
@@ -69,3 +200,135 @@ func (c *Cfg) LogLevel() string {
 func (c *Cfg) LogFilePath() string {
 	return c.logFilePath
 }
+
+// IDIndexCollection returns the name of the database collection that maps event IDs
+// to the collections the events are stored in. An empty string disables the index.
+func (c *Cfg) IDIndexCollection() string {
+	return c.idIndexCollection
+}
+
+// DBWorkers returns the maximum number of concurrent database requests to
+// run for a single API request. Default is 8.
+func (c *Cfg) DBWorkers() int {
+	if c.dbWorkers < 1 {
+		c.dbWorkers = defaultDBWorkers
+	}
+	return c.dbWorkers
+}
+
+// EnableGraphQL returns true if the GraphQL API should be served.
+func (c *Cfg) EnableGraphQL() bool {
+	return c.enableGraphQL
+}
+
+// CreateIndexes returns true if Goer should create the database indexes it
+// benefits from, e.g. on links.target, instead of leaving that to the administrator.
+func (c *Cfg) CreateIndexes() bool {
+	return c.createIndexes
+}
+
+// EnableAdmin returns true if the administrative endpoints, which may scan
+// the whole database, should be served.
+func (c *Cfg) EnableAdmin() bool {
+	return c.enableAdmin
+}
+
+// AdminToken returns the bearer token that requests to the administrative
+// endpoints deleting events must carry. Those endpoints aren't served if
+// it's empty.
+func (c *Cfg) AdminToken() string {
+	return c.adminToken
+}
+
+// DuplicatePolicy returns the name of the policy for ingested events whose
+// IDs are already stored. Default is ignore-identical.
+func (c *Cfg) DuplicatePolicy() string {
+	if c.duplicatePolicy == "" {
+		c.duplicatePolicy = defaultDuplicatePolicy
+	}
+	return c.duplicatePolicy
+}
+
+// PublicKeyFiles returns the paths of the PEM files with the public keys
+// that event signatures are verified against.
+func (c *Cfg) PublicKeyFiles() []string {
+	return splitList(c.publicKeyFiles)
+}
+
+// JWKSFile returns the path of the JSON Web Key Set file with the public keys
+// that event signatures are verified against, or an empty string if none.
+func (c *Cfg) JWKSFile() string {
+	return c.jwksFile
+}
+
+// RequireSignatures returns true if ingested events must have signatures
+// that can be verified.
+func (c *Cfg) RequireSignatures() bool {
+	return c.requireSignatures
+}
+
+// AMQPURL returns the URL of the AMQP broker to consume events from, or an
+// empty string if events shouldn't be consumed.
+func (c *Cfg) AMQPURL() string {
+	return c.amqpURL
+}
+
+// AMQPExchange returns the name of the exchange to consume events from.
+// Default is amq.topic.
+func (c *Cfg) AMQPExchange() string {
+	if c.amqpExchange == "" {
+		c.amqpExchange = defaultAMQPExchange
+	}
+	return c.amqpExchange
+}
+
+// AMQPQueue returns the name of the queue to consume events through.
+// Default is goer.
+func (c *Cfg) AMQPQueue() string {
+	if c.amqpQueue == "" {
+		c.amqpQueue = defaultAMQPQueue
+	}
+	return c.amqpQueue
+}
+
+// AMQPBindings returns the routing keys to bind the queue to the exchange
+// with. Default is #, i.e. all events.
+func (c *Cfg) AMQPBindings() []string {
+	if bindings := splitList(c.amqpBindings); len(bindings) > 0 {
+		return bindings
+	}
+	return []string{defaultAMQPBindings}
+}
+
+// WebhookSecretsFile returns the path of the JSON file with the secrets that
+// producers sign their webhook requests with, or an empty string if the
+// webhook shouldn't be served.
+func (c *Cfg) WebhookSecretsFile() string {
+	return c.webhookSecrets
+}
+
+// RetentionPolicy returns the rules for how long events of each type are kept,
+// or an empty string if events are kept forever.
+func (c *Cfg) RetentionPolicy() string {
+	return c.retentionPolicy
+}
+
+// ArchiveDir returns the directory that expired events are archived in.
+func (c *Cfg) ArchiveDir() string {
+	return c.archiveDir
+}
+
+// RetentionInterval returns the time between applying the retention policy.
+// Default is 24 hours.
+func (c *Cfg) RetentionInterval() time.Duration {
+	if c.retentionInterval <= 0 {
+		c.retentionInterval = defaultRetentionInterval
+	}
+	return c.retentionInterval
+}
+
+// AllowedOrigins returns the origins of the web pages allowed to subscribe to
+// events over WebSocket, besides Goer's own, where * allows any origin.
+func (c *Cfg) AllowedOrigins() []string {
+	return splitList(c.allowedOrigins)
+}
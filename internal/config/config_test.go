@@ -16,9 +16,13 @@
 package config
 
 import (
+	"flag"
+	"io"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Test that it is possible to get a Cfg from Get with values taken from environment variables.
@@ -27,10 +31,15 @@ func TestGet(t *testing.T) {
 	connectionString := "connection string"
 	logLevel := "DEBUG"
 	logFilePath := "path/to/a/file"
+	idIndexCollection := "eventIDs"
 	t.Setenv("CONNECTION_STRING", connectionString)
 	t.Setenv("API_PORT", port)
 	t.Setenv("LOGLEVEL", logLevel)
 	t.Setenv("LOG_FILE_PATH", logFilePath)
+	t.Setenv("ID_INDEX_COLLECTION", idIndexCollection)
+	t.Setenv("DB_WORKERS", "4")
+	t.Setenv("ENABLE_GRAPHQL", "true")
+	t.Setenv("CREATE_INDEXES", "true")
 
 	cfg, ok := Get().(*Cfg)
 	assert.Truef(t, ok, "cfg returned from get is not a config interface")
@@ -38,6 +47,26 @@ func TestGet(t *testing.T) {
 	assert.Equal(t, port, cfg.apiPort)
 	assert.Equal(t, logLevel, cfg.logLevel)
 	assert.Equal(t, logFilePath, cfg.logFilePath)
+	assert.Equal(t, idIndexCollection, cfg.idIndexCollection)
+	assert.Equal(t, 4, cfg.dbWorkers)
+	assert.True(t, cfg.enableGraphQL)
+	assert.True(t, cfg.createIndexes)
+}
+
+// Test that the configuration flags can be parsed along with other flags
+// and that unknown flags are errors.
+func TestParse(t *testing.T) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	other := flags.Bool("other", false, "Another flag.")
+	cfg, err := Parse(flags, []string{"-connectionstring=mongodb://db/test", "-other", "-dbworkers", "2"})
+	assert.NoError(t, err)
+	assert.True(t, *other)
+	assert.Equal(t, "mongodb://db/test", cfg.DBConnectionString())
+	assert.Equal(t, 2, cfg.DBWorkers())
+
+	_, err = Parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-other"})
+	assert.Error(t, err)
 }
 
 type getter func() string
@@ -69,3 +98,122 @@ func TestGetters(t *testing.T) {
 		})
 	}
 }
+
+// Test that each flag is returned by its getter, and what the getter returns
+// when the flag isn't set.
+func TestFlags(t *testing.T) {
+	tests := []struct {
+		flag         string
+		value        string
+		getter       func(Config) interface{}
+		want         interface{}
+		defaultValue interface{}
+	}{
+		{
+			flag: "idindexcollection", value: "eventIDs",
+			getter: func(c Config) interface{} { return c.IDIndexCollection() },
+			want:   "eventIDs", defaultValue: "",
+		},
+		{
+			flag: "dbworkers", value: "16",
+			getter: func(c Config) interface{} { return c.DBWorkers() },
+			want:   16, defaultValue: defaultDBWorkers,
+		},
+		{
+			flag: "enablegraphql", value: "true",
+			getter: func(c Config) interface{} { return c.EnableGraphQL() },
+			want:   true, defaultValue: false,
+		},
+		{
+			flag: "createindexes", value: "true",
+			getter: func(c Config) interface{} { return c.CreateIndexes() },
+			want:   true, defaultValue: false,
+		},
+		{
+			flag: "enableadmin", value: "true",
+			getter: func(c Config) interface{} { return c.EnableAdmin() },
+			want:   true, defaultValue: false,
+		},
+		{
+			flag: "admintoken", value: "s3cr3t",
+			getter: func(c Config) interface{} { return c.AdminToken() },
+			want:   "s3cr3t", defaultValue: "",
+		},
+		{
+			flag: "duplicatepolicy", value: "reject",
+			getter: func(c Config) interface{} { return c.DuplicatePolicy() },
+			want:   "reject", defaultValue: defaultDuplicatePolicy,
+		},
+		{
+			flag: "publickeys", value: "a.pem, b.pem,",
+			getter: func(c Config) interface{} { return c.PublicKeyFiles() },
+			want:   []string{"a.pem", "b.pem"}, defaultValue: []string(nil),
+		},
+		{
+			flag: "jwksfile", value: "keys.json",
+			getter: func(c Config) interface{} { return c.JWKSFile() },
+			want:   "keys.json", defaultValue: "",
+		},
+		{
+			flag: "requiresignatures", value: "true",
+			getter: func(c Config) interface{} { return c.RequireSignatures() },
+			want:   true, defaultValue: false,
+		},
+		{
+			flag: "amqpurl", value: "amqp://broker",
+			getter: func(c Config) interface{} { return c.AMQPURL() },
+			want:   "amqp://broker", defaultValue: "",
+		},
+		{
+			flag: "amqpexchange", value: "eiffel",
+			getter: func(c Config) interface{} { return c.AMQPExchange() },
+			want:   "eiffel", defaultValue: defaultAMQPExchange,
+		},
+		{
+			flag: "amqpqueue", value: "events",
+			getter: func(c Config) interface{} { return c.AMQPQueue() },
+			want:   "events", defaultValue: defaultAMQPQueue,
+		},
+		{
+			flag: "amqpbindings", value: "eiffel.*.EiffelArtifactCreatedEvent.#,eiffel.*.EiffelActivityFinishedEvent.#",
+			getter: func(c Config) interface{} { return c.AMQPBindings() },
+			want:   []string{"eiffel.*.EiffelArtifactCreatedEvent.#", "eiffel.*.EiffelActivityFinishedEvent.#"}, defaultValue: []string{defaultAMQPBindings},
+		},
+		{
+			flag: "webhooksecretsfile", value: "secrets.json",
+			getter: func(c Config) interface{} { return c.WebhookSecretsFile() },
+			want:   "secrets.json", defaultValue: "",
+		},
+		{
+			flag: "retention", value: "*=90d",
+			getter: func(c Config) interface{} { return c.RetentionPolicy() },
+			want:   "*=90d", defaultValue: "",
+		},
+		{
+			flag: "archivedir", value: "archive",
+			getter: func(c Config) interface{} { return c.ArchiveDir() },
+			want:   "archive", defaultValue: "",
+		},
+		{
+			flag: "retentioninterval", value: "1h",
+			getter: func(c Config) interface{} { return c.RetentionInterval() },
+			want:   time.Hour, defaultValue: defaultRetentionInterval,
+		},
+		{
+			flag: "allowedorigins", value: "https://ui.example.com, *",
+			getter: func(c Config) interface{} { return c.AllowedOrigins() },
+			want:   []string{"https://ui.example.com", "*"}, defaultValue: []string(nil),
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.flag, func(t *testing.T) {
+			cfg, err := Parse(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-" + testCase.flag + "=" + testCase.value})
+			require.NoError(t, err)
+			assert.Equal(t, testCase.want, testCase.getter(cfg))
+
+			cfg, err = Parse(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.defaultValue, testCase.getter(cfg))
+		})
+	}
+}
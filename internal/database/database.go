@@ -22,6 +22,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/eiffel-community/eiffel-goer/internal/config"
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers/mongodb"
 )
@@ -30,15 +31,16 @@ import (
 // The variable is exported to assist with testing of this and other packages.
 var Drivers = []drivers.DatabaseDriver{&mongodb.Driver{}}
 
-// Get a new database driver and connect to database.
-func Get(ctx context.Context, connectionString string, logger *log.Entry) (drivers.Database, error) {
-	connectionURL, err := url.Parse(connectionString)
+// Get a new database driver and connect to the database pointed to by the
+// connection string in the configuration.
+func Get(ctx context.Context, cfg config.Config, logger *log.Entry) (drivers.Database, error) {
+	connectionURL, err := url.Parse(cfg.DBConnectionString())
 	if err != nil {
 		return nil, err
 	}
 	for _, driver := range Drivers {
 		if driver.SupportsScheme(connectionURL.Scheme) {
-			return driver.Get(ctx, connectionURL, logger)
+			return driver.Get(ctx, connectionURL, cfg, logger)
 		}
 	}
 	return nil, fmt.Errorf("cannot find database for scheme %q", connectionURL.Scheme)
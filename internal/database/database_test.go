@@ -21,6 +21,7 @@ import (
 
 	"github.com/eiffel-community/eiffel-goer/internal/database"
 	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
 	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
 	"github.com/golang/mock/gomock"
 	log "github.com/sirupsen/logrus"
@@ -35,24 +36,31 @@ func TestGet(t *testing.T) {
 	test.SetDatabaseDriver(mockDriver)
 	defer test.ResetDatabaseDriver()
 
-	url := "mongodb://db/test"
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().DBConnectionString().Return("mongodb://db/test")
 	entry := &log.Entry{}
 
 	mockDriver.EXPECT().SupportsScheme("mongodb").Return(true)
-	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), entry).Return(mockDB, nil)
+	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), mockCfg, entry).Return(mockDB, nil)
 	ctx := context.Background()
-	_, err := database.Get(ctx, url, entry)
+	_, err := database.Get(ctx, mockCfg, entry)
 	assert.NoError(t, err)
 }
 
 func TestGetUnknownScheme(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().DBConnectionString().Return("unknown://db/test")
 	ctx := context.Background()
-	_, err := database.Get(ctx, "unknown://db/test", &log.Entry{})
+	_, err := database.Get(ctx, mockCfg, &log.Entry{})
 	assert.Error(t, err)
 }
 
 func TestGetUnparsableScheme(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().DBConnectionString().Return("://")
 	ctx := context.Background()
-	_, err := database.Get(ctx, "://", &log.Entry{})
+	_, err := database.Get(ctx, mockCfg, &log.Entry{})
 	assert.Error(t, err)
 }
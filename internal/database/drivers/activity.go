@@ -0,0 +1,192 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// Event types and the link type making up an activity.
+const (
+	ActivityTriggered = "EiffelActivityTriggeredEvent"
+	ActivityCanceled  = "EiffelActivityCanceledEvent"
+	ActivityStarted   = "EiffelActivityStartedEvent"
+	ActivityFinished  = "EiffelActivityFinishedEvent"
+
+	LinkTypeActivityExecution = "ACTIVITY_EXECUTION"
+)
+
+// ActivityEvents are the events of an activity. Events that haven't been
+// sent yet are nil.
+type ActivityEvents struct {
+	Triggered EiffelEvent `json:"triggered"`
+	Canceled  EiffelEvent `json:"canceled,omitempty"`
+	Started   EiffelEvent `json:"started,omitempty"`
+	Finished  EiffelEvent `json:"finished,omitempty"`
+}
+
+// Activity is the timeline of an activity, computed from its
+// EiffelActivityTriggeredEvent and the events linking to it with
+// ACTIVITY_EXECUTION links. Times are in milliseconds since the epoch and
+// durations in milliseconds. Times and durations that can't be computed
+// yet, e.g. the finish time of an ongoing activity, are nil.
+type Activity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	TriggerTime int64  `json:"triggerTime"`
+	StartTime   *int64 `json:"startTime"`
+	FinishTime  *int64 `json:"finishTime"`
+	// QueueTime is the time from the trigger to the start.
+	QueueTime *int64 `json:"queueTime"`
+	// Duration is the time from the start to the finish.
+	Duration *int64 `json:"duration"`
+	// Outcome is the data.outcome of the EiffelActivityFinishedEvent.
+	Outcome interface{}    `json:"outcome"`
+	Events  ActivityEvents `json:"events"`
+}
+
+// NewActivity computes the timeline of an activity from its triggered event
+// and the events linking to it. Linking events that aren't part of the
+// activity are ignored and if an event was sent more than once, the
+// earliest one is used.
+func NewActivity(triggered EiffelEvent, linking []EiffelEvent) Activity {
+	activity := Activity{
+		ID:          triggered.ID(),
+		Name:        triggered.StringField("data.name"),
+		TriggerTime: triggered.Time(),
+		Events:      ActivityEvents{Triggered: triggered},
+	}
+	for _, event := range linking {
+		if !linksTo(event, LinkTypeActivityExecution, activity.ID) {
+			continue
+		}
+		switch event.Type() {
+		case ActivityCanceled:
+			activity.Events.Canceled = earliest(activity.Events.Canceled, event)
+		case ActivityStarted:
+			activity.Events.Started = earliest(activity.Events.Started, event)
+		case ActivityFinished:
+			activity.Events.Finished = earliest(activity.Events.Finished, event)
+		}
+	}
+	if started := activity.Events.Started; started != nil {
+		startTime := started.Time()
+		queueTime := startTime - activity.TriggerTime
+		activity.StartTime = &startTime
+		activity.QueueTime = &queueTime
+	}
+	if finished := activity.Events.Finished; finished != nil {
+		finishTime := finished.Time()
+		activity.FinishTime = &finishTime
+		if activity.StartTime != nil {
+			duration := finishTime - *activity.StartTime
+			activity.Duration = &duration
+		}
+		activity.Outcome, _ = finished.Field("data.outcome")
+	}
+	return activity
+}
+
+// linksTo returns true if the event has a link of the type to the target.
+func linksTo(event EiffelEvent, linkType string, target string) bool {
+	for _, link := range event.Links() {
+		if link.Type == linkType && link.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// earliest returns the earliest of two events, where the current one may be nil.
+func earliest(current EiffelEvent, event EiffelEvent) EiffelEvent {
+	if current == nil || event.Time() < current.Time() {
+		return event
+	}
+	return current
+}
+
+// GetActivity gets the timeline of the activity triggered by the
+// EiffelActivityTriggeredEvent with the ID. ErrNotFound is returned if
+// there's no such event.
+func GetActivity(ctx context.Context, db Database, id string) (Activity, error) {
+	triggered, err := db.GetEventByID(ctx, id)
+	if err != nil {
+		return Activity{}, err
+	}
+	if triggered.Type() != ActivityTriggered {
+		return Activity{}, fmt.Errorf("%w: %q is an %s", ErrNotFound, id, triggered.Type())
+	}
+	linking, err := db.GetLinkingEvents(ctx, []string{id}, []string{LinkTypeActivityExecution})
+	if err != nil {
+		return Activity{}, err
+	}
+	return NewActivity(triggered, linking), nil
+}
+
+// GetActivities gets the timelines of a page of the activities matching the
+// request, in the order the database returns their triggered events. The
+// events of all activities on the page are fetched with a single
+// GetLinkingEvents call.
+func GetActivities(ctx context.Context, db Database, request requests.ActivitiesRequest) ([]Activity, error) {
+	conditions := []query.Condition{{Field: "meta.type", Op: "=", Value: ActivityTriggered}}
+	if request.Name != "" {
+		conditions = append(conditions, query.Condition{Field: "data.name", Op: "=", Value: request.Name})
+	}
+	if request.From != 0 {
+		conditions = append(conditions, query.Condition{
+			Field: "meta.time", Op: ">=", Value: strconv.FormatInt(request.From, 10), TypeConv: "int",
+		})
+	}
+	if request.To != 0 {
+		conditions = append(conditions, query.Condition{
+			Field: "meta.time", Op: "<=", Value: strconv.FormatInt(request.To, 10), TypeConv: "int",
+		})
+	}
+	stream, _, err := db.GetEvents(ctx, requests.MultipleEventsRequest{
+		PageNo:     request.PageNo,
+		PageSize:   request.PageSize,
+		Count:      requests.CountNone,
+		Conditions: conditions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	triggered, err := Collect(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(triggered))
+	for _, event := range triggered {
+		ids = append(ids, event.ID())
+	}
+	activities := make([]Activity, 0, len(triggered))
+	if len(triggered) == 0 {
+		return activities, nil
+	}
+	linking, err := db.GetLinkingEvents(ctx, ids, []string{LinkTypeActivityExecution})
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range triggered {
+		activities = append(activities, NewActivity(event, linking))
+	}
+	return activities, nil
+}
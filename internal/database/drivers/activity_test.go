@@ -0,0 +1,124 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+var (
+	buildTriggered = test.NewEvent("10000000-0000-4000-8000-000000000001", drivers.ActivityTriggered, 1000,
+		map[string]interface{}{"name": "build"})
+	buildStarted = test.NewEvent("10000000-0000-4000-8000-000000000002", drivers.ActivityStarted, 1500, nil,
+		drivers.Link{Type: "ACTIVITY_EXECUTION", Target: buildTriggered.ID()})
+	buildStartedAgain = test.NewEvent("10000000-0000-4000-8000-000000000003", drivers.ActivityStarted, 1700, nil,
+		drivers.Link{Type: "ACTIVITY_EXECUTION", Target: buildTriggered.ID()})
+	buildFinished = test.NewEvent("10000000-0000-4000-8000-000000000004", drivers.ActivityFinished, 4500,
+		map[string]interface{}{"outcome": map[string]interface{}{"conclusion": "SUCCESSFUL"}},
+		drivers.Link{Type: "ACTIVITY_EXECUTION", Target: buildTriggered.ID()})
+	// An event that links to the activity in another way isn't part of it.
+	buildCaused = test.NewEvent("10000000-0000-4000-8000-000000000005", drivers.ActivityStarted, 1200, nil,
+		drivers.Link{Type: "CAUSE", Target: buildTriggered.ID()})
+	testTriggered = test.NewEvent("10000000-0000-4000-8000-000000000006", drivers.ActivityTriggered, 5000,
+		map[string]interface{}{"name": "test"})
+	nextBuildTriggered = test.NewEvent("10000000-0000-4000-8000-000000000007", drivers.ActivityTriggered, 6000,
+		map[string]interface{}{"name": "build"})
+)
+
+func int64Pointer(i int64) *int64 {
+	return &i
+}
+
+// Test that the timeline is computed from the earliest events of the activity.
+func TestGetActivity(t *testing.T) {
+	db := test.NewMemoryDatabase(buildTriggered, buildStarted, buildStartedAgain, buildFinished, buildCaused, testTriggered)
+
+	activity, err := drivers.GetActivity(context.Background(), db, buildTriggered.ID())
+	require.NoError(t, err)
+	assert.Equal(t, drivers.Activity{
+		ID:          buildTriggered.ID(),
+		Name:        "build",
+		TriggerTime: 1000,
+		StartTime:   int64Pointer(1500),
+		FinishTime:  int64Pointer(4500),
+		QueueTime:   int64Pointer(500),
+		Duration:    int64Pointer(3000),
+		Outcome:     map[string]interface{}{"conclusion": "SUCCESSFUL"},
+		Events: drivers.ActivityEvents{
+			Triggered: buildTriggered,
+			Started:   buildStarted,
+			Finished:  buildFinished,
+		},
+	}, activity)
+
+	activity, err = drivers.GetActivity(context.Background(), db, testTriggered.ID())
+	require.NoError(t, err)
+	assert.Equal(t, drivers.Activity{
+		ID:          testTriggered.ID(),
+		Name:        "test",
+		TriggerTime: 5000,
+		Events:      drivers.ActivityEvents{Triggered: testTriggered},
+	}, activity)
+
+	_, err = drivers.GetActivity(context.Background(), db, buildStarted.ID())
+	assert.True(t, errors.Is(err, drivers.ErrNotFound))
+}
+
+// Test that activities are filtered by name and trigger time.
+func TestGetActivities(t *testing.T) {
+	db := test.NewMemoryDatabase(buildTriggered, buildStarted, buildFinished, testTriggered, nextBuildTriggered)
+	tests := []struct {
+		name     string
+		request  requests.ActivitiesRequest
+		expected []string
+	}{
+		{name: "All", request: requests.ActivitiesRequest{}, expected: []string{buildTriggered.ID(), testTriggered.ID(), nextBuildTriggered.ID()}},
+		{name: "Name", request: requests.ActivitiesRequest{Name: "build"}, expected: []string{buildTriggered.ID(), nextBuildTriggered.ID()}},
+		{name: "From", request: requests.ActivitiesRequest{Name: "build", From: 1001}, expected: []string{nextBuildTriggered.ID()}},
+		{name: "To", request: requests.ActivitiesRequest{To: 5000}, expected: []string{buildTriggered.ID(), testTriggered.ID()}},
+		{name: "Page", request: requests.ActivitiesRequest{PageNo: 2, PageSize: 2}, expected: []string{nextBuildTriggered.ID()}},
+		{name: "None", request: requests.ActivitiesRequest{Name: "deploy"}, expected: []string{}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := testCase.request
+			if request.PageNo == 0 {
+				request.PageNo, request.PageSize = 1, 10
+			}
+			activities, err := drivers.GetActivities(context.Background(), db, request)
+			require.NoError(t, err)
+			ids := []string{}
+			for _, activity := range activities {
+				ids = append(ids, activity.ID)
+			}
+			assert.Equal(t, testCase.expected, ids)
+		})
+	}
+
+	activities, err := drivers.GetActivities(context.Background(), db, requests.ActivitiesRequest{PageNo: 1, PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, activities, 1)
+	assert.Equal(t, int64Pointer(3000), activities[0].Duration)
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// Event types and link types making up the lineage of an artifact.
+const (
+	ArtifactCreated         = "EiffelArtifactCreatedEvent"
+	ArtifactPublished       = "EiffelArtifactPublishedEvent"
+	ConfidenceLevelModified = "EiffelConfidenceLevelModifiedEvent"
+	SourceChangeCreated     = "EiffelSourceChangeCreatedEvent"
+	SourceChangeSubmitted   = "EiffelSourceChangeSubmittedEvent"
+
+	LinkTypeArtifact = "ARTIFACT"
+	LinkTypeCause    = "CAUSE"
+	LinkTypeChange   = "CHANGE"
+	LinkTypeSubject  = "SUBJECT"
+)
+
+// sourceChangeLinkTypes are the link types followed upstream from an
+// artifact to find the source changes it was built from. Links like BASE
+// and PREVIOUS_VERSION are left out since they lead into the history of
+// the source rather than to what's in the artifact.
+var sourceChangeLinkTypes = []string{LinkTypeCause, LinkTypeContext, LinkTypeChange}
+
+// ArtifactLineage is an artifact together with where it was published,
+// the confidence levels reported against it and the source changes it was
+// built from. All lists are ordered by time.
+type ArtifactLineage struct {
+	Identity         string        `json:"identity"`
+	Artifact         EiffelEvent   `json:"artifact"`
+	Publications     []EiffelEvent `json:"publications"`
+	ConfidenceLevels []EiffelEvent `json:"confidenceLevels"`
+	SourceChanges    []EiffelEvent `json:"sourceChanges"`
+}
+
+// GetArtifactLineages gets the lineages of the artifacts created with the
+// data.identity, usually a purl. There's normally at most one such
+// artifact but all of them are returned, oldest first.
+//
+// Publications and confidence levels are the EiffelArtifactPublishedEvents
+// and EiffelConfidenceLevelModifiedEvents linking to the artifact with
+// ARTIFACT and SUBJECT links respectively. Source changes are the
+// EiffelSourceChangeCreatedEvents and EiffelSourceChangeSubmittedEvents
+// reached by following CAUSE, CONTEXT and CHANGE links upstream.
+func GetArtifactLineages(ctx context.Context, db Database, identity string) ([]ArtifactLineage, error) {
+	stream, _, err := db.GetEvents(ctx, requests.MultipleEventsRequest{
+		Unpaged: true,
+		Count:   requests.CountNone,
+		Conditions: []query.Condition{
+			{Field: "meta.type", Op: "=", Value: ArtifactCreated},
+			{Field: "data.identity", Op: "=", Value: identity},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	artifacts, err := Collect(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		ids = append(ids, artifact.ID())
+	}
+	lineages := make([]ArtifactLineage, 0, len(artifacts))
+	if len(artifacts) == 0 {
+		return lineages, nil
+	}
+	SortByTime(artifacts)
+
+	linking, err := db.GetLinkingEvents(ctx, ids, []string{LinkTypeArtifact, LinkTypeSubject})
+	if err != nil {
+		return nil, err
+	}
+	SortByTime(linking)
+	for _, artifact := range artifacts {
+		lineage := ArtifactLineage{
+			Identity:         identity,
+			Artifact:         artifact,
+			Publications:     []EiffelEvent{},
+			ConfidenceLevels: []EiffelEvent{},
+			SourceChanges:    []EiffelEvent{},
+		}
+		for _, event := range linking {
+			switch {
+			case event.Type() == ArtifactPublished && linksTo(event, LinkTypeArtifact, artifact.ID()):
+				lineage.Publications = append(lineage.Publications, event)
+			case event.Type() == ConfidenceLevelModified && linksTo(event, LinkTypeSubject, artifact.ID()):
+				lineage.ConfidenceLevels = append(lineage.ConfidenceLevels, event)
+			}
+		}
+		upstream, err := Traverse(ctx, db, []EiffelEvent{artifact}, Upstream, sourceChangeLinkTypes, -1, -1)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range upstream {
+			if event.Type() == SourceChangeCreated || event.Type() == SourceChangeSubmitted {
+				lineage.SourceChanges = append(lineage.SourceChanges, event)
+			}
+		}
+		SortByTime(lineage.SourceChanges)
+		lineages = append(lineages, lineage)
+	}
+	return lineages, nil
+}
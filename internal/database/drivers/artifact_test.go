@@ -0,0 +1,68 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+// Test that the lineage includes publications, confidence levels and source
+// changes but not the history of the source.
+func TestGetArtifactLineages(t *testing.T) {
+	identity := "pkg:generic/goer@1.0.0"
+	oldSubmitted := test.NewEvent("30000000-0000-4000-8000-000000000001", drivers.SourceChangeSubmitted, 100, nil)
+	created := test.NewEvent("30000000-0000-4000-8000-000000000002", drivers.SourceChangeCreated, 200, nil,
+		drivers.Link{Type: "BASE", Target: oldSubmitted.ID()})
+	submitted := test.NewEvent("30000000-0000-4000-8000-000000000003", drivers.SourceChangeSubmitted, 300, nil,
+		drivers.Link{Type: "CHANGE", Target: created.ID()},
+		drivers.Link{Type: "PREVIOUS_VERSION", Target: oldSubmitted.ID()})
+	build := test.NewEvent("30000000-0000-4000-8000-000000000004", drivers.ActivityTriggered, 400, nil,
+		drivers.Link{Type: "CAUSE", Target: submitted.ID()})
+	artifact := test.NewEvent("30000000-0000-4000-8000-000000000005", drivers.ArtifactCreated, 500,
+		map[string]interface{}{"identity": identity},
+		drivers.Link{Type: "CONTEXT", Target: build.ID()})
+	otherArtifact := test.NewEvent("30000000-0000-4000-8000-000000000006", drivers.ArtifactCreated, 550,
+		map[string]interface{}{"identity": "pkg:generic/goer@0.9.0"})
+	published := test.NewEvent("30000000-0000-4000-8000-000000000007", drivers.ArtifactPublished, 600, nil,
+		drivers.Link{Type: "ARTIFACT", Target: artifact.ID()})
+	confidence := test.NewEvent("30000000-0000-4000-8000-000000000008", drivers.ConfidenceLevelModified, 700,
+		map[string]interface{}{"name": "stable", "value": "SUCCESS"},
+		drivers.Link{Type: "SUBJECT", Target: artifact.ID()})
+	otherConfidence := test.NewEvent("30000000-0000-4000-8000-000000000009", drivers.ConfidenceLevelModified, 800, nil,
+		drivers.Link{Type: "SUBJECT", Target: otherArtifact.ID()})
+	db := test.NewMemoryDatabase(oldSubmitted, created, submitted, build, artifact, otherArtifact, published, confidence, otherConfidence)
+
+	lineages, err := drivers.GetArtifactLineages(context.Background(), db, identity)
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.ArtifactLineage{{
+		Identity:         identity,
+		Artifact:         artifact,
+		Publications:     []drivers.EiffelEvent{published},
+		ConfidenceLevels: []drivers.EiffelEvent{confidence},
+		SourceChanges:    []drivers.EiffelEvent{created, submitted},
+	}}, lineages)
+
+	lineages, err = drivers.GetArtifactLineages(context.Background(), db, "pkg:generic/goer@2.0.0")
+	require.NoError(t, err)
+	assert.Empty(t, lineages)
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// Event types and link types found downstream from source changes.
+const (
+	CompositionDefined = "EiffelCompositionDefinedEvent"
+
+	LinkTypeComposition = "COMPOSITION"
+	LinkTypeElement     = "ELEMENT"
+	LinkTypeIUT         = "IUT"
+)
+
+// DefaultChangeLinkTypes are the link types followed downstream from source
+// changes by default. They lead from the changes to the artifacts built from
+// them, the compositions including the artifacts and the tests of both.
+var DefaultChangeLinkTypes = []string{
+	LinkTypeChange,
+	LinkTypeCause,
+	LinkTypeContext,
+	LinkTypeComposition,
+	LinkTypeElement,
+	LinkTypeArtifact,
+	LinkTypeSubject,
+	LinkTypeIUT,
+	LinkTypeTestCaseExecution,
+	LinkTypeTestSuiteExecution,
+}
+
+// ChangeTrace is what source changes ended up in. The events downstream
+// of the changes are also sorted into artifacts, compositions and test
+// results for convenience.
+type ChangeTrace struct {
+	// Changes are the EiffelSourceChangeCreatedEvents and
+	// EiffelSourceChangeSubmittedEvents with the requested identifiers,
+	// followed by those found downstream of them.
+	Changes      []EiffelEvent `json:"changes"`
+	Artifacts    []EiffelEvent `json:"artifacts"`
+	Compositions []EiffelEvent `json:"compositions"`
+	// TestResults are the EiffelTestCaseFinishedEvents and
+	// EiffelTestSuiteFinishedEvents with the verdicts.
+	TestResults []EiffelEvent `json:"testResults"`
+	// Downstream are all events found downstream of the changes.
+	Downstream []EiffelEvent `json:"downstream"`
+	// Truncated is true if the traversal stopped at the request's limit,
+	// in which case there may be more events downstream.
+	Truncated bool `json:"truncated"`
+}
+
+// TraceChanges finds the source changes with the request's git commit SHA
+// (data.gitIdentifier.commitId) or Gerrit change ID
+// (data.gerritIdentifier.changeId) and follows links of the given types
+// downstream from them, within the request's levels and limit. ErrNotFound
+// is returned if there are no such source changes.
+func TraceChanges(ctx context.Context, db Database, request requests.ChangesRequest, linkTypes []string) (ChangeTrace, error) {
+	var identifiers []query.Condition
+	if request.Commit != "" {
+		identifiers = append(identifiers, query.Condition{Field: "data.gitIdentifier.commitId", Op: "=", Value: request.Commit})
+	}
+	if request.ChangeID != "" {
+		identifiers = append(identifiers, query.Condition{Field: "data.gerritIdentifier.changeId", Op: "=", Value: request.ChangeID})
+	}
+	trace := ChangeTrace{
+		Changes:      []EiffelEvent{},
+		Artifacts:    []EiffelEvent{},
+		Compositions: []EiffelEvent{},
+		TestResults:  []EiffelEvent{},
+		Downstream:   []EiffelEvent{},
+	}
+	// The query language can't express "or", so each combination of
+	// event type and identifier is queried separately.
+	seen := map[string]struct{}{}
+	for _, eventType := range []string{SourceChangeCreated, SourceChangeSubmitted} {
+		for _, identifier := range identifiers {
+			stream, _, err := db.GetEvents(ctx, requests.MultipleEventsRequest{
+				Unpaged: true,
+				Count:   requests.CountNone,
+				Conditions: []query.Condition{
+					{Field: "meta.type", Op: "=", Value: eventType},
+					identifier,
+				},
+			})
+			if err != nil {
+				return ChangeTrace{}, err
+			}
+			changes, err := Collect(ctx, stream)
+			if err != nil {
+				return ChangeTrace{}, err
+			}
+			for _, change := range changes {
+				if _, ok := seen[change.ID()]; !ok {
+					seen[change.ID()] = struct{}{}
+					trace.Changes = append(trace.Changes, change)
+				}
+			}
+		}
+	}
+	if len(trace.Changes) == 0 {
+		return ChangeTrace{}, fmt.Errorf("%w: no source changes with the identifiers", ErrNotFound)
+	}
+	SortByTime(trace.Changes)
+
+	downstream, err := Traverse(ctx, db, trace.Changes, Downstream, linkTypes, int(request.Levels), int(request.Limit))
+	if err != nil {
+		return ChangeTrace{}, err
+	}
+	for _, event := range downstream {
+		switch event.Type() {
+		case SourceChangeCreated, SourceChangeSubmitted:
+			// Changes found downstream, e.g. the submission of a
+			// created change, are listed along with the others.
+			trace.Changes = append(trace.Changes, event)
+		case ArtifactCreated:
+			trace.Artifacts = append(trace.Artifacts, event)
+		case CompositionDefined:
+			trace.Compositions = append(trace.Compositions, event)
+		case TestCaseFinished, TestSuiteFinished:
+			trace.TestResults = append(trace.TestResults, event)
+		}
+		trace.Downstream = append(trace.Downstream, event)
+	}
+	trace.Truncated = request.Limit >= 0 && len(downstream) >= int(request.Limit)
+	return trace, nil
+}
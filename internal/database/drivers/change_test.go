@@ -0,0 +1,86 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+// Test that source changes are found by either identifier and traced to what they ended up in.
+func TestTraceChanges(t *testing.T) {
+	created := test.NewEvent("60000000-0000-4000-8000-000000000001", drivers.SourceChangeCreated, 100,
+		map[string]interface{}{
+			"gitIdentifier":    map[string]interface{}{"commitId": "fe3c0a3"},
+			"gerritIdentifier": map[string]interface{}{"changeId": "I8473b959"},
+		})
+	submitted := test.NewEvent("60000000-0000-4000-8000-000000000002", drivers.SourceChangeSubmitted, 200,
+		map[string]interface{}{"gitIdentifier": map[string]interface{}{"commitId": "fe3c0a3"}},
+		drivers.Link{Type: "CHANGE", Target: created.ID()})
+	artifact := test.NewEvent("60000000-0000-4000-8000-000000000003", drivers.ArtifactCreated, 300, nil,
+		drivers.Link{Type: "CAUSE", Target: submitted.ID()})
+	composition := test.NewEvent("60000000-0000-4000-8000-000000000004", drivers.CompositionDefined, 400, nil,
+		drivers.Link{Type: "ELEMENT", Target: artifact.ID()})
+	testCase := test.NewEvent("60000000-0000-4000-8000-000000000005", drivers.TestCaseTriggered, 500, nil,
+		drivers.Link{Type: "IUT", Target: artifact.ID()})
+	testResult := test.NewEvent("60000000-0000-4000-8000-000000000006", drivers.TestCaseFinished, 600,
+		map[string]interface{}{"outcome": map[string]interface{}{"verdict": drivers.VerdictPassed}},
+		drivers.Link{Type: "TEST_CASE_EXECUTION", Target: testCase.ID()})
+	unrelated := test.NewEvent("60000000-0000-4000-8000-000000000007", drivers.SourceChangeSubmitted, 700,
+		map[string]interface{}{"gitIdentifier": map[string]interface{}{"commitId": "0123abc"}})
+	db := test.NewMemoryDatabase(created, submitted, artifact, composition, testCase, testResult, unrelated)
+
+	trace, err := drivers.TraceChanges(context.Background(), db,
+		requests.ChangesRequest{Commit: "fe3c0a3", Levels: -1, Limit: -1}, drivers.DefaultChangeLinkTypes)
+	require.NoError(t, err)
+	assert.Equal(t, drivers.ChangeTrace{
+		Changes:      []drivers.EiffelEvent{created, submitted},
+		Artifacts:    []drivers.EiffelEvent{artifact},
+		Compositions: []drivers.EiffelEvent{composition},
+		TestResults:  []drivers.EiffelEvent{testResult},
+		Downstream:   []drivers.EiffelEvent{artifact, composition, testCase, testResult},
+	}, trace)
+
+	// The created change leads to the submitted one, which is listed with the changes.
+	trace, err = drivers.TraceChanges(context.Background(), db,
+		requests.ChangesRequest{ChangeID: "I8473b959", Levels: 2, Limit: -1}, drivers.DefaultChangeLinkTypes)
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.EiffelEvent{created, submitted}, trace.Changes)
+	assert.Equal(t, []drivers.EiffelEvent{submitted, artifact}, trace.Downstream)
+
+	trace, err = drivers.TraceChanges(context.Background(), db,
+		requests.ChangesRequest{Commit: "fe3c0a3", Levels: -1, Limit: 2}, drivers.DefaultChangeLinkTypes)
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.EiffelEvent{artifact, composition}, trace.Downstream)
+	assert.True(t, trace.Truncated)
+
+	trace, err = drivers.TraceChanges(context.Background(), db,
+		requests.ChangesRequest{Commit: "fe3c0a3", Levels: -1, Limit: -1}, []string{"CAUSE"})
+	require.NoError(t, err)
+	assert.Equal(t, []drivers.EiffelEvent{artifact}, trace.Downstream)
+
+	_, err = drivers.TraceChanges(context.Background(), db,
+		requests.ChangesRequest{Commit: "fffffff", Levels: -1, Limit: -1}, drivers.DefaultChangeLinkTypes)
+	assert.True(t, errors.Is(err, drivers.ErrNotFound))
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// LinkTypeSubConfidenceLevel links a confidence level to the confidence
+// levels it was computed from.
+const LinkTypeSubConfidenceLevel = "SUB_CONFIDENCE_LEVEL"
+
+// Confidence level values, from the best to the worst. ConfidenceNone is
+// the status of an artifact without any confidence levels.
+const (
+	ConfidenceNone         = "NONE"
+	ConfidenceSuccess      = "SUCCESS"
+	ConfidenceInconclusive = "INCONCLUSIVE"
+	ConfidenceFailure      = "FAILURE"
+)
+
+// confidenceRank orders the confidence level values from the best to the
+// worst. Unknown values rank as INCONCLUSIVE.
+var confidenceRank = map[string]int{
+	ConfidenceNone:         0,
+	ConfidenceSuccess:      1,
+	ConfidenceInconclusive: 2,
+	ConfidenceFailure:      3,
+}
+
+// worstConfidence returns the worst of two confidence level values.
+func worstConfidence(a string, b string) string {
+	rank := func(value string) int {
+		if r, ok := confidenceRank[value]; ok {
+			return r
+		}
+		return confidenceRank[ConfidenceInconclusive]
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
+
+// ConfidenceLevel is a confidence level reported by an
+// EiffelConfidenceLevelModifiedEvent.
+type ConfidenceLevel struct {
+	Name  string      `json:"name"`
+	Value string      `json:"value"`
+	Time  int64       `json:"time"`
+	Event EiffelEvent `json:"event"`
+	// SubLevels are the confidence levels the level was computed from,
+	// linked with SUB_CONFIDENCE_LEVEL links.
+	SubLevels []ConfidenceLevel `json:"subLevels"`
+	// Status is the worst of the value and the statuses of the sub levels.
+	Status string `json:"status"`
+}
+
+// ArtifactConfidence is the latest confidence level of each name reported
+// against an artifact.
+type ArtifactConfidence struct {
+	ID       string `json:"id"`
+	Identity string `json:"identity"`
+	// Status is the worst status of the levels, or NONE if there are none.
+	Status string            `json:"status"`
+	Levels []ConfidenceLevel `json:"levels"`
+}
+
+// GetArtifactConfidence gets the latest confidence level of each data.name
+// among the EiffelConfidenceLevelModifiedEvents linking to the
+// EiffelArtifactCreatedEvent with the ID with SUBJECT links, ordered by
+// name. ErrNotFound is returned if there's no such event.
+//
+// The statuses of the levels roll up the levels linked with
+// SUB_CONFIDENCE_LEVEL links, recursively, so that e.g. a failed
+// confidence level of a component fails the levels computed from it.
+func GetArtifactConfidence(ctx context.Context, db Database, id string) (ArtifactConfidence, error) {
+	artifact, err := db.GetEventByID(ctx, id)
+	if err != nil {
+		return ArtifactConfidence{}, err
+	}
+	if artifact.Type() != ArtifactCreated {
+		return ArtifactConfidence{}, fmt.Errorf("%w: %q is an %s", ErrNotFound, id, artifact.Type())
+	}
+	confidence := ArtifactConfidence{
+		ID:       id,
+		Identity: artifact.StringField("data.identity"),
+		Status:   ConfidenceNone,
+		Levels:   []ConfidenceLevel{},
+	}
+	linking, err := db.GetLinkingEvents(ctx, []string{id}, []string{LinkTypeSubject})
+	if err != nil {
+		return ArtifactConfidence{}, err
+	}
+	SortByTime(linking)
+	latest := map[string]EiffelEvent{}
+	for _, event := range linking {
+		if event.Type() == ConfidenceLevelModified && linksTo(event, LinkTypeSubject, id) {
+			latest[event.StringField("data.name")] = event
+		}
+	}
+	if len(latest) == 0 {
+		return confidence, nil
+	}
+
+	levels := make([]EiffelEvent, 0, len(latest))
+	for _, event := range latest {
+		levels = append(levels, event)
+	}
+	subLevels, err := getSubConfidenceLevels(ctx, db, levels)
+	if err != nil {
+		return ArtifactConfidence{}, err
+	}
+	for _, event := range levels {
+		level := newConfidenceLevel(event, subLevels, map[string]struct{}{})
+		confidence.Levels = append(confidence.Levels, level)
+		confidence.Status = worstConfidence(confidence.Status, level.Status)
+	}
+	sort.Slice(confidence.Levels, func(i, j int) bool {
+		return confidence.Levels[i].Name < confidence.Levels[j].Name
+	})
+	return confidence, nil
+}
+
+// getSubConfidenceLevels gets the events reached by following
+// SUB_CONFIDENCE_LEVEL links from the levels, recursively, by ID.
+func getSubConfidenceLevels(ctx context.Context, db Database, levels []EiffelEvent) (map[string]EiffelEvent, error) {
+	subLevels, err := Traverse(ctx, db, levels, Upstream, []string{LinkTypeSubConfidenceLevel}, -1, -1)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]EiffelEvent, len(subLevels)+len(levels))
+	for _, event := range append(subLevels, levels...) {
+		byID[event.ID()] = event
+	}
+	return byID, nil
+}
+
+// newConfidenceLevel returns the confidence level of an event with its sub
+// levels looked up in the events by ID. Sub levels already on the path from
+// the top level are left out to break cycles.
+func newConfidenceLevel(event EiffelEvent, events map[string]EiffelEvent, path map[string]struct{}) ConfidenceLevel {
+	level := ConfidenceLevel{
+		Name:      event.StringField("data.name"),
+		Value:     event.StringField("data.value"),
+		Time:      event.Time(),
+		Event:     event,
+		SubLevels: []ConfidenceLevel{},
+	}
+	level.Status = worstConfidence(ConfidenceNone, level.Value)
+	path[event.ID()] = struct{}{}
+	defer delete(path, event.ID())
+	for _, link := range event.Links() {
+		if link.Type != LinkTypeSubConfidenceLevel {
+			continue
+		}
+		sub, ok := events[link.Target]
+		if _, onPath := path[link.Target]; !ok || onPath {
+			continue
+		}
+		subLevel := newConfidenceLevel(sub, events, path)
+		level.SubLevels = append(level.SubLevels, subLevel)
+		level.Status = worstConfidence(level.Status, subLevel.Status)
+	}
+	return level
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+// confidenceLevel returns an EiffelConfidenceLevelModifiedEvent with the links.
+func confidenceLevel(id string, time int64, name string, value string, links ...drivers.Link) drivers.EiffelEvent {
+	return test.NewEvent(id, drivers.ConfidenceLevelModified, time,
+		map[string]interface{}{"name": name, "value": value}, links...)
+}
+
+// Test that the latest level of each name is used and that sub levels roll up.
+func TestGetArtifactConfidence(t *testing.T) {
+	artifact := test.NewEvent("40000000-0000-4000-8000-000000000001", drivers.ArtifactCreated, 100,
+		map[string]interface{}{"identity": "pkg:generic/goer@1.0.0"})
+	component := test.NewEvent("40000000-0000-4000-8000-000000000002", drivers.ArtifactCreated, 50, nil)
+	subject := drivers.Link{Type: "SUBJECT", Target: artifact.ID()}
+
+	componentLevel := confidenceLevel("40000000-0000-4000-8000-000000000003", 150, "tested", drivers.ConfidenceFailure,
+		drivers.Link{Type: "SUBJECT", Target: component.ID()})
+	oldStable := confidenceLevel("40000000-0000-4000-8000-000000000004", 200, "stable", drivers.ConfidenceFailure, subject)
+	stable := confidenceLevel("40000000-0000-4000-8000-000000000005", 300, "stable", drivers.ConfidenceSuccess, subject)
+	// The sub level links form a cycle, which must not be followed forever.
+	system := confidenceLevel("40000000-0000-4000-8000-000000000006", 400, "system", drivers.ConfidenceSuccess, subject,
+		drivers.Link{Type: "SUB_CONFIDENCE_LEVEL", Target: componentLevel.ID()},
+		drivers.Link{Type: "SUB_CONFIDENCE_LEVEL", Target: "40000000-0000-4000-8000-000000000006"})
+	db := test.NewMemoryDatabase(artifact, component, componentLevel, oldStable, stable, system)
+
+	confidence, err := drivers.GetArtifactConfidence(context.Background(), db, artifact.ID())
+	require.NoError(t, err)
+	assert.Equal(t, artifact.ID(), confidence.ID)
+	assert.Equal(t, "pkg:generic/goer@1.0.0", confidence.Identity)
+	assert.Equal(t, drivers.ConfidenceFailure, confidence.Status)
+	assert.Equal(t, []drivers.ConfidenceLevel{
+		{
+			Name:      "stable",
+			Value:     drivers.ConfidenceSuccess,
+			Time:      300,
+			Event:     stable,
+			SubLevels: []drivers.ConfidenceLevel{},
+			Status:    drivers.ConfidenceSuccess,
+		},
+		{
+			Name:  "system",
+			Value: drivers.ConfidenceSuccess,
+			Time:  400,
+			Event: system,
+			SubLevels: []drivers.ConfidenceLevel{{
+				Name:      "tested",
+				Value:     drivers.ConfidenceFailure,
+				Time:      150,
+				Event:     componentLevel,
+				SubLevels: []drivers.ConfidenceLevel{},
+				Status:    drivers.ConfidenceFailure,
+			}},
+			Status: drivers.ConfidenceFailure,
+		},
+	}, confidence.Levels)
+
+	confidence, err = drivers.GetArtifactConfidence(context.Background(), db, component.ID())
+	require.NoError(t, err)
+	assert.Equal(t, drivers.ConfidenceFailure, confidence.Status)
+
+	_, err = drivers.GetArtifactConfidence(context.Background(), db, stable.ID())
+	assert.True(t, errors.Is(err, drivers.ErrNotFound))
+}
+
+// Test that artifacts without confidence levels have no status.
+func TestGetArtifactConfidenceNone(t *testing.T) {
+	artifact := test.NewEvent("40000000-0000-4000-8000-000000000001", drivers.ArtifactCreated, 100, nil)
+	confidence, err := drivers.GetArtifactConfidence(context.Background(), test.NewMemoryDatabase(artifact), artifact.ID())
+	require.NoError(t, err)
+	assert.Equal(t, drivers.ArtifactConfidence{
+		ID:     artifact.ID(),
+		Status: drivers.ConfidenceNone,
+		Levels: []drivers.ConfidenceLevel{},
+	}, confidence)
+}
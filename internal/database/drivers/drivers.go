@@ -17,23 +17,96 @@ package drivers
 
 import (
 	"context"
+	"errors"
 	"net/url"
 
 	log "github.com/sirupsen/logrus"
 
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
 	"github.com/eiffel-community/eiffel-goer/internal/requests"
 )
 
 type EiffelEvent map[string]interface{}
 
+// ErrNotFound is returned, possibly wrapped, when a requested event isn't in the database.
+var ErrNotFound = errors.New("event not found")
+
+// ErrReadOnly is returned when events should be stored in a database that
+// doesn't implement Writer.
+var ErrReadOnly = errors.New("the database doesn't support storing events")
+
 type DatabaseDriver interface {
-	Get(context.Context, *url.URL, *log.Entry) (Database, error)
+	Get(context.Context, *url.URL, config.Config, *log.Entry) (Database, error)
 	SupportsScheme(string) bool
 }
 
+// EventStream iterates over events read from a database without loading
+// them all into memory. Next must be called before each call to Event,
+// and the stream must be closed when it's no longer needed.
+type EventStream interface {
+	// Next advances the stream to the next event and returns false
+	// when there are no more events or an error has occurred.
+	Next(context.Context) bool
+	// Event returns the current event.
+	Event() EiffelEvent
+	// Err returns the error, if any, that made Next return false.
+	Err() error
+	Close(context.Context) error
+}
+
 type Database interface {
-	GetEvents(context.Context, requests.MultipleEventsRequest) ([]EiffelEvent, int64, error)
-	UpstreamDownstreamSearch(context.Context, string) ([]EiffelEvent, error)
+	GetEvents(context.Context, requests.MultipleEventsRequest) (EventStream, int64, error)
+	UpstreamDownstreamSearch(context.Context, string, requests.SearchRequest) (SearchResult, error)
 	GetEventByID(context.Context, string) (EiffelEvent, error)
+	// GetEventsByIDs gets the events with any of the given IDs. IDs of
+	// events that aren't in the database are left out of the result.
+	GetEventsByIDs(context.Context, []string) ([]EiffelEvent, error)
+	// GetLinkingEvents gets the events that link to any of the target
+	// event IDs with any of the link types, or with any link type if
+	// no link types are given.
+	GetLinkingEvents(ctx context.Context, targetIDs []string, linkTypes []string) ([]EiffelEvent, error)
 	Close(context.Context) error
 }
+
+// ChangeFeed is implemented by databases that can notify about events as
+// they're stored. Databases that don't implement it, or fail to open a
+// change feed, are polled instead.
+type ChangeFeed interface {
+	// Watch returns a never-ending stream of newly stored events that
+	// match the conditions. Next blocks until an event arrives or the
+	// context is done.
+	Watch(context.Context, []query.Condition) (EventStream, error)
+}
+
+// StoredEvent is one stored copy of an event. Normally there's one copy of
+// each event but producers that retry sending may cause more.
+type StoredEvent struct {
+	Event EiffelEvent `json:"event"`
+	// Location describes where the copy is stored, e.g. its collection.
+	Location string `json:"location"`
+	// Ref identifies the copy to the database that returned it.
+	Ref interface{} `json:"-"`
+}
+
+// Writer is implemented by databases that events can be stored in.
+type Writer interface {
+	// InsertEvent stores an event. It doesn't check whether an event
+	// with the same ID is already stored, which is up to the caller.
+	InsertEvent(context.Context, EiffelEvent) error
+	// GetStoredEvents gets every stored copy of the event with the ID,
+	// the first stored copy first.
+	GetStoredEvents(ctx context.Context, id string) ([]StoredEvent, error)
+	// DeleteStoredEvents deletes copies returned by GetStoredEvents.
+	DeleteStoredEvents(context.Context, []StoredEvent) error
+	// DeleteEvents deletes every stored copy of the events with the IDs
+	// that matches the conditions and returns the number of copies deleted.
+	DeleteEvents(ctx context.Context, ids []string, conditions []query.Condition) (int, error)
+	// InsertConflict records an event that was ingested with the ID of a
+	// different stored event. It's kept apart from the stored events, so
+	// reading the event by ID still returns the stored one.
+	InsertConflict(context.Context, EiffelEvent) error
+	// GetConflicts gets the recorded conflicting events in the order they
+	// were recorded.
+	GetConflicts(context.Context) ([]StoredEvent, error)
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+)
+
+// Field returns the value of a field in the event given its dotted path,
+// e.g. "meta.id" or "links.0.target", and whether the field exists.
+// Nested objects may be of any map type with string keys since database
+// drivers tend to decode them into their own types.
+func (e EiffelEvent) Field(path string) (interface{}, bool) {
+	var value interface{} = map[string]interface{}(e)
+	for _, key := range strings.Split(path, ".") {
+		v := reflect.ValueOf(value)
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			elem := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+			if !elem.IsValid() {
+				return nil, false
+			}
+			value = elem.Interface()
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= v.Len() {
+				return nil, false
+			}
+			value = v.Index(i).Interface()
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// StringField returns the value of a string field in the event given its
+// dotted path, or an empty string if the field doesn't exist or isn't a string.
+func (e EiffelEvent) StringField(path string) string {
+	value, _ := e.Field(path)
+	s, _ := value.(string)
+	return s
+}
+
+// ID returns the meta.id of the event.
+func (e EiffelEvent) ID() string {
+	return e.StringField("meta.id")
+}
+
+// Type returns the meta.type of the event.
+func (e EiffelEvent) Type() string {
+	return e.StringField("meta.type")
+}
+
+// Time returns the meta.time of the event in milliseconds since the epoch,
+// or zero if it's missing.
+func (e EiffelEvent) Time() int64 {
+	value, _ := e.Field("meta.time")
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		return int64(v.Float())
+	default:
+		return 0
+	}
+}
+
+// Equal returns true if the events have the same content. They're compared
+// as JSON since events decoded by database drivers use other types than the
+// same events decoded from JSON, e.g. int64 instead of float64 for numbers.
+func (e EiffelEvent) Equal(other EiffelEvent) bool {
+	a, err := json.Marshal(e)
+	if err != nil {
+		return false
+	}
+	b, err := json.Marshal(other)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(a, b)
+}
+
+// SortByTime sorts events by meta.time, oldest first, keeping the order of
+// events with the same time.
+func SortByTime(events []EiffelEvent) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time() < events[j].Time()
+	})
+}
+
+// Link is an entry in the links array of an event.
+type Link struct {
+	Type     string `json:"type"`
+	Target   string `json:"target"`
+	DomainID string `json:"domainId,omitempty"`
+}
+
+// Links returns the links of the event.
+func (e EiffelEvent) Links() []Link {
+	var links []Link
+	for i := 0; ; i++ {
+		prefix := "links." + strconv.Itoa(i) + "."
+		linkType := e.StringField(prefix + "type")
+		if linkType == "" {
+			return links
+		}
+		links = append(links, Link{
+			Type:     linkType,
+			Target:   e.StringField(prefix + "target"),
+			DomainID: e.StringField(prefix + "domainId"),
+		})
+	}
+}
+
+// Matches returns true if the event matches all the conditions.
+func (e EiffelEvent) Matches(conditions []query.Condition) bool {
+	for _, condition := range conditions {
+		if !condition.Match(e.Field(condition.Field)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+)
+
+// nestedMap is a named map type like the ones database drivers decode nested documents into.
+type nestedMap map[string]interface{}
+
+// Test that fields can be looked up by their dotted paths regardless of the map types used.
+func TestField(t *testing.T) {
+	event := EiffelEvent{
+		"meta": nestedMap{"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "time": int64(1629449650361)},
+		"links": []interface{}{
+			EiffelEvent{"type": "CAUSE", "target": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"},
+		},
+	}
+	tests := []struct {
+		name   string
+		path   string
+		value  interface{}
+		exists bool
+	}{
+		{name: "Nested", path: "meta.id", value: "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", exists: true},
+		{name: "NonString", path: "meta.time", value: int64(1629449650361), exists: true},
+		{name: "ArrayIndex", path: "links.0.type", value: "CAUSE", exists: true},
+		{name: "ArrayOutOfRange", path: "links.1.type", exists: false},
+		{name: "Missing", path: "data.name", exists: false},
+		{name: "IntoScalar", path: "meta.id.foo", exists: false},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			value, exists := event.Field(testCase.path)
+			assert.Equal(t, testCase.exists, exists)
+			assert.Equal(t, testCase.value, value)
+		})
+	}
+	assert.Equal(t, "CAUSE", event.StringField("links.0.type"))
+	assert.Equal(t, "", event.StringField("meta.time"))
+}
+
+// Test the accessors for common meta fields and matching against conditions.
+func TestMetaAndMatches(t *testing.T) {
+	event := EiffelEvent{
+		"meta": nestedMap{"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "type": "EiffelActivityTriggeredEvent", "time": 1629449650361.0},
+		"data": nestedMap{"name": "Test activity"},
+	}
+	assert.Equal(t, "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", event.ID())
+	assert.Equal(t, "EiffelActivityTriggeredEvent", event.Type())
+	assert.Equal(t, int64(1629449650361), event.Time())
+	assert.Equal(t, int64(0), EiffelEvent{}.Time())
+
+	assert.True(t, event.Matches(nil))
+	assert.True(t, event.Matches([]query.Condition{
+		{Field: "meta.type", Op: "=", Value: "EiffelActivityTriggeredEvent"},
+		{Field: "meta.time", Op: ">", Value: "1629449650000", TypeConv: "int"},
+		{Field: "data.name", Op: "exists", Value: "true", TypeConv: "bool"},
+	}))
+	assert.False(t, event.Matches([]query.Condition{
+		{Field: "meta.type", Op: "=", Value: "EiffelActivityTriggeredEvent"},
+		{Field: "data.name", Op: "!=", Value: "Test activity"},
+	}))
+}
+
+// Test that links are read from the event.
+func TestLinks(t *testing.T) {
+	event := EiffelEvent{
+		"links": []interface{}{
+			nestedMap{"type": "CAUSE", "target": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"},
+			nestedMap{"type": "CONTEXT", "target": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "domainId": "other"},
+		},
+	}
+	assert.Equal(t, []Link{
+		{Type: "CAUSE", Target: "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"},
+		{Type: "CONTEXT", Target: "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", DomainID: "other"},
+	}, event.Links())
+	assert.Empty(t, EiffelEvent{}.Links())
+}
+
+// Test that events are compared by content regardless of the types used.
+func TestEqual(t *testing.T) {
+	decoded := EiffelEvent{
+		"meta": nestedMap{"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "time": int64(1629449650361)},
+		"data": nestedMap{"name": "build"},
+	}
+	assert.True(t, decoded.Equal(EiffelEvent{
+		"data": map[string]interface{}{"name": "build"},
+		"meta": map[string]interface{}{"time": float64(1629449650361), "id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84"},
+	}))
+	assert.False(t, decoded.Equal(EiffelEvent{
+		"meta": map[string]interface{}{"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "time": float64(1629449650361)},
+		"data": map[string]interface{}{"name": "test"},
+	}))
+}
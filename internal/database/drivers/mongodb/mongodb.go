@@ -24,15 +24,20 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"regexp"
 	"strconv"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 
+	"github.com/eiffel-community/eiffel-goer/internal/config"
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
 	"github.com/eiffel-community/eiffel-goer/internal/query"
 	"github.com/eiffel-community/eiffel-goer/internal/requests"
@@ -45,7 +50,7 @@ type Driver struct {
 }
 
 // Get creates and connects a new database.Database interface against MongoDB.
-func (d *Driver) Get(ctx context.Context, connectionURL *url.URL, logger *log.Entry) (drivers.Database, error) {
+func (d *Driver) Get(ctx context.Context, connectionURL *url.URL, cfg config.Config, logger *log.Entry) (drivers.Database, error) {
 	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connectionURL.String()))
 	if err != nil {
 		return nil, err
@@ -60,9 +65,13 @@ func (d *Driver) Get(ctx context.Context, connectionURL *url.URL, logger *log.En
 		return &Database{}, err
 	}
 	return &Database{
-		database: d.client.Database(d.connectionString.Database),
-		client:   d.client,
-		logger:   logger,
+		database:           d.client.Database(d.connectionString.Database),
+		client:             d.client,
+		logger:             logger,
+		idIndexCollection:  cfg.IDIndexCollection(),
+		workers:            cfg.DBWorkers(),
+		createIndexes:      cfg.CreateIndexes(),
+		indexedCollections: map[string]struct{}{},
 	}, nil
 }
 
@@ -78,11 +87,51 @@ func (d *Driver) SupportsScheme(scheme string) bool {
 	}
 }
 
+// collectionCacheTTL is how long the list of collection names is cached.
+const collectionCacheTTL = 10 * time.Second
+
+// conflictCollection is the name of the collection with the events that
+// were ingested with the IDs of different stored events.
+const conflictCollection = "goerConflicts"
+
 // Database is a connected database interface for requesting events from MongoDB.
 type Database struct {
 	database *mongo.Database
 	client   *mongo.Client
 	logger   *log.Entry
+
+	// idIndexCollection is the name of the collection mapping event IDs
+	// to the collections the events are stored in. Empty if disabled.
+	idIndexCollection string
+
+	// workers is the maximum number of collections to query concurrently.
+	workers int
+
+	// collectionCache holds the names of the event collections, refreshed
+	// when they're older than collectionCacheTTL.
+	collectionCacheMu      sync.Mutex
+	collectionCache        []string
+	collectionCacheExpires time.Time
+
+	// createIndexes enables creation of the indexes in eventIndexes on
+	// the event collections. indexedCollections are the collections
+	// that the indexes have been created on, guarded by collectionCacheMu.
+	createIndexes      bool
+	indexedCollections map[string]struct{}
+}
+
+// eventIndexes are the indexes created on event collections when enabled.
+// links.target is needed to find the events linking to an event without
+// scanning all events.
+var eventIndexes = []mongo.IndexModel{
+	{Keys: bson.D{{Key: "meta.id", Value: 1}}},
+	{Keys: bson.D{{Key: "links.target", Value: 1}}},
+}
+
+// idIndexEntry is a document in the event ID index collection.
+type idIndexEntry struct {
+	ID         string `bson:"_id"`
+	Collection string `bson:"collection"`
 }
 
 // operators is a translation table from query.Param to mongodb operators.
@@ -131,17 +180,186 @@ func (m *Database) collections(ctx context.Context, filter bson.D) ([]string, er
 	typeConditions := m.findDValue(filter, "meta.type")
 	// meta.type not set, return all collections.
 	if typeConditions == nil {
-		return m.database.ListCollectionNames(ctx, bson.D{})
+		return m.eventCollections(ctx)
+	}
+
+	var candidates []string
+	if typeValue := m.findDValue(typeConditions.(bson.D), "$eq"); typeValue != nil {
+		// With an $eq condition there's at most one collection to look in,
+		// so there's no need to ask the database for the full list.
+		name, ok := typeValue.(string)
+		if !ok {
+			return nil, nil
+		}
+		candidates = []string{name}
+	} else {
+		var err error
+		if candidates, err = m.eventCollections(ctx); err != nil {
+			return nil, err
+		}
+	}
+	collections := make([]string, 0, len(candidates))
+	for _, name := range candidates {
+		if collectionMatches(name, typeConditions.(bson.D)) {
+			collections = append(collections, name)
+		}
+	}
+	return collections, nil
+}
+
+// collectionMatches evaluates meta.type conditions against a collection name,
+// which is the same as the meta.type of all events in the collection.
+// Operators that can't be evaluated are assumed to match.
+func collectionMatches(name string, conditions bson.D) bool {
+	for _, condition := range conditions {
+		if !collectionMatchesOperator(name, condition.Key, condition.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// collectionMatchesOperator evaluates a single meta.type condition against
+// a collection name.
+func collectionMatchesOperator(name string, op string, operand interface{}) bool {
+	switch value := operand.(type) {
+	case string:
+		switch op {
+		case "$eq":
+			return name == value
+		case "$ne":
+			return name != value
+		case "$gt":
+			return name > value
+		case "$gte":
+			return name >= value
+		case "$lt":
+			return name < value
+		case "$lte":
+			return name <= value
+		case "$regex":
+			re, err := regexp.Compile(value)
+			return err != nil || re.MatchString(name)
+		}
+	case primitive.Regex:
+		if op == "$regex" {
+			re, err := regexp.Compile(value.Pattern)
+			return err != nil || re.MatchString(name)
+		}
+	case []string:
+		return collectionMatchesOperator(name, op, stringsToArray(value))
+	case bson.A:
+		if op == "$in" || op == "$nin" {
+			found := false
+			for _, v := range value {
+				s, ok := v.(string)
+				if !ok {
+					// E.g. a regular expression; don't try to evaluate it.
+					return true
+				}
+				if s == name {
+					found = true
+				}
+			}
+			return found == (op == "$in")
+		}
+	case bool:
+		if op == "$exists" {
+			return value
+		}
+	default:
+		// meta.type is always a string so comparisons against other
+		// types, e.g. after a type conversion, never match.
+		switch op {
+		case "$eq", "$gt", "$gte", "$lt", "$lte":
+			return false
+		}
+	}
+	return true
+}
+
+// stringsToArray converts a slice of strings to a bson.A.
+func stringsToArray(values []string) bson.A {
+	a := make(bson.A, len(values))
+	for i, v := range values {
+		a[i] = v
+	}
+	return a
+}
+
+// internalCollections returns the names of the collections used internally by Goer.
+func (m *Database) internalCollections() []string {
+	collections := []string{conflictCollection}
+	if m.idIndexCollection != "" {
+		collections = append(collections, m.idIndexCollection)
 	}
+	return collections
+}
 
-	typeValue := m.findDValue(typeConditions.(bson.D), "$eq")
-	// No $eq. Apply collection filter to reduce the collection names
-	// request.
-	if typeValue == nil {
-		// TODO: Collection filter
-		return m.database.ListCollectionNames(ctx, bson.D{})
+// isInternalCollection returns true if the named collection is used internally
+// by Goer and doesn't contain events.
+func (m *Database) isInternalCollection(name string) bool {
+	for _, internal := range m.internalCollections() {
+		if name == internal {
+			return true
+		}
+	}
+	return false
+}
+
+// eventCollections returns the names of all collections that contain events,
+// i.e. all collections except the ones used internally by Goer. The names
+// are cached for collectionCacheTTL to avoid listing the collections on
+// every request.
+func (m *Database) eventCollections(ctx context.Context) ([]string, error) {
+	m.collectionCacheMu.Lock()
+	defer m.collectionCacheMu.Unlock()
+	if m.collectionCache != nil && time.Now().Before(m.collectionCacheExpires) {
+		return m.collectionCache, nil
+	}
+	names, err := m.database.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	collections := make([]string, 0, len(names))
+	for _, name := range names {
+		if m.isInternalCollection(name) {
+			continue
+		}
+		collections = append(collections, name)
+	}
+	m.collectionCache = collections
+	m.collectionCacheExpires = time.Now().Add(collectionCacheTTL)
+	if m.createIndexes {
+		var unindexed []string
+		for _, collection := range collections {
+			if _, ok := m.indexedCollections[collection]; !ok {
+				m.indexedCollections[collection] = struct{}{}
+				unindexed = append(unindexed, collection)
+			}
+		}
+		// Building indexes on large collections takes a while,
+		// so don't make the request wait for it.
+		if len(unindexed) > 0 {
+			go m.ensureIndexes(context.Background(), unindexed)
+		}
+	}
+	return collections, nil
+}
+
+// ensureIndexes creates the indexes in eventIndexes on the collections
+// unless they already exist.
+func (m *Database) ensureIndexes(ctx context.Context, collections []string) {
+	for _, collection := range collections {
+		if _, err := m.database.Collection(collection).Indexes().CreateMany(ctx, eventIndexes); err != nil {
+			m.logger.Warningf("Error creating indexes on %q: %s", collection, err)
+			m.collectionCacheMu.Lock()
+			delete(m.indexedCollections, collection)
+			m.collectionCacheMu.Unlock()
+			continue
+		}
+		m.logger.Debugf("Ensured indexes on %q", collection)
 	}
-	return []string{typeValue.(string)}, nil
 }
 
 // findDValue walks through a bson.D and returns the value of the first matching key.
@@ -154,8 +372,112 @@ func (m *Database) findDValue(d bson.D, key string) interface{} {
 	return nil
 }
 
-// GetEvents gets all events information.
-func (m *Database) GetEvents(ctx context.Context, request requests.MultipleEventsRequest) ([]drivers.EiffelEvent, int64, error) {
+// forEachCollection calls fn once for each collection, running at most
+// m.workers calls concurrently. It returns when all calls have returned.
+func (m *Database) forEachCollection(collections []string, fn func(i int, collection string)) {
+	workers := m.workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(collections) {
+		workers = len(collections)
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i, collections[i])
+			}
+		}()
+	}
+	for i := range collections {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// collectionResult is the outcome of querying a single collection for a page of events.
+type collectionResult struct {
+	cursor *mongo.Cursor
+	count  int64
+	err    error
+}
+
+// queryCollection opens a cursor over up to a full page of events matching
+// the filter in a collection and counts the total number of matching events
+// in it. The cursor is nil if the page size is zero.
+func (m *Database) queryCollection(ctx context.Context, collection string, filter bson.D, request requests.MultipleEventsRequest) collectionResult {
+	var result collectionResult
+	col := m.database.Collection(collection)
+	// fetched is the number of matching events in the collection, if we
+	// know it without counting. That's the case when the server returned
+	// all of them in the first batch of the cursor.
+	fetched := -1
+	if request.PageSize > 0 || request.Unpaged {
+		findOptions := options.Find().SetProjection(bson.M{"_id": 0})
+		if !request.Unpaged {
+			findOptions.
+				SetSkip(int64((request.PageNo - 1) * request.PageSize)).
+				SetLimit(int64(request.PageSize))
+		}
+		result.cursor, result.err = col.Find(ctx, filter, findOptions)
+		if result.err != nil {
+			return result
+		}
+		if result.cursor.ID() == 0 {
+			fetched = result.cursor.RemainingBatchLength()
+		}
+	}
+	result.count = m.countCollection(ctx, col, filter, request, fetched)
+	return result
+}
+
+// countCollection counts the events matching the filter in a collection
+// according to the request's count strategy. fetched is the number of events
+// that were returned for the requested page, or -1 if not known.
+func (m *Database) countCollection(ctx context.Context, col *mongo.Collection, filter bson.D, request requests.MultipleEventsRequest, fetched int) int64 {
+	if request.Count == requests.CountNone {
+		return 0
+	}
+	if request.Unpaged {
+		if fetched >= 0 {
+			return int64(fetched)
+		}
+		count, _ := col.CountDocuments(ctx, filter)
+		return count
+	}
+	// If the first page isn't full we've already seen all matching
+	// events and can skip the count.
+	if request.PageNo == 1 && request.PageSize > 0 && fetched >= 0 && int32(fetched) < request.PageSize {
+		return int64(fetched)
+	}
+	countOptions := options.Count()
+	if request.Count == requests.CountEstimated {
+		if len(filter) == 0 {
+			count, err := col.EstimatedDocumentCount(ctx)
+			if err == nil {
+				return count
+			}
+			m.logger.Debugf("Error estimating the number of documents in %q: %s", col.Name(), err)
+		}
+		// Count just far enough to tell whether there's a page after
+		// the requested one.
+		countOptions.SetLimit(int64(request.PageNo*request.PageSize) + 1)
+	}
+	count, _ := col.CountDocuments(ctx, filter, countOptions)
+	return count
+}
+
+// GetEvents gets all events information. The collections are queried
+// concurrently but the events are streamed in collection order, so the
+// returned page is the same as if they had been queried one by one.
+// The returned count is -1 if the request asks for no count. Unpaged
+// requests stream all matching events from all collections.
+func (m *Database) GetEvents(ctx context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
 	filter, err := buildFilter(request.Conditions)
 	if err != nil {
 		m.logger.Errorf("Database: %v", err)
@@ -168,62 +490,324 @@ func (m *Database) GetEvents(ctx context.Context, request requests.MultipleEvent
 	}
 
 	m.logger.Debugf("fetching events from %d collections", len(collections))
-	allEvents := make([]drivers.EiffelEvent, 0, request.PageSize)
+	results := make([]collectionResult, len(collections))
+	m.forEachCollection(collections, func(i int, collection string) {
+		results[i] = m.queryCollection(ctx, collection, filter, request)
+	})
+
+	cursors := make([]*mongo.Cursor, 0, len(results))
 	var numberOfDocuments int64
-	for _, collection := range collections {
-		var events []drivers.EiffelEvent
-		limit := request.PageSize - int32(len(allEvents))
-
-		col := m.database.Collection(collection)
-		if limit > 0 {
-			cursor, err := col.Find(ctx, filter, options.Find().
-				SetProjection(bson.M{"_id": 0}).
-				SetSkip(int64((request.PageNo-1)*request.PageSize)).
-				SetLimit(int64(limit)),
-			)
-			if err != nil {
-				continue
-			}
-			if err = cursor.All(ctx, &events); err != nil {
-				m.logger.Info(err.Error())
-				continue
-			}
-			allEvents = append(allEvents, events...)
+	for i, result := range results {
+		if result.err != nil {
+			m.logger.Infof("Error fetching events from %q: %s", collections[i], result.err)
+			continue
 		}
-		if len(events) > 0 && int32(len(events)) < limit && request.PageNo == 1 {
-			numberOfDocuments += int64(len(events))
-		} else {
-			count, _ := col.CountDocuments(ctx, filter, &options.CountOptions{})
-			numberOfDocuments += count
+		if result.cursor != nil {
+			cursors = append(cursors, result.cursor)
 		}
+		numberOfDocuments += result.count
 	}
-	return allEvents, numberOfDocuments, nil
+	if request.Count == requests.CountNone {
+		numberOfDocuments = -1
+	}
+	limit := int(request.PageSize)
+	if request.Unpaged {
+		limit = 0
+	}
+	return newCursorStream(cursors, limit), numberOfDocuments, nil
 }
 
 // UpstreamDownstreamSearch searches for events upstream and/or downstream of event by ID.
-func (m *Database) UpstreamDownstreamSearch(_ context.Context, _ string) ([]drivers.EiffelEvent, error) {
-	return nil, errors.New("not yet implemented")
+func (m *Database) UpstreamDownstreamSearch(ctx context.Context, id string, request requests.SearchRequest) (drivers.SearchResult, error) {
+	return drivers.UpstreamDownstreamSearch(ctx, m, id, request)
 }
 
-// GetEventByID gets an event by ID in all collections.
+// GetEventByID gets an event by ID in all collections. If the event ID index
+// is enabled it's consulted first, otherwise (or if the index doesn't know about
+// the event) all collections are searched in parallel.
 func (m *Database) GetEventByID(ctx context.Context, id string) (drivers.EiffelEvent, error) {
-	collections, err := m.collections(ctx, bson.D{})
+	if collection := m.lookupIDIndex(ctx, id); collection != "" {
+		event, err := m.findEvent(ctx, collection, id)
+		if err == nil {
+			return event, nil
+		}
+		m.logger.Debugf("Event %q not found in %q as indicated by the ID index: %s", id, collection, err)
+	}
+	collections, err := m.eventCollections(ctx)
 	if err != nil {
 		return nil, err
 	}
-	filter := bson.D{{Key: "meta.id", Value: id}}
-	for _, collection := range collections {
-		var event bson.M
-		singleResult := m.database.Collection(collection).FindOne(ctx, filter,
-			// Remove the _id field from the resulting document.
-			options.FindOne().SetProjection(bson.M{"_id": 0}))
-		err := singleResult.Decode(&event)
+	event, collection, err := m.findEventInCollections(ctx, id, collections)
+	if err != nil {
+		return nil, err
+	}
+	m.updateIDIndex(ctx, id, collection)
+	return event, nil
+}
+
+// findEvent gets an event by ID from a single collection.
+func (m *Database) findEvent(ctx context.Context, collection string, id string) (drivers.EiffelEvent, error) {
+	stored, err := m.findStoredEvent(ctx, collection, id)
+	if err != nil {
+		return nil, err
+	}
+	return stored.Event, nil
+}
+
+// findStoredEvent gets the first stored copy of an event from a single collection.
+func (m *Database) findStoredEvent(ctx context.Context, collection string, id string) (drivers.StoredEvent, error) {
+	var document bson.M
+	singleResult := m.database.Collection(collection).FindOne(ctx, bson.D{{Key: "meta.id", Value: id}},
+		options.FindOne().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err := singleResult.Decode(&document); err != nil {
+		return drivers.StoredEvent{}, err
+	}
+	ref := storedEventRef{collection: collection, id: document["_id"]}
+	delete(document, "_id")
+	return drivers.StoredEvent{Event: drivers.EiffelEvent(document), Location: collection, Ref: ref}, nil
+}
+
+// findEventInCollections searches for an event in all the given collections
+// concurrently and returns the first stored copy, i.e. the same copy that
+// GetStoredEvents lists first, along with the name of the collection where
+// it was found. Copies that can't be ordered by ObjectID are ordered by
+// collection.
+func (m *Database) findEventInCollections(ctx context.Context, id string, collections []string) (drivers.EiffelEvent, string, error) {
+	copies := make([]*drivers.StoredEvent, len(collections))
+	m.forEachCollection(collections, func(i int, collection string) {
+		stored, err := m.findStoredEvent(ctx, collection, id)
+		if err != nil {
+			if !errors.Is(err, mongo.ErrNoDocuments) {
+				m.logger.Debugf("Error looking up %q in %q: %s", id, collection, err)
+			}
+			return
+		}
+		copies[i] = &stored
+	})
+	var found []drivers.StoredEvent
+	for _, stored := range copies {
+		if stored != nil {
+			found = append(found, *stored)
+		}
+	}
+	if len(found) == 0 {
+		return nil, "", fmt.Errorf("%w: %q isn't in any collection", drivers.ErrNotFound, id)
+	}
+	sortStoredEvents(found)
+	return found[0].Event, found[0].Location, nil
+}
+
+// lookupIDIndex returns the name of the collection that the event ID index
+// says contains the event with the given ID, or an empty string if the index
+// is disabled or doesn't contain the ID.
+func (m *Database) lookupIDIndex(ctx context.Context, id string) string {
+	if m.idIndexCollection == "" {
+		return ""
+	}
+	var entry idIndexEntry
+	err := m.database.Collection(m.idIndexCollection).FindOne(ctx, bson.D{{Key: "_id", Value: id}}).Decode(&entry)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			m.logger.Warningf("Error looking up %q in the ID index: %s", id, err)
+		}
+		return ""
+	}
+	return entry.Collection
+}
+
+// updateIDIndex records in the event ID index which collection an event is
+// stored in. Failures are logged but otherwise ignored since the index
+// is only an optimization.
+func (m *Database) updateIDIndex(ctx context.Context, id string, collection string) {
+	m.updateIDIndexMany(ctx, map[string]string{id: collection})
+}
+
+// updateIDIndexMany records in the event ID index which collections events,
+// given as a map from ID to collection, are stored in.
+func (m *Database) updateIDIndexMany(ctx context.Context, collections map[string]string) {
+	if m.idIndexCollection == "" || len(collections) == 0 {
+		return
+	}
+	models := make([]mongo.WriteModel, 0, len(collections))
+	for id, collection := range collections {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{Key: "_id", Value: id}}).
+			SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "collection", Value: collection}}}}).
+			SetUpsert(true))
+	}
+	_, err := m.database.Collection(m.idIndexCollection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		m.logger.Warningf("Error updating the ID index for %d events: %s", len(collections), err)
+	}
+}
+
+// lookupIDIndexMany returns the collections that the event ID index says
+// contain the events with the given IDs, mapped to the IDs in each collection.
+// IDs that aren't in the index are left out.
+func (m *Database) lookupIDIndexMany(ctx context.Context, ids []string) map[string][]string {
+	byCollection := map[string][]string{}
+	if m.idIndexCollection == "" {
+		return byCollection
+	}
+	cursor, err := m.database.Collection(m.idIndexCollection).Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}})
+	if err != nil {
+		m.logger.Warningf("Error looking up %d events in the ID index: %s", len(ids), err)
+		return byCollection
+	}
+	var entries []idIndexEntry
+	if err := cursor.All(ctx, &entries); err != nil {
+		m.logger.Warningf("Error looking up %d events in the ID index: %s", len(ids), err)
+		return byCollection
+	}
+	for _, entry := range entries {
+		byCollection[entry.Collection] = append(byCollection[entry.Collection], entry.ID)
+	}
+	return byCollection
+}
+
+// GetEventsByIDs gets the events with any of the given IDs, making at most one
+// query per collection. Collections that the event ID index points to are
+// queried first and the remaining IDs are then searched for in all collections.
+// The events are returned in the order of the IDs.
+func (m *Database) GetEventsByIDs(ctx context.Context, ids []string) ([]drivers.EiffelEvent, error) {
+	ids = uniqueStrings(ids)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	found := make(map[string]drivers.EiffelEvent, len(ids))
+
+	byCollection := m.lookupIDIndexMany(ctx, ids)
+	indexed := make([]string, 0, len(byCollection))
+	for collection := range byCollection {
+		indexed = append(indexed, collection)
+	}
+	if _, err := m.findEventsInCollections(ctx, indexed, func(collection string) []string {
+		return byCollection[collection]
+	}, found); err != nil {
+		return nil, err
+	}
+
+	var remaining []string
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			remaining = append(remaining, id)
+		}
+	}
+	if len(remaining) > 0 {
+		collections, err := m.eventCollections(ctx)
+		if err != nil {
+			return nil, err
+		}
+		foundIn, err := m.findEventsInCollections(ctx, collections, func(string) []string {
+			return remaining
+		}, found)
 		if err != nil {
+			return nil, err
+		}
+		m.updateIDIndexMany(ctx, foundIn)
+	}
+
+	events := make([]drivers.EiffelEvent, 0, len(found))
+	for _, id := range ids {
+		if event, ok := found[id]; ok {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// findEventsInCollections queries the given collections concurrently for the
+// events with the IDs returned by idsIn for each collection, adding the events
+// to found. It returns the collections that the events were found in.
+func (m *Database) findEventsInCollections(ctx context.Context, collections []string, idsIn func(collection string) []string, found map[string]drivers.EiffelEvent) (map[string]string, error) {
+	foundIn := map[string]string{}
+	var mu sync.Mutex
+	var firstErr error
+	m.forEachCollection(collections, func(_ int, collection string) {
+		ids := idsIn(collection)
+		if len(ids) == 0 {
+			return
+		}
+		cursor, err := m.database.Collection(collection).Find(ctx,
+			bson.D{{Key: "meta.id", Value: bson.D{{Key: "$in", Value: ids}}}},
+			options.Find().SetProjection(bson.M{"_id": 0}))
+		var events []bson.M
+		if err == nil {
+			err = cursor.All(ctx, &events)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		for _, event := range events {
+			event := drivers.EiffelEvent(event)
+			if _, ok := found[event.ID()]; !ok {
+				found[event.ID()] = event
+				foundIn[event.ID()] = collection
+			}
+		}
+	})
+	return foundIn, firstErr
+}
+
+// GetLinkingEvents gets the events that link to any of the target event IDs
+// with any of the link types, or with any link type if none are given. All
+// event collections are queried concurrently, so an index on links.target in
+// each collection is strongly recommended.
+func (m *Database) GetLinkingEvents(ctx context.Context, targetIDs []string, linkTypes []string) ([]drivers.EiffelEvent, error) {
+	if len(targetIDs) == 0 {
+		return nil, nil
+	}
+	collections, err := m.eventCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	linkCondition := bson.D{{Key: "target", Value: bson.D{{Key: "$in", Value: uniqueStrings(targetIDs)}}}}
+	if len(linkTypes) > 0 {
+		linkCondition = append(linkCondition, bson.E{Key: "type", Value: bson.D{{Key: "$in", Value: linkTypes}}})
+	}
+	filter := bson.D{{Key: "links", Value: bson.D{{Key: "$elemMatch", Value: linkCondition}}}}
+
+	results := make([][]bson.M, len(collections))
+	errs := make([]error, len(collections))
+	m.forEachCollection(collections, func(i int, collection string) {
+		cursor, err := m.database.Collection(collection).Find(ctx, filter,
+			options.Find().SetProjection(bson.M{"_id": 0}))
+		if err != nil {
+			errs[i] = err
+			return
+		}
+		errs[i] = cursor.All(ctx, &results[i])
+	})
+	var events []drivers.EiffelEvent
+	for i, result := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, event := range result {
+			events = append(events, drivers.EiffelEvent(event))
+		}
+	}
+	drivers.SortByTime(events)
+	return events, nil
+}
+
+// uniqueStrings returns the strings in order with duplicates removed.
+func uniqueStrings(strings []string) []string {
+	seen := make(map[string]struct{}, len(strings))
+	unique := make([]string, 0, len(strings))
+	for _, s := range strings {
+		if _, ok := seen[s]; ok {
 			continue
 		}
-		return drivers.EiffelEvent(event), nil
+		seen[s] = struct{}{}
+		unique = append(unique, s)
 	}
-	return nil, fmt.Errorf("%q not found in any collection", id)
+	return unique
 }
 
 // Close the database connection.
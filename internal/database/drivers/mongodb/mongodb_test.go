@@ -0,0 +1,82 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mongodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+)
+
+// Test that meta.type conditions are correctly evaluated against collection names.
+func TestCollectionMatches(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions bson.D
+		expected   []string
+	}{
+		{name: "NotEqual", conditions: bson.D{{Key: "$ne", Value: "EiffelActivityStartedEvent"}}, expected: []string{"EiffelActivityFinishedEvent", "EiffelArtifactCreatedEvent"}},
+		{name: "Range", conditions: bson.D{{Key: "$gte", Value: "EiffelActivity"}, {Key: "$lt", Value: "EiffelArtifact"}}, expected: []string{"EiffelActivityFinishedEvent", "EiffelActivityStartedEvent"}},
+		{name: "Regex", conditions: bson.D{{Key: "$regex", Value: "^EiffelArtifact"}}, expected: []string{"EiffelArtifactCreatedEvent"}},
+		{name: "RegexValue", conditions: bson.D{{Key: "$regex", Value: primitive.Regex{Pattern: "Finished"}}}, expected: []string{"EiffelActivityFinishedEvent"}},
+		{name: "BadRegex", conditions: bson.D{{Key: "$regex", Value: "("}}, expected: []string{"EiffelActivityFinishedEvent", "EiffelActivityStartedEvent", "EiffelArtifactCreatedEvent"}},
+		{name: "In", conditions: bson.D{{Key: "$in", Value: []string{"EiffelActivityStartedEvent", "EiffelOtherEvent"}}}, expected: []string{"EiffelActivityStartedEvent"}},
+		{name: "NotIn", conditions: bson.D{{Key: "$nin", Value: bson.A{"EiffelActivityStartedEvent"}}}, expected: []string{"EiffelActivityFinishedEvent", "EiffelArtifactCreatedEvent"}},
+		{name: "NotExists", conditions: bson.D{{Key: "$exists", Value: false}}, expected: []string{}},
+		{name: "TypeConverted", conditions: bson.D{{Key: "$gt", Value: int64(1)}}, expected: []string{}},
+		{name: "Unknown", conditions: bson.D{{Key: "$size", Value: int64(1)}}, expected: []string{"EiffelActivityFinishedEvent", "EiffelActivityStartedEvent", "EiffelArtifactCreatedEvent"}},
+	}
+	collections := []string{"EiffelActivityFinishedEvent", "EiffelActivityStartedEvent", "EiffelArtifactCreatedEvent"}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			matching := []string{}
+			for _, collection := range collections {
+				if collectionMatches(collection, testCase.conditions) {
+					matching = append(matching, collection)
+				}
+			}
+			assert.Equal(t, testCase.expected, matching)
+		})
+	}
+}
+
+// Test that duplicates are removed while keeping the order of the strings.
+func TestUniqueStrings(t *testing.T) {
+	assert.Equal(t, []string{"b", "a", "c"}, uniqueStrings([]string{"b", "a", "b", "c", "a"}))
+	assert.Equal(t, []string{}, uniqueStrings(nil))
+}
+
+// Test that stored copies are sorted by ObjectID with other IDs last.
+func TestSortStoredEvents(t *testing.T) {
+	first := primitive.NewObjectIDFromTimestamp(time.Unix(1000, 0))
+	second := primitive.NewObjectIDFromTimestamp(time.Unix(2000, 0))
+	stored := []drivers.StoredEvent{
+		{Location: "custom", Ref: storedEventRef{collection: "custom", id: "custom"}},
+		{Location: "second", Ref: storedEventRef{collection: "second", id: second}},
+		{Location: "first", Ref: storedEventRef{collection: "first", id: first}},
+	}
+	sortStoredEvents(stored)
+	locations := []string{}
+	for _, event := range stored {
+		locations = append(locations, event.Location)
+	}
+	assert.Equal(t, []string{"first", "second", "custom"}, locations)
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+)
+
+// cursorStream is a drivers.EventStream that reads events from a number
+// of cursors, one after another, until a limit is reached.
+type cursorStream struct {
+	cursors   []*mongo.Cursor
+	remaining int
+	event     drivers.EiffelEvent
+	err       error
+}
+
+// newCursorStream returns a stream of at most limit events from the cursors.
+// A limit of zero or less means that there's no limit.
+func newCursorStream(cursors []*mongo.Cursor, limit int) *cursorStream {
+	if limit <= 0 {
+		limit = -1
+	}
+	return &cursorStream{cursors: cursors, remaining: limit}
+}
+
+// Next advances the stream to the next event.
+func (s *cursorStream) Next(ctx context.Context) bool {
+	s.event = nil
+	for s.err == nil && s.remaining != 0 && len(s.cursors) > 0 {
+		cursor := s.cursors[0]
+		if cursor.Next(ctx) {
+			var event drivers.EiffelEvent
+			if s.err = cursor.Decode(&event); s.err != nil {
+				return false
+			}
+			s.event = event
+			if s.remaining > 0 {
+				s.remaining--
+			}
+			return true
+		}
+		if s.err = cursor.Err(); s.err != nil {
+			return false
+		}
+		s.err = cursor.Close(ctx)
+		s.cursors = s.cursors[1:]
+	}
+	return false
+}
+
+// Event returns the current event.
+func (s *cursorStream) Event() drivers.EiffelEvent {
+	return s.event
+}
+
+// Err returns the error that made Next return false, if any.
+func (s *cursorStream) Err() error {
+	return s.err
+}
+
+// Close the stream and all of its remaining cursors.
+func (s *cursorStream) Close(ctx context.Context) error {
+	var err error
+	for _, cursor := range s.cursors {
+		if closeErr := cursor.Close(ctx); err == nil {
+			err = closeErr
+		}
+	}
+	s.cursors = nil
+	return err
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+)
+
+// Watch opens a change stream over all event collections that yields newly
+// inserted events matching the conditions. Change streams are only available
+// when MongoDB runs as a replica set or a sharded cluster.
+func (m *Database) Watch(ctx context.Context, conditions []query.Condition) (drivers.EventStream, error) {
+	filter, err := buildFilter(conditions)
+	if err != nil {
+		return nil, err
+	}
+	match := bson.D{{Key: "operationType", Value: "insert"}}
+	for _, e := range filter {
+		match = append(match, bson.E{Key: "fullDocument." + e.Key, Value: e.Value})
+	}
+	if internal := m.internalCollections(); len(internal) > 0 {
+		match = append(match, bson.E{Key: "ns.coll", Value: bson.D{{Key: "$nin", Value: internal}}})
+	}
+	changeStream, err := m.database.Watch(ctx, mongo.Pipeline{{{Key: "$match", Value: match}}})
+	if err != nil {
+		return nil, err
+	}
+	return &changeStreamEvents{changeStream: changeStream}, nil
+}
+
+// changeStreamEvents is a drivers.EventStream over the documents
+// inserted according to a change stream.
+type changeStreamEvents struct {
+	changeStream *mongo.ChangeStream
+	event        drivers.EiffelEvent
+	err          error
+}
+
+// Next waits for the next inserted event.
+func (s *changeStreamEvents) Next(ctx context.Context) bool {
+	s.event = nil
+	if !s.changeStream.Next(ctx) {
+		s.err = s.changeStream.Err()
+		return false
+	}
+	var change struct {
+		FullDocument drivers.EiffelEvent `bson:"fullDocument"`
+	}
+	if s.err = s.changeStream.Decode(&change); s.err != nil {
+		return false
+	}
+	delete(change.FullDocument, "_id")
+	s.event = change.FullDocument
+	return true
+}
+
+// Event returns the current event.
+func (s *changeStreamEvents) Event() drivers.EiffelEvent {
+	return s.event
+}
+
+// Err returns the error that made Next return false, if any.
+func (s *changeStreamEvents) Err() error {
+	return s.err
+}
+
+// Close the change stream.
+func (s *changeStreamEvents) Close(ctx context.Context) error {
+	return s.changeStream.Close(ctx)
+}
@@ -0,0 +1,209 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package mongodb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+)
+
+// storedEventRef identifies a stored copy of an event.
+type storedEventRef struct {
+	collection string
+	id         interface{}
+}
+
+// InsertEvent stores an event in the collection named after its type and
+// records it in the event ID index.
+func (m *Database) InsertEvent(ctx context.Context, event drivers.EiffelEvent) error {
+	collection := event.Type()
+	if collection == "" || m.isInternalCollection(collection) {
+		return fmt.Errorf("can't store %q with the type %q", event.ID(), collection)
+	}
+	if _, err := m.database.Collection(collection).InsertOne(ctx, event); err != nil {
+		return err
+	}
+	m.updateIDIndex(ctx, event.ID(), collection)
+	return nil
+}
+
+// GetStoredEvents gets every copy of the event with the ID in all collections.
+// The copies are ordered by their _id, which for ObjectIDs is the order they
+// were stored in.
+func (m *Database) GetStoredEvents(ctx context.Context, id string) ([]drivers.StoredEvent, error) {
+	collections, err := m.eventCollections(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	var stored []drivers.StoredEvent
+	var errs []error
+	m.forEachCollection(collections, func(_ int, collection string) {
+		cursor, err := m.database.Collection(collection).Find(ctx, bson.D{{Key: "meta.id", Value: id}},
+			options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+		var documents []bson.M
+		if err == nil {
+			err = cursor.All(ctx, &documents)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, err)
+			return
+		}
+		for _, document := range documents {
+			ref := storedEventRef{collection: collection, id: document["_id"]}
+			delete(document, "_id")
+			stored = append(stored, drivers.StoredEvent{Event: drivers.EiffelEvent(document), Location: collection, Ref: ref})
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	sortStoredEvents(stored)
+	return stored, nil
+}
+
+// sortStoredEvents sorts copies by their ObjectIDs, and thereby the time
+// they were stored. Copies without ObjectIDs are kept in order last.
+func sortStoredEvents(stored []drivers.StoredEvent) {
+	sort.SliceStable(stored, func(i, j int) bool {
+		a, aOK := stored[i].Ref.(storedEventRef).id.(primitive.ObjectID)
+		b, bOK := stored[j].Ref.(storedEventRef).id.(primitive.ObjectID)
+		if aOK && bOK {
+			return bytes.Compare(a[:], b[:]) < 0
+		}
+		return aOK && !bOK
+	})
+}
+
+// DeleteStoredEvents deletes copies of events by their _id. Event ID index
+// entries pointing to collections without copies left are removed.
+func (m *Database) DeleteStoredEvents(ctx context.Context, stored []drivers.StoredEvent) error {
+	deleted := map[string]string{}
+	for _, event := range stored {
+		ref, ok := event.Ref.(storedEventRef)
+		if !ok {
+			return fmt.Errorf("%q in %q wasn't returned by MongoDB", event.Event.ID(), event.Location)
+		}
+		if _, err := m.database.Collection(ref.collection).DeleteOne(ctx, bson.D{{Key: "_id", Value: ref.id}}); err != nil {
+			return err
+		}
+		deleted[event.Event.ID()] = ref.collection
+	}
+	m.removeFromIDIndex(ctx, deleted)
+	return nil
+}
+
+// removeFromIDIndex removes the event ID index entries of events, given as
+// a map from ID to collection, that are no longer in those collections.
+// Failures are logged but otherwise ignored, like when updating the index.
+func (m *Database) removeFromIDIndex(ctx context.Context, collections map[string]string) {
+	if m.idIndexCollection == "" {
+		return
+	}
+	for id, collection := range collections {
+		left, err := m.database.Collection(collection).CountDocuments(ctx, bson.D{{Key: "meta.id", Value: id}},
+			options.Count().SetLimit(1))
+		if err == nil && left == 0 {
+			_, err = m.database.Collection(m.idIndexCollection).DeleteOne(ctx,
+				bson.D{{Key: "_id", Value: id}, {Key: "collection", Value: collection}})
+		}
+		if err != nil {
+			m.logger.Warningf("Error removing %q from the ID index: %s", id, err)
+		}
+	}
+}
+
+// deleteBatchSize is the maximum number of event IDs per delete request.
+const deleteBatchSize = 1000
+
+// DeleteEvents deletes every stored copy of the events with the IDs that
+// matches the conditions, with one request per batch of IDs in each
+// collection that the conditions may match. Event ID index entries pointing
+// to collections where copies were deleted are removed, and restored by the
+// next lookup if copies are left there.
+func (m *Database) DeleteEvents(ctx context.Context, ids []string, conditions []query.Condition) (int, error) {
+	filter, err := buildFilter(conditions)
+	if err != nil {
+		return 0, err
+	}
+	collections, err := m.collections(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for start := 0; start < len(ids); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch := bson.D{{Key: "$in", Value: stringsToArray(ids[start:end])}}
+		batchFilter := bson.D{{Key: "$and", Value: bson.A{bson.D{{Key: "meta.id", Value: batch}}, filter}}}
+		for _, collection := range collections {
+			result, err := m.database.Collection(collection).DeleteMany(ctx, batchFilter)
+			if err != nil {
+				return deleted, err
+			}
+			deleted += int(result.DeletedCount)
+			if result.DeletedCount > 0 && m.idIndexCollection != "" {
+				_, err := m.database.Collection(m.idIndexCollection).DeleteMany(ctx,
+					bson.D{{Key: "_id", Value: batch}, {Key: "collection", Value: collection}})
+				if err != nil {
+					m.logger.Warningf("Error removing deleted events from the ID index: %s", err)
+				}
+			}
+		}
+	}
+	return deleted, nil
+}
+
+// InsertConflict records a conflicting event in the conflict collection,
+// where it's not found when reading events.
+func (m *Database) InsertConflict(ctx context.Context, event drivers.EiffelEvent) error {
+	_, err := m.database.Collection(conflictCollection).InsertOne(ctx, event)
+	return err
+}
+
+// GetConflicts gets the events in the conflict collection, ordered by their _id.
+func (m *Database) GetConflicts(ctx context.Context) ([]drivers.StoredEvent, error) {
+	cursor, err := m.database.Collection(conflictCollection).Find(ctx, bson.D{},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var documents []bson.M
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	conflicts := make([]drivers.StoredEvent, 0, len(documents))
+	for _, document := range documents {
+		ref := storedEventRef{collection: conflictCollection, id: document["_id"]}
+		delete(document, "_id")
+		conflicts = append(conflicts, drivers.StoredEvent{Event: drivers.EiffelEvent(document), Location: conflictCollection, Ref: ref})
+	}
+	return conflicts, nil
+}
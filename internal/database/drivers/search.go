@@ -0,0 +1,159 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// LinkTypeAll is a link type in searches that matches links of any type.
+const LinkTypeAll = "ALL"
+
+// Direction is the direction in which links are followed when traversing events.
+type Direction int
+
+const (
+	// Upstream follows the links of an event to their targets.
+	Upstream Direction = iota
+	// Downstream follows links backwards to the events linking to an event.
+	Downstream
+)
+
+// SearchResult is the result of an upstream/downstream search. Both lists
+// start with the event that was searched from.
+type SearchResult struct {
+	Upstream   []EiffelEvent `json:"upstreamLinkObjects"`
+	Downstream []EiffelEvent `json:"downstreamLinkObjects"`
+}
+
+// DefaultTraversalLevels and DefaultTraversalLimit bound the traversals of
+// endpoints that follow links on their own, unless a request asks for more,
+// so that a densely linked event can't make a request walk the whole
+// database.
+const (
+	DefaultTraversalLevels = 25
+	DefaultTraversalLimit  = 10000
+)
+
+// Traverse follows links of the given types from the start events, level by
+// level, and returns the events reached in the order they were found. Links
+// of any type are followed if linkTypes contains LinkTypeAll and none are
+// followed if it's empty. A negative levels or limit means no limit on the
+// number of levels to follow or events to return, respectively. The start
+// events are never returned and every event is returned at most once.
+//
+// Traverse works with any Database, making one GetEventsByIDs or
+// GetLinkingEvents call per level.
+func Traverse(ctx context.Context, db Database, start []EiffelEvent, direction Direction, linkTypes []string, levels int, limit int) ([]EiffelEvent, error) {
+	if len(linkTypes) == 0 || limit == 0 {
+		return nil, nil
+	}
+	anyType := false
+	wanted := make(map[string]struct{}, len(linkTypes))
+	for _, linkType := range linkTypes {
+		if linkType == LinkTypeAll {
+			anyType = true
+		}
+		wanted[linkType] = struct{}{}
+	}
+	follow := func(linkType string) bool {
+		_, ok := wanted[linkType]
+		return anyType || ok
+	}
+
+	seen := make(map[string]struct{}, len(start))
+	for _, event := range start {
+		seen[event.ID()] = struct{}{}
+	}
+	var found []EiffelEvent
+	frontier := start
+	for level := 0; (levels < 0 || level < levels) && len(frontier) > 0; level++ {
+		var next []EiffelEvent
+		var err error
+		switch direction {
+		case Upstream:
+			var targets []string
+			for _, event := range frontier {
+				for _, link := range event.Links() {
+					if _, ok := seen[link.Target]; !ok && follow(link.Type) {
+						targets = append(targets, link.Target)
+					}
+				}
+			}
+			if len(targets) > 0 {
+				next, err = db.GetEventsByIDs(ctx, targets)
+			}
+		case Downstream:
+			ids := make([]string, 0, len(frontier))
+			for _, event := range frontier {
+				ids = append(ids, event.ID())
+			}
+			queryTypes := linkTypes
+			if anyType {
+				queryTypes = nil
+			}
+			next, err = db.GetLinkingEvents(ctx, ids, queryTypes)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = frontier[:0:0]
+		for _, event := range next {
+			if _, ok := seen[event.ID()]; ok {
+				continue
+			}
+			seen[event.ID()] = struct{}{}
+			found = append(found, event)
+			frontier = append(frontier, event)
+			if limit >= 0 && len(found) >= limit {
+				return found, nil
+			}
+		}
+	}
+	return found, nil
+}
+
+// UpstreamDownstreamSearch implements Database.UpstreamDownstreamSearch for any
+// database by traversing the links upstream and downstream of an event. The
+// limit of the request applies to the total number of events found in both
+// directions, upstream first. A wrapped ErrNotFound is returned if there's no
+// event with the ID.
+func UpstreamDownstreamSearch(ctx context.Context, db Database, id string, request requests.SearchRequest) (SearchResult, error) {
+	event, err := db.GetEventByID(ctx, id)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	start := []EiffelEvent{event}
+	upstream, err := Traverse(ctx, db, start, Upstream, request.ULT, int(request.Levels), int(request.Limit))
+	if err != nil {
+		return SearchResult{}, err
+	}
+	limit := int(request.Limit)
+	if limit >= 0 {
+		limit -= len(upstream)
+	}
+	downstream, err := Traverse(ctx, db, start, Downstream, request.DLT, int(request.Levels), limit)
+	if err != nil {
+		return SearchResult{}, err
+	}
+	return SearchResult{
+		Upstream:   append([]EiffelEvent{event}, upstream...),
+		Downstream: append([]EiffelEvent{event}, downstream...),
+	}, nil
+}
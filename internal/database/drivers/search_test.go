@@ -0,0 +1,98 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+// The events form a chain: artC <-ARTIFACT- artP, and artC -CAUSE-> sourceChange,
+// with a confidence level pointing at artC and an event in the chain's context.
+var (
+	sourceChange = test.NewEvent("00000000-0000-4000-8000-000000000001", "EiffelSourceChangeSubmittedEvent", 1, nil)
+	artC         = test.NewEvent("00000000-0000-4000-8000-000000000002", "EiffelArtifactCreatedEvent", 2, nil,
+		drivers.Link{Type: "CAUSE", Target: sourceChange.ID()})
+	artP = test.NewEvent("00000000-0000-4000-8000-000000000003", "EiffelArtifactPublishedEvent", 3, nil,
+		drivers.Link{Type: "ARTIFACT", Target: artC.ID()})
+	clm = test.NewEvent("00000000-0000-4000-8000-000000000004", "EiffelConfidenceLevelModifiedEvent", 4, nil,
+		drivers.Link{Type: "SUBJECT", Target: artC.ID()})
+	announcement = test.NewEvent("00000000-0000-4000-8000-000000000005", "EiffelAnnouncementPublishedEvent", 5, nil,
+		drivers.Link{Type: "CONTEXT", Target: clm.ID()})
+)
+
+func ids(events []drivers.EiffelEvent) []string {
+	ids := []string{}
+	for _, event := range events {
+		ids = append(ids, event.ID())
+	}
+	return ids
+}
+
+// Test that links are followed in the right direction, with the right types and within the limits.
+func TestTraverse(t *testing.T) {
+	db := test.NewMemoryDatabase(sourceChange, artC, artP, clm, announcement)
+	tests := []struct {
+		name      string
+		start     drivers.EiffelEvent
+		direction drivers.Direction
+		linkTypes []string
+		levels    int
+		limit     int
+		expected  []string
+	}{
+		{name: "UpstreamAll", start: artP, direction: drivers.Upstream, linkTypes: []string{drivers.LinkTypeAll}, levels: -1, limit: -1, expected: ids([]drivers.EiffelEvent{artC, sourceChange})},
+		{name: "UpstreamOneLevel", start: artP, direction: drivers.Upstream, linkTypes: []string{drivers.LinkTypeAll}, levels: 1, limit: -1, expected: ids([]drivers.EiffelEvent{artC})},
+		{name: "UpstreamOtherType", start: artP, direction: drivers.Upstream, linkTypes: []string{"CAUSE"}, levels: -1, limit: -1, expected: []string{}},
+		{name: "DownstreamAll", start: sourceChange, direction: drivers.Downstream, linkTypes: []string{drivers.LinkTypeAll}, levels: -1, limit: -1, expected: ids([]drivers.EiffelEvent{artC, artP, clm, announcement})},
+		{name: "DownstreamTypes", start: sourceChange, direction: drivers.Downstream, linkTypes: []string{"CAUSE", "SUBJECT"}, levels: -1, limit: -1, expected: ids([]drivers.EiffelEvent{artC, clm})},
+		{name: "DownstreamLimit", start: sourceChange, direction: drivers.Downstream, linkTypes: []string{drivers.LinkTypeAll}, levels: -1, limit: 2, expected: ids([]drivers.EiffelEvent{artC, artP})},
+		{name: "NoLinkTypes", start: sourceChange, direction: drivers.Downstream, levels: -1, limit: -1, expected: []string{}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			found, err := drivers.Traverse(context.Background(), db, []drivers.EiffelEvent{testCase.start},
+				testCase.direction, testCase.linkTypes, testCase.levels, testCase.limit)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, ids(found))
+		})
+	}
+}
+
+// Test that searches include the event in both directions and share the limit between them.
+func TestUpstreamDownstreamSearch(t *testing.T) {
+	db := test.NewMemoryDatabase(sourceChange, artC, artP, clm, announcement)
+	result, err := drivers.UpstreamDownstreamSearch(context.Background(), db, artC.ID(), requests.SearchRequest{
+		Limit:  2,
+		Levels: -1,
+		ULT:    []string{drivers.LinkTypeAll},
+		DLT:    []string{drivers.LinkTypeAll},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ids([]drivers.EiffelEvent{artC, sourceChange}), ids(result.Upstream))
+	assert.Equal(t, ids([]drivers.EiffelEvent{artC, artP}), ids(result.Downstream))
+
+	_, err = drivers.UpstreamDownstreamSearch(context.Background(), db, "00000000-0000-4000-8000-000000000000", requests.SearchRequest{})
+	assert.True(t, errors.Is(err, drivers.ErrNotFound))
+}
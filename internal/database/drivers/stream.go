@@ -0,0 +1,174 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// sliceStream is an EventStream over events that are already in memory.
+type sliceStream struct {
+	events  []EiffelEvent
+	current int
+}
+
+// NewSliceStream returns an EventStream that yields the given events.
+func NewSliceStream(events []EiffelEvent) EventStream {
+	return &sliceStream{events: events, current: -1}
+}
+
+// Next advances the stream to the next event.
+func (s *sliceStream) Next(_ context.Context) bool {
+	if s.current+1 >= len(s.events) {
+		s.current = len(s.events)
+		return false
+	}
+	s.current++
+	return true
+}
+
+// Event returns the current event.
+func (s *sliceStream) Event() EiffelEvent {
+	return s.events[s.current]
+}
+
+// Err always returns nil since reading from a slice can't fail.
+func (s *sliceStream) Err() error {
+	return nil
+}
+
+// Close the stream.
+func (s *sliceStream) Close(_ context.Context) error {
+	return nil
+}
+
+// Collect reads all remaining events from a stream into a slice
+// and closes the stream.
+func Collect(ctx context.Context, stream EventStream) ([]EiffelEvent, error) {
+	var events []EiffelEvent
+	for stream.Next(ctx) {
+		events = append(events, stream.Event())
+	}
+	err := stream.Err()
+	if closeErr := stream.Close(ctx); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// StreamFilter wraps a stream in one that leaves out some of its events.
+type StreamFilter func(EventStream) EventStream
+
+// FilterStream returns a filter that keeps the events that keep returns true for.
+func FilterStream(keep func(EiffelEvent) bool) StreamFilter {
+	return func(stream EventStream) EventStream {
+		return &filteredStream{stream: stream, keep: keep}
+	}
+}
+
+// filteredStream is an EventStream with the events of another stream
+// that keep returns true for.
+type filteredStream struct {
+	stream EventStream
+	keep   func(EiffelEvent) bool
+}
+
+// Next advances the stream to the next event to keep.
+func (s *filteredStream) Next(ctx context.Context) bool {
+	for s.stream.Next(ctx) {
+		if s.keep(s.stream.Event()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Event returns the current event.
+func (s *filteredStream) Event() EiffelEvent {
+	return s.stream.Event()
+}
+
+// Err returns the error of the underlying stream.
+func (s *filteredStream) Err() error {
+	return s.stream.Err()
+}
+
+// Close the underlying stream.
+func (s *filteredStream) Close(ctx context.Context) error {
+	return s.stream.Close(ctx)
+}
+
+// GetFilteredEvents is like Database.GetEvents but passes the matching
+// events through the filters before paging them, for filters that can't
+// be expressed as database queries.
+//
+// The events are filtered as they're read from the database. Pages are
+// therefore exact, but the database is read until the page is full. Since
+// counting every event would mean reading and filtering every matching
+// event, exact counts are estimated too: the database is read until the
+// event after the page, so the count is exact on the last page and
+// otherwise a lower bound telling that there are more pages. The count
+// is -1 for unpaged requests.
+func GetFilteredEvents(ctx context.Context, db Database, request requests.MultipleEventsRequest, filters ...StreamFilter) (EventStream, int64, error) {
+	all := request
+	all.Unpaged = true
+	all.Count = requests.CountNone
+	stream, _, err := db.GetEvents(ctx, all)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, filter := range filters {
+		stream = filter(stream)
+	}
+	if request.Unpaged {
+		return stream, -1, nil
+	}
+
+	skip := int64((request.PageNo - 1) * request.PageSize)
+	end := skip + int64(request.PageSize)
+	// countTo is how many events to read.
+	var countTo int64
+	switch request.Count {
+	case requests.CountExact, requests.CountEstimated:
+		countTo = end + 1
+	default:
+		countTo = end
+	}
+	var page []EiffelEvent
+	var total int64
+	for total < countTo && stream.Next(ctx) {
+		if total >= skip && total < end {
+			page = append(page, stream.Event())
+		}
+		total++
+	}
+	err = stream.Err()
+	if closeErr := stream.Close(ctx); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if request.Count == requests.CountNone {
+		total = -1
+	}
+	return NewSliceStream(page), total, nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that a slice stream yields its events in order and that Collect reads all of them.
+func TestSliceStream(t *testing.T) {
+	ctx := context.Background()
+	events := []EiffelEvent{{"meta": "a"}, {"meta": "b"}}
+
+	stream := NewSliceStream(events)
+	assert.True(t, stream.Next(ctx))
+	assert.Equal(t, events[0], stream.Event())
+	collected, err := Collect(ctx, stream)
+	assert.NoError(t, err)
+	assert.Equal(t, events[1:], collected)
+	assert.False(t, stream.Next(ctx))
+
+	collected, err = Collect(ctx, NewSliceStream(nil))
+	assert.NoError(t, err)
+	assert.Empty(t, collected)
+}
+
+// Test that a filtered stream only yields the events to keep.
+func TestFilterStream(t *testing.T) {
+	events := []EiffelEvent{{"meta": "a"}, {"meta": "b"}, {"meta": "c"}}
+	stream := FilterStream(func(event EiffelEvent) bool {
+		return event["meta"] != "b"
+	})(NewSliceStream(events))
+	collected, err := Collect(context.Background(), stream)
+	assert.NoError(t, err)
+	assert.Equal(t, []EiffelEvent{events[0], events[2]}, collected)
+}
@@ -0,0 +1,212 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// Event types and link types making up a test suite execution.
+const (
+	TestSuiteStarted  = "EiffelTestSuiteStartedEvent"
+	TestSuiteFinished = "EiffelTestSuiteFinishedEvent"
+	TestCaseTriggered = "EiffelTestCaseTriggeredEvent"
+	TestCaseCanceled  = "EiffelTestCaseCanceledEvent"
+	TestCaseStarted   = "EiffelTestCaseStartedEvent"
+	TestCaseFinished  = "EiffelTestCaseFinishedEvent"
+
+	LinkTypeContext            = "CONTEXT"
+	LinkTypeTestSuiteExecution = "TEST_SUITE_EXECUTION"
+	LinkTypeTestCaseExecution  = "TEST_CASE_EXECUTION"
+)
+
+// Test verdicts in the outcomes of finished test cases and test suites.
+const (
+	VerdictPassed       = "PASSED"
+	VerdictFailed       = "FAILED"
+	VerdictInconclusive = "INCONCLUSIVE"
+)
+
+// TestCaseResult is the result of a test case execution. Times are in
+// milliseconds since the epoch and durations in milliseconds. Values that
+// aren't known yet, e.g. the verdict of an ongoing test case, are empty.
+type TestCaseResult struct {
+	// ID is the data.testCase.id of the test case.
+	ID string `json:"id"`
+	// TriggeredID is the ID of the EiffelTestCaseTriggeredEvent.
+	TriggeredID string `json:"triggeredId"`
+	Verdict     string `json:"verdict"`
+	Conclusion  string `json:"conclusion"`
+	Canceled    bool   `json:"canceled"`
+	TriggerTime int64  `json:"triggerTime"`
+	StartTime   *int64 `json:"startTime"`
+	FinishTime  *int64 `json:"finishTime"`
+	// Duration is the time from the start to the finish.
+	Duration *int64 `json:"duration"`
+}
+
+// TestSuiteCounts are the number of test cases in a test suite by verdict.
+// Unfinished test cases have been triggered but neither finished nor canceled.
+type TestSuiteCounts struct {
+	Total        int `json:"total"`
+	Passed       int `json:"passed"`
+	Failed       int `json:"failed"`
+	Inconclusive int `json:"inconclusive"`
+	Canceled     int `json:"canceled"`
+	Unfinished   int `json:"unfinished"`
+}
+
+// TestSuiteSummary summarizes the execution of a test suite and its test
+// cases, including those of the test suites executed within it.
+type TestSuiteSummary struct {
+	// ID is the ID of the EiffelTestSuiteStartedEvent.
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Verdict    string `json:"verdict"`
+	StartTime  int64  `json:"startTime"`
+	FinishTime *int64 `json:"finishTime"`
+	// Duration is the time from the start to the finish.
+	Duration  *int64           `json:"duration"`
+	Counts    TestSuiteCounts  `json:"counts"`
+	TestCases []TestCaseResult `json:"testCases"`
+	// Truncated is true if the traversal stopped at the request's limit,
+	// in which case the test cases may be incomplete.
+	Truncated bool `json:"truncated"`
+}
+
+// GetTestSuiteSummary summarizes the test suite execution started by the
+// EiffelTestSuiteStartedEvent with the ID. ErrNotFound is returned if there's
+// no such event.
+//
+// The test cases are found by following TEST_SUITE_EXECUTION and CONTEXT
+// links downstream from the test suite, which includes the test cases of
+// nested test suites, within the request's levels and limit, and their
+// results by following TEST_CASE_EXECUTION links from the test cases.
+func GetTestSuiteSummary(ctx context.Context, db Database, id string, request requests.TestSuiteSummaryRequest) (TestSuiteSummary, error) {
+	started, err := db.GetEventByID(ctx, id)
+	if err != nil {
+		return TestSuiteSummary{}, err
+	}
+	if started.Type() != TestSuiteStarted {
+		return TestSuiteSummary{}, fmt.Errorf("%w: %q is an %s", ErrNotFound, id, started.Type())
+	}
+	summary := TestSuiteSummary{
+		ID:        id,
+		Name:      started.StringField("data.name"),
+		StartTime: started.Time(),
+		TestCases: []TestCaseResult{},
+	}
+
+	downstream, err := Traverse(ctx, db, []EiffelEvent{started}, Downstream,
+		[]string{LinkTypeTestSuiteExecution, LinkTypeContext}, int(request.Levels), int(request.Limit))
+	if err != nil {
+		return TestSuiteSummary{}, err
+	}
+	summary.Truncated = request.Limit >= 0 && len(downstream) >= int(request.Limit)
+	var finished EiffelEvent
+	var triggered []EiffelEvent
+	var triggeredIDs []string
+	for _, event := range downstream {
+		switch {
+		case event.Type() == TestSuiteFinished && linksTo(event, LinkTypeTestSuiteExecution, id):
+			finished = earliest(finished, event)
+		case event.Type() == TestCaseTriggered:
+			triggered = append(triggered, event)
+			triggeredIDs = append(triggeredIDs, event.ID())
+		}
+	}
+	if finished != nil {
+		finishTime := finished.Time()
+		duration := finishTime - summary.StartTime
+		summary.FinishTime = &finishTime
+		summary.Duration = &duration
+		summary.Verdict = finished.StringField("data.outcome.verdict")
+	}
+	if len(triggered) == 0 {
+		return summary, nil
+	}
+
+	executions, err := db.GetLinkingEvents(ctx, triggeredIDs, []string{LinkTypeTestCaseExecution})
+	if err != nil {
+		return TestSuiteSummary{}, err
+	}
+	for _, event := range triggered {
+		result := newTestCaseResult(event, executions)
+		summary.TestCases = append(summary.TestCases, result)
+		summary.Counts.Total++
+		switch {
+		case result.Canceled:
+			summary.Counts.Canceled++
+		case result.FinishTime == nil:
+			summary.Counts.Unfinished++
+		case result.Verdict == VerdictPassed:
+			summary.Counts.Passed++
+		case result.Verdict == VerdictFailed:
+			summary.Counts.Failed++
+		case result.Verdict == VerdictInconclusive:
+			summary.Counts.Inconclusive++
+		}
+	}
+	sort.SliceStable(summary.TestCases, func(i, j int) bool {
+		return summary.TestCases[i].TriggerTime < summary.TestCases[j].TriggerTime
+	})
+	return summary, nil
+}
+
+// newTestCaseResult computes the result of a test case from its triggered
+// event and the events linking to it. Linking events that aren't part of
+// the test case execution are ignored and if an event was sent more than
+// once, the earliest one is used.
+func newTestCaseResult(triggered EiffelEvent, linking []EiffelEvent) TestCaseResult {
+	result := TestCaseResult{
+		ID:          triggered.StringField("data.testCase.id"),
+		TriggeredID: triggered.ID(),
+		TriggerTime: triggered.Time(),
+	}
+	var started, finished EiffelEvent
+	for _, event := range linking {
+		if !linksTo(event, LinkTypeTestCaseExecution, result.TriggeredID) {
+			continue
+		}
+		switch event.Type() {
+		case TestCaseCanceled:
+			result.Canceled = true
+		case TestCaseStarted:
+			started = earliest(started, event)
+		case TestCaseFinished:
+			finished = earliest(finished, event)
+		}
+	}
+	if started != nil {
+		startTime := started.Time()
+		result.StartTime = &startTime
+	}
+	if finished != nil {
+		finishTime := finished.Time()
+		result.FinishTime = &finishTime
+		result.Verdict = finished.StringField("data.outcome.verdict")
+		result.Conclusion = finished.StringField("data.outcome.conclusion")
+		if result.StartTime != nil {
+			duration := finishTime - *result.StartTime
+			result.Duration = &duration
+		}
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+// testCaseEvents returns the events of a test case execution in a test suite,
+// leaving out the started and finished events if their time is zero.
+func testCaseEvents(id string, testCaseID string, suiteID string, triggerTime, startTime, finishTime int64, verdict string) []drivers.EiffelEvent {
+	triggered := test.NewEvent(id+"1", drivers.TestCaseTriggered, triggerTime,
+		map[string]interface{}{"testCase": map[string]interface{}{"id": testCaseID}},
+		drivers.Link{Type: "CONTEXT", Target: suiteID})
+	events := []drivers.EiffelEvent{triggered}
+	if startTime != 0 {
+		events = append(events, test.NewEvent(id+"2", drivers.TestCaseStarted, startTime, nil,
+			drivers.Link{Type: "TEST_CASE_EXECUTION", Target: triggered.ID()}))
+	}
+	if finishTime != 0 {
+		events = append(events, test.NewEvent(id+"3", drivers.TestCaseFinished, finishTime,
+			map[string]interface{}{"outcome": map[string]interface{}{"verdict": verdict, "conclusion": "SUCCESSFUL"}},
+			drivers.Link{Type: "TEST_CASE_EXECUTION", Target: triggered.ID()}))
+	}
+	return events
+}
+
+// Test that test cases are summarized, including those of nested test suites.
+func TestGetTestSuiteSummary(t *testing.T) {
+	unlimited := requests.TestSuiteSummaryRequest{Levels: -1, Limit: -1}
+	suite := test.NewEvent("20000000-0000-4000-8000-000000000001", drivers.TestSuiteStarted, 1000,
+		map[string]interface{}{"name": "regression"})
+	suiteFinished := test.NewEvent("20000000-0000-4000-8000-000000000002", drivers.TestSuiteFinished, 9000,
+		map[string]interface{}{"outcome": map[string]interface{}{"verdict": drivers.VerdictFailed}},
+		drivers.Link{Type: "TEST_SUITE_EXECUTION", Target: suite.ID()})
+	nestedSuite := test.NewEvent("20000000-0000-4000-8000-000000000003", drivers.TestSuiteStarted, 1500, nil,
+		drivers.Link{Type: "CONTEXT", Target: suite.ID()})
+	nestedSuiteFinished := test.NewEvent("20000000-0000-4000-8000-000000000004", drivers.TestSuiteFinished, 8000,
+		map[string]interface{}{"outcome": map[string]interface{}{"verdict": drivers.VerdictPassed}},
+		drivers.Link{Type: "TEST_SUITE_EXECUTION", Target: nestedSuite.ID()})
+	events := []drivers.EiffelEvent{suite, suiteFinished, nestedSuite, nestedSuiteFinished}
+	events = append(events, testCaseEvents("21000000-0000-4000-8000-00000000000", "boot", suite.ID(), 1100, 1200, 1700, drivers.VerdictPassed)...)
+	events = append(events, testCaseEvents("22000000-0000-4000-8000-00000000000", "login", nestedSuite.ID(), 1600, 1600, 2600, drivers.VerdictFailed)...)
+	events = append(events, testCaseEvents("23000000-0000-4000-8000-00000000000", "upload", nestedSuite.ID(), 1650, 1700, 1800, drivers.VerdictInconclusive)...)
+	events = append(events, testCaseEvents("24000000-0000-4000-8000-00000000000", "logout", suite.ID(), 2000, 2100, 0, "")...)
+	canceled := testCaseEvents("25000000-0000-4000-8000-00000000000", "cleanup", suite.ID(), 3000, 0, 0, "")
+	events = append(events, canceled...)
+	events = append(events, test.NewEvent("25000000-0000-4000-8000-000000000004", drivers.TestCaseCanceled, 3100, nil,
+		drivers.Link{Type: "TEST_CASE_EXECUTION", Target: canceled[0].ID()}))
+	db := test.NewMemoryDatabase(events...)
+
+	summary, err := drivers.GetTestSuiteSummary(context.Background(), db, suite.ID(), unlimited)
+	require.NoError(t, err)
+	assert.Equal(t, "regression", summary.Name)
+	assert.Equal(t, drivers.VerdictFailed, summary.Verdict)
+	assert.Equal(t, int64Pointer(8000), summary.Duration)
+	assert.Equal(t, drivers.TestSuiteCounts{Total: 5, Passed: 1, Failed: 1, Inconclusive: 1, Canceled: 1, Unfinished: 1}, summary.Counts)
+	require.Len(t, summary.TestCases, 5)
+	assert.Equal(t, drivers.TestCaseResult{
+		ID:          "boot",
+		TriggeredID: "21000000-0000-4000-8000-000000000001",
+		Verdict:     drivers.VerdictPassed,
+		Conclusion:  "SUCCESSFUL",
+		TriggerTime: 1100,
+		StartTime:   int64Pointer(1200),
+		FinishTime:  int64Pointer(1700),
+		Duration:    int64Pointer(500),
+	}, summary.TestCases[0])
+	var order []string
+	for _, result := range summary.TestCases {
+		order = append(order, result.ID)
+	}
+	assert.Equal(t, []string{"boot", "login", "upload", "logout", "cleanup"}, order)
+	assert.True(t, summary.TestCases[4].Canceled)
+	assert.Nil(t, summary.TestCases[3].Duration)
+
+	nested, err := drivers.GetTestSuiteSummary(context.Background(), db, nestedSuite.ID(), unlimited)
+	require.NoError(t, err)
+	assert.Equal(t, drivers.VerdictPassed, nested.Verdict)
+	assert.Equal(t, drivers.TestSuiteCounts{Total: 2, Failed: 1, Inconclusive: 1}, nested.Counts)
+	assert.False(t, nested.Truncated)
+
+	// The nested test suite's test cases are two levels down.
+	shallow, err := drivers.GetTestSuiteSummary(context.Background(), db, suite.ID(), requests.TestSuiteSummaryRequest{Levels: 1, Limit: -1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, shallow.Counts.Total)
+	limited, err := drivers.GetTestSuiteSummary(context.Background(), db, suite.ID(), requests.TestSuiteSummaryRequest{Levels: -1, Limit: 2})
+	require.NoError(t, err)
+	assert.True(t, limited.Truncated)
+	assert.False(t, summary.Truncated)
+
+	_, err = drivers.GetTestSuiteSummary(context.Background(), db, suiteFinished.ID(), unlimited)
+	assert.True(t, errors.Is(err, drivers.ErrNotFound))
+}
@@ -0,0 +1,165 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers
+
+import (
+	"context"
+
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// LinkTypePreviousVersion links an event to the events it's a new version of.
+const LinkTypePreviousVersion = "PREVIOUS_VERSION"
+
+// headBatchSize is how many events are checked for successors at a time
+// when filtering out events that aren't the heads of their version chains.
+const headBatchSize = 100
+
+// VersionHistory is a version chain formed by PREVIOUS_VERSION links.
+type VersionHistory struct {
+	// Head is the latest event in the chain that has no newer version.
+	Head EiffelEvent `json:"head"`
+	// History is all events in the chain, oldest first.
+	History []EiffelEvent `json:"history"`
+}
+
+// GetVersionHistory gets the version chain of the event with the ID, which
+// may be any event in the chain. The chain is found by following
+// PREVIOUS_VERSION links upstream to the oldest versions and then
+// downstream from them, so versions branching off older versions are
+// included. ErrNotFound is returned if there's no such event.
+func GetVersionHistory(ctx context.Context, db Database, id string) (VersionHistory, error) {
+	event, err := db.GetEventByID(ctx, id)
+	if err != nil {
+		return VersionHistory{}, err
+	}
+	linkTypes := []string{LinkTypePreviousVersion}
+	older, err := Traverse(ctx, db, []EiffelEvent{event}, Upstream, linkTypes, -1, -1)
+	if err != nil {
+		return VersionHistory{}, err
+	}
+	known := append([]EiffelEvent{event}, older...)
+	newer, err := Traverse(ctx, db, known, Downstream, linkTypes, -1, -1)
+	if err != nil {
+		return VersionHistory{}, err
+	}
+	history := append(known, newer...)
+	SortByTime(history)
+
+	hasSuccessor := map[string]struct{}{}
+	for _, version := range history {
+		for _, link := range version.Links() {
+			if link.Type == LinkTypePreviousVersion {
+				hasSuccessor[link.Target] = struct{}{}
+			}
+		}
+	}
+	var head EiffelEvent
+	for _, version := range history {
+		if _, ok := hasSuccessor[version.ID()]; !ok {
+			// The history is sorted, so the last one is the latest.
+			head = version
+		}
+	}
+	return VersionHistory{Head: head, History: history}, nil
+}
+
+// GetLatestEvents is like Database.GetEvents but leaves out events that
+// have newer versions, i.e. events that other events link to with
+// PREVIOUS_VERSION links, so that only the heads of version chains remain.
+// See GetFilteredEvents for how the events are paged and counted.
+func GetLatestEvents(ctx context.Context, db Database, request requests.MultipleEventsRequest) (EventStream, int64, error) {
+	return GetFilteredEvents(ctx, db, request, LatestFilter(db))
+}
+
+// LatestFilter returns a filter that leaves out events with newer versions.
+func LatestFilter(db Database) StreamFilter {
+	return func(stream EventStream) EventStream {
+		return &headStream{db: db, stream: stream}
+	}
+}
+
+// headStream is an EventStream with the events of another stream that no
+// events link to with PREVIOUS_VERSION links.
+type headStream struct {
+	db     Database
+	stream EventStream
+	// batch has the heads read from the stream but not yet returned.
+	batch []EiffelEvent
+	event EiffelEvent
+	err   error
+}
+
+// Next advances the stream to the next head.
+func (s *headStream) Next(ctx context.Context) bool {
+	for len(s.batch) == 0 {
+		if s.err != nil || !s.readBatch(ctx) {
+			s.event = nil
+			return false
+		}
+	}
+	s.event = s.batch[0]
+	s.batch = s.batch[1:]
+	return true
+}
+
+// readBatch reads the next batch of events from the stream and keeps the
+// heads among them. Returns false when there are no more events.
+func (s *headStream) readBatch(ctx context.Context) bool {
+	var events []EiffelEvent
+	var ids []string
+	for len(events) < headBatchSize && s.stream.Next(ctx) {
+		events = append(events, s.stream.Event())
+		ids = append(ids, s.stream.Event().ID())
+	}
+	if s.err = s.stream.Err(); s.err != nil || len(events) == 0 {
+		return false
+	}
+	successors, err := s.db.GetLinkingEvents(ctx, ids, []string{LinkTypePreviousVersion})
+	if err != nil {
+		s.err = err
+		return false
+	}
+	hasSuccessor := map[string]struct{}{}
+	for _, successor := range successors {
+		for _, link := range successor.Links() {
+			if link.Type == LinkTypePreviousVersion {
+				hasSuccessor[link.Target] = struct{}{}
+			}
+		}
+	}
+	for _, event := range events {
+		if _, ok := hasSuccessor[event.ID()]; !ok {
+			s.batch = append(s.batch, event)
+		}
+	}
+	return true
+}
+
+// Event returns the current event.
+func (s *headStream) Event() EiffelEvent {
+	return s.event
+}
+
+// Err returns the error that made Next return false, if any.
+func (s *headStream) Err() error {
+	return s.err
+}
+
+// Close the underlying stream.
+func (s *headStream) Close(ctx context.Context) error {
+	return s.stream.Close(ctx)
+}
@@ -0,0 +1,127 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package drivers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+// The versions form a chain v1 <- v2 <- v3 with a branch v2 <- hotfix,
+// next to an unversioned environment.
+var (
+	v1 = test.NewEvent("50000000-0000-4000-8000-000000000001", "EiffelCompositionDefinedEvent", 100, nil)
+	v2 = test.NewEvent("50000000-0000-4000-8000-000000000002", "EiffelCompositionDefinedEvent", 200, nil,
+		drivers.Link{Type: "PREVIOUS_VERSION", Target: v1.ID()})
+	hotfix = test.NewEvent("50000000-0000-4000-8000-000000000003", "EiffelCompositionDefinedEvent", 250, nil,
+		drivers.Link{Type: "PREVIOUS_VERSION", Target: v2.ID()})
+	v3 = test.NewEvent("50000000-0000-4000-8000-000000000004", "EiffelCompositionDefinedEvent", 300, nil,
+		drivers.Link{Type: "PREVIOUS_VERSION", Target: v2.ID()},
+		drivers.Link{Type: "CAUSE", Target: v1.ID()})
+	environment = test.NewEvent("50000000-0000-4000-8000-000000000005", "EiffelEnvironmentDefinedEvent", 400, nil)
+)
+
+// Test that the whole chain is found from any of its events.
+func TestGetVersionHistory(t *testing.T) {
+	db := test.NewMemoryDatabase(v1, v2, hotfix, v3, environment)
+	for _, event := range []drivers.EiffelEvent{v1, v2, hotfix, v3} {
+		history, err := drivers.GetVersionHistory(context.Background(), db, event.ID())
+		require.NoError(t, err)
+		assert.Equal(t, drivers.VersionHistory{Head: v3, History: []drivers.EiffelEvent{v1, v2, hotfix, v3}}, history)
+	}
+
+	history, err := drivers.GetVersionHistory(context.Background(), db, environment.ID())
+	require.NoError(t, err)
+	assert.Equal(t, drivers.VersionHistory{Head: environment, History: []drivers.EiffelEvent{environment}}, history)
+
+	_, err = drivers.GetVersionHistory(context.Background(), db, "00000000-0000-4000-8000-000000000000")
+	assert.True(t, errors.Is(err, drivers.ErrNotFound))
+}
+
+// Test that only chain heads are returned and paged.
+func TestGetLatestEvents(t *testing.T) {
+	db := test.NewMemoryDatabase(v1, v2, hotfix, v3, environment)
+	tests := []struct {
+		name     string
+		request  requests.MultipleEventsRequest
+		expected []drivers.EiffelEvent
+		total    int64
+	}{
+		{
+			name:     "All",
+			request:  requests.MultipleEventsRequest{PageNo: 1, PageSize: 10, Count: requests.CountExact},
+			expected: []drivers.EiffelEvent{hotfix, v3, environment},
+			total:    3,
+		},
+		{
+			name:     "SecondPage",
+			request:  requests.MultipleEventsRequest{PageNo: 2, PageSize: 2, Count: requests.CountExact},
+			expected: []drivers.EiffelEvent{environment},
+			total:    3,
+		},
+		{
+			name:     "ExactIsLowerBound",
+			request:  requests.MultipleEventsRequest{PageNo: 1, PageSize: 1, Count: requests.CountExact},
+			expected: []drivers.EiffelEvent{hotfix},
+			total:    2,
+		},
+		{
+			name:     "Estimated",
+			request:  requests.MultipleEventsRequest{PageNo: 1, PageSize: 1, Count: requests.CountEstimated},
+			expected: []drivers.EiffelEvent{hotfix},
+			total:    2,
+		},
+		{
+			name:     "NoCount",
+			request:  requests.MultipleEventsRequest{PageNo: 1, PageSize: 1, Count: requests.CountNone},
+			expected: []drivers.EiffelEvent{hotfix},
+			total:    -1,
+		},
+		{
+			name: "Conditions",
+			request: requests.MultipleEventsRequest{PageNo: 1, PageSize: 10, Count: requests.CountExact, Conditions: []query.Condition{
+				{Field: "meta.type", Op: "=", Value: "EiffelEnvironmentDefinedEvent"},
+			}},
+			expected: []drivers.EiffelEvent{environment},
+			total:    1,
+		},
+		{
+			name:     "Unpaged",
+			request:  requests.MultipleEventsRequest{Unpaged: true, Count: requests.CountNone},
+			expected: []drivers.EiffelEvent{hotfix, v3, environment},
+			total:    -1,
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			stream, total, err := drivers.GetLatestEvents(context.Background(), db, testCase.request)
+			require.NoError(t, err)
+			events, err := drivers.Collect(context.Background(), stream)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, events)
+			assert.Equal(t, testCase.total, total)
+		})
+	}
+}
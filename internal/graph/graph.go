@@ -0,0 +1,181 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// graph renders sets of events and the links between them as graphs in
+// formats understood by common graph drawing tools.
+package graph
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+)
+
+// labelFields are the data fields shown in node labels when present,
+// chosen to identify the events of the common event types.
+var labelFields = []string{
+	"name",
+	"identity",
+	"value",
+	"outcome.conclusion",
+	"outcome.verdict",
+	"testCase.id",
+	"heading",
+	"title",
+}
+
+// Node is an event in a graph.
+type Node struct {
+	ID   string
+	Type string
+	// Details are the key data fields of the event as "field: value" strings.
+	Details []string
+}
+
+// Label returns the text to label the node with.
+func (n Node) Label() string {
+	return strings.Join(append([]string{n.Type}, n.Details...), "\n")
+}
+
+// Edge is a link from one event in a graph to another.
+type Edge struct {
+	Source string
+	Target string
+	Type   string
+}
+
+// Graph is a set of events and the links between them.
+type Graph struct {
+	Nodes []Node
+	Edges []Edge
+}
+
+// New returns the graph of the events and the links between them.
+// Duplicate events are included once and links to events outside of
+// the set are left out.
+func New(events []drivers.EiffelEvent) Graph {
+	var graph Graph
+	included := make(map[string]struct{}, len(events))
+	var unique []drivers.EiffelEvent
+	for _, event := range events {
+		if _, ok := included[event.ID()]; ok {
+			continue
+		}
+		included[event.ID()] = struct{}{}
+		unique = append(unique, event)
+		node := Node{ID: event.ID(), Type: event.Type()}
+		for _, field := range labelFields {
+			if value, ok := event.Field("data." + field); ok {
+				node.Details = append(node.Details, fmt.Sprintf("%s: %v", field, value))
+			}
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	for _, event := range unique {
+		for _, link := range event.Links() {
+			if _, ok := included[link.Target]; ok {
+				graph.Edges = append(graph.Edges, Edge{Source: event.ID(), Target: link.Target, Type: link.Type})
+			}
+		}
+	}
+	return graph
+}
+
+// DOT renders the graph in the Graphviz DOT language.
+func (g Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph events {\n")
+	b.WriteString("  node [shape=box];\n")
+	for _, node := range g.Nodes {
+		fmt.Fprintf(&b, "  %s [label=%s];\n", dotQuote(node.ID), dotQuote(node.Label()))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s];\n", dotQuote(edge.Source), dotQuote(edge.Target), dotQuote(edge.Type))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote returns a string as a quoted DOT ID.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Nodes are named by
+// their position in the graph since event IDs aren't valid Mermaid node IDs.
+func (g Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	names := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		names[node.ID] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", names[node.ID], mermaidEscape(node.Label()))
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", names[edge.Source], mermaidEscape(edge.Type), names[edge.Target])
+	}
+	return b.String()
+}
+
+// mermaidEscape escapes text for use in a Mermaid label.
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(
+		`"`, "#quot;",
+		"|", "#124;",
+		"<", "#lt;",
+		">", "#gt;",
+		"\n", "<br/>",
+	).Replace(s)
+}
+
+// cytoscapeElement is a node or an edge in the Cytoscape.js JSON format.
+type cytoscapeElement struct {
+	Data map[string]string `json:"data"`
+}
+
+// CytoscapeJSON is a graph in the Cytoscape.js JSON format.
+type CytoscapeJSON struct {
+	Elements struct {
+		Nodes []cytoscapeElement `json:"nodes"`
+		Edges []cytoscapeElement `json:"edges"`
+	} `json:"elements"`
+}
+
+// Cytoscape returns the graph in the Cytoscape.js JSON format, ready
+// to be encoded and passed to cytoscape() or cy.json().
+func (g Graph) Cytoscape() CytoscapeJSON {
+	var c CytoscapeJSON
+	c.Elements.Nodes = make([]cytoscapeElement, 0, len(g.Nodes))
+	c.Elements.Edges = make([]cytoscapeElement, 0, len(g.Edges))
+	for _, node := range g.Nodes {
+		c.Elements.Nodes = append(c.Elements.Nodes, cytoscapeElement{Data: map[string]string{
+			"id":    node.ID,
+			"type":  node.Type,
+			"label": node.Label(),
+		}})
+	}
+	for _, edge := range g.Edges {
+		c.Elements.Edges = append(c.Elements.Edges, cytoscapeElement{Data: map[string]string{
+			"id":     fmt.Sprintf("%s-%s-%s", edge.Source, edge.Type, edge.Target),
+			"source": edge.Source,
+			"target": edge.Target,
+			"label":  edge.Type,
+		}})
+	}
+	return c
+}
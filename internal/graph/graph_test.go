@@ -0,0 +1,79 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+var (
+	artifact = test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "EiffelArtifactCreatedEvent", 1,
+		map[string]interface{}{"identity": "pkg:generic/goer@1.0.0"})
+	published = test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "EiffelArtifactPublishedEvent", 2, nil,
+		drivers.Link{Type: "ARTIFACT", Target: artifact.ID()},
+		drivers.Link{Type: "CAUSE", Target: "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f"})
+)
+
+// Test that duplicate events and links out of the graph are left out.
+func TestNew(t *testing.T) {
+	g := New([]drivers.EiffelEvent{artifact, published, artifact})
+	assert.Equal(t, []Node{
+		{ID: artifact.ID(), Type: "EiffelArtifactCreatedEvent", Details: []string{"identity: pkg:generic/goer@1.0.0"}},
+		{ID: published.ID(), Type: "EiffelArtifactPublishedEvent"},
+	}, g.Nodes)
+	assert.Equal(t, []Edge{{Source: published.ID(), Target: artifact.ID(), Type: "ARTIFACT"}}, g.Edges)
+}
+
+func TestDOT(t *testing.T) {
+	expected := `digraph events {
+  node [shape=box];
+  "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827" [label="EiffelArtifactCreatedEvent\nidentity: pkg:generic/goer@1.0.0"];
+  "e04cf9d3-4d57-471e-bd65-f8fc20d21d84" [label="EiffelArtifactPublishedEvent"];
+  "e04cf9d3-4d57-471e-bd65-f8fc20d21d84" -> "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827" [label="ARTIFACT"];
+}
+`
+	assert.Equal(t, expected, New([]drivers.EiffelEvent{artifact, published}).DOT())
+}
+
+func TestMermaid(t *testing.T) {
+	expected := `flowchart TD
+  n0["EiffelArtifactCreatedEvent<br/>identity: pkg:generic/goer@1.0.0"]
+  n1["EiffelArtifactPublishedEvent"]
+  n1 -->|ARTIFACT| n0
+`
+	assert.Equal(t, expected, New([]drivers.EiffelEvent{artifact, published}).Mermaid())
+}
+
+func TestCytoscape(t *testing.T) {
+	data, err := json.Marshal(New([]drivers.EiffelEvent{artifact, published}).Cytoscape())
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"elements": {
+		"nodes": [
+			{"data": {"id": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "type": "EiffelArtifactCreatedEvent", "label": "EiffelArtifactCreatedEvent\nidentity: pkg:generic/goer@1.0.0"}},
+			{"data": {"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "type": "EiffelArtifactPublishedEvent", "label": "EiffelArtifactPublishedEvent"}}
+		],
+		"edges": [
+			{"data": {"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84-ARTIFACT-3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "source": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "target": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "label": "ARTIFACT"}}
+		]
+	}}`, string(data))
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// amqp implements the ingest.Broker interface against AMQP 0.9.1 brokers
+// like RabbitMQ, consuming Eiffel events from a topic exchange.
+package amqp
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+)
+
+// DefaultPrefetch is the default number of unacknowledged deliveries.
+const DefaultPrefetch = 32
+
+// Broker consumes events from an exchange through a durable queue bound to it.
+// The exchange must exist while the queue, and a dead-letter exchange and queue
+// named after it with a .dead-letter suffix, are declared if they don't.
+type Broker struct {
+	URL      string
+	Exchange string
+	Queue    string
+	// Bindings are the routing keys to bind the queue to the exchange with.
+	Bindings []string
+	Prefetch int
+}
+
+// DeadLetterName returns the name of the dead-letter exchange and queue.
+func (b *Broker) DeadLetterName() string {
+	return b.Queue + ".dead-letter"
+}
+
+// Consume connects to the broker, sets up the queues and starts consuming.
+func (b *Broker) Consume(ctx context.Context) (<-chan ingest.Delivery, error) {
+	connection, err := amqp.Dial(b.URL)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := b.setUp(connection)
+	if err != nil {
+		_ = connection.Close()
+		return nil, err
+	}
+
+	go func() {
+		<-ctx.Done()
+		// Closing the connection requeues the unacknowledged deliveries
+		// and closes the messages channel.
+		_ = connection.Close()
+	}()
+	deliveries := make(chan ingest.Delivery)
+	go func() {
+		defer close(deliveries)
+		for message := range messages {
+			select {
+			case deliveries <- &delivery{message}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// setUp declares and binds the queues and starts consuming.
+func (b *Broker) setUp(connection *amqp.Connection) (<-chan amqp.Delivery, error) {
+	channel, err := connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	prefetch := b.Prefetch
+	if prefetch < 1 {
+		prefetch = DefaultPrefetch
+	}
+	if err := channel.Qos(prefetch, 0, false); err != nil {
+		return nil, err
+	}
+	if err := channel.ExchangeDeclarePassive(b.Exchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		return nil, err
+	}
+
+	deadLetter := b.DeadLetterName()
+	if err := channel.ExchangeDeclare(deadLetter, amqp.ExchangeFanout, true, false, false, false, nil); err != nil {
+		return nil, err
+	}
+	if _, err := channel.QueueDeclare(deadLetter, true, false, false, false, nil); err != nil {
+		return nil, err
+	}
+	if err := channel.QueueBind(deadLetter, "", deadLetter, false, nil); err != nil {
+		return nil, err
+	}
+
+	arguments := amqp.Table{"x-dead-letter-exchange": deadLetter}
+	if _, err := channel.QueueDeclare(b.Queue, true, false, false, false, arguments); err != nil {
+		return nil, err
+	}
+	for _, binding := range b.Bindings {
+		if err := channel.QueueBind(b.Queue, binding, b.Exchange, false, nil); err != nil {
+			return nil, err
+		}
+	}
+	return channel.Consume(b.Queue, "", false, false, false, false, nil)
+}
+
+// delivery is an ingest.Delivery of an AMQP message.
+type delivery struct {
+	message amqp.Delivery
+}
+
+func (d *delivery) Body() []byte {
+	return d.message.Body
+}
+
+func (d *delivery) Ack() error {
+	return d.message.Ack(false)
+}
+
+// Reject the message without requeuing it, which routes it to the
+// dead-letter exchange.
+func (d *delivery) Reject() error {
+	return d.message.Reject(false)
+}
+
+func (d *delivery) Requeue() error {
+	return d.message.Nack(false, true)
+}
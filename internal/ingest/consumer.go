@@ -0,0 +1,150 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Default delays before reconnecting to a broker, doubled for every failed
+// attempt in between.
+const (
+	DefaultMinReconnectDelay = time.Second
+	DefaultMaxReconnectDelay = time.Minute
+)
+
+// errConnectionLost is returned when a broker closes its deliveries.
+var errConnectionLost = errors.New("the connection to the broker was lost")
+
+// Delivery is a message, hopefully an Eiffel event, delivered by a broker.
+// Every delivery must be settled by exactly one of Ack, Reject or Requeue.
+type Delivery interface {
+	Body() []byte
+	// Ack tells the broker that the message is handled and can be removed.
+	Ack() error
+	// Reject tells the broker that the message is invalid, which
+	// dead-letters it if the broker is set up for that.
+	Reject() error
+	// Requeue tells the broker to deliver the message again later.
+	Requeue() error
+}
+
+// Broker is a message broker that events are consumed from.
+type Broker interface {
+	// Consume connects to the broker and returns the delivered messages.
+	// The connection is closed when the context is done, and the channel is
+	// closed when the connection is. Unsettled deliveries are then delivered
+	// again.
+	Consume(ctx context.Context) (<-chan Delivery, error)
+}
+
+// Consumer ingests the events delivered by a broker. Deliveries are only
+// acknowledged after they've been stored, so that no events are lost if
+// Goer or the database goes down.
+type Consumer struct {
+	Broker   Broker
+	Ingester *Ingester
+	Logger   *log.Entry
+
+	MinReconnectDelay time.Duration
+	MaxReconnectDelay time.Duration
+}
+
+// NewConsumer creates a consumer with the default reconnect delays.
+func NewConsumer(broker Broker, ingester *Ingester, logger *log.Entry) *Consumer {
+	return &Consumer{
+		Broker:            broker,
+		Ingester:          ingester,
+		Logger:            logger,
+		MinReconnectDelay: DefaultMinReconnectDelay,
+		MaxReconnectDelay: DefaultMaxReconnectDelay,
+	}
+}
+
+// Run consumes events until the context is done, reconnecting to the broker
+// whenever the connection is lost or an event can't be stored.
+func (c *Consumer) Run(ctx context.Context) {
+	delay := c.MinReconnectDelay
+	for {
+		connected, err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			delay = c.MinReconnectDelay
+		}
+		c.Logger.Warningf("Consuming events stopped, reconnecting in %s: %s", delay, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > c.MaxReconnectDelay {
+			delay = c.MaxReconnectDelay
+		}
+	}
+}
+
+// consume handles deliveries until the connection is lost or an event can't
+// be stored. It returns whether it managed to connect.
+func (c *Consumer) consume(ctx context.Context) (bool, error) {
+	// Close the connection when returning so that the deliveries not yet
+	// handled are delivered again.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	deliveries, err := c.Broker.Consume(ctx)
+	if err != nil {
+		return false, err
+	}
+	c.Logger.Info("Consuming events")
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return true, errConnectionLost
+			}
+			if err := c.handle(ctx, delivery); err != nil {
+				return true, err
+			}
+		}
+	}
+}
+
+// handle ingests a delivered event and settles the delivery. Invalid and
+// rejected events are dead-lettered. Events that couldn't be stored are
+// requeued and returned as errors, pausing consumption for a while.
+func (c *Consumer) handle(ctx context.Context, delivery Delivery) error {
+	result, err := c.Ingester.IngestJSON(ctx, delivery.Body())
+	switch {
+	case err == nil:
+		c.Logger.Debugf("Ingested event: %s", result)
+		return delivery.Ack()
+	case Rejected(err):
+		c.Logger.Warningf("Dead-lettering event: %s", err)
+		return delivery.Reject()
+	default:
+		if requeueErr := delivery.Requeue(); requeueErr != nil {
+			c.Logger.Errorf("Error requeuing event: %s", requeueErr)
+		}
+		return err
+	}
+}
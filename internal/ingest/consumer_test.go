@@ -0,0 +1,135 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ingest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+var (
+	validEvent = []byte(`{
+		"meta": {"id": "71000000-0000-4000-8000-000000000001", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650361},
+		"data": {"name": "build"},
+		"links": []
+	}`)
+	otherEvent = []byte(`{
+		"meta": {"id": "71000000-0000-4000-8000-000000000002", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650362},
+		"data": {"name": "test"},
+		"links": []
+	}`)
+	unknownEvent = []byte(`{"meta": {"id": "71000000-0000-4000-8000-000000000003", "type": "EiffelUnknownEvent", "version": "1.0.0"}}`)
+)
+
+// failingDatabase fails to store the first events.
+type failingDatabase struct {
+	*test.MemoryDatabase
+	failures int
+}
+
+func (d *failingDatabase) InsertEvent(ctx context.Context, event drivers.EiffelEvent) error {
+	if d.failures > 0 {
+		d.failures--
+		return errors.New("the database is down")
+	}
+	return d.MemoryDatabase.InsertEvent(ctx, event)
+}
+
+// runConsumer runs a consumer until the returned function is called, which
+// waits for it to stop.
+func runConsumer(t *testing.T, broker ingest.Broker, db drivers.Database) func() {
+	ingester, err := ingest.New(db, ingest.PolicyIgnoreIdentical, &log.Entry{Logger: log.New()})
+	require.NoError(t, err)
+	consumer := ingest.NewConsumer(broker, ingester, &log.Entry{Logger: log.New()})
+	consumer.MinReconnectDelay = time.Millisecond
+	consumer.MaxReconnectDelay = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		consumer.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Test that stored and duplicate events are acknowledged and invalid ones dead-lettered.
+func TestConsumer(t *testing.T) {
+	broker := test.NewMemoryBroker()
+	db := test.NewMemoryDatabase()
+	stop := runConsumer(t, broker, db)
+	broker.Publish(validEvent)
+	broker.Publish([]byte("not json"))
+	broker.Publish(unknownEvent)
+	broker.Publish(validEvent)
+	assert.Eventually(t, func() bool {
+		return len(broker.Acked()) == 2 && len(broker.Rejected()) == 2
+	}, time.Second, time.Millisecond)
+	stop()
+
+	require.Len(t, db.Events, 1)
+	assert.Equal(t, "71000000-0000-4000-8000-000000000001", db.Events[0].ID())
+	assert.Equal(t, int64(1629449650361), db.Events[0].Time())
+	assert.Equal(t, int64(1629449650361), db.Events[0]["meta"].(map[string]interface{})["time"])
+}
+
+// Test that events that can't be stored are delivered again after reconnecting.
+func TestConsumerRetry(t *testing.T) {
+	broker := test.NewMemoryBroker()
+	db := &failingDatabase{MemoryDatabase: test.NewMemoryDatabase(), failures: 2}
+	stop := runConsumer(t, broker, db)
+	broker.Publish(validEvent)
+	assert.Eventually(t, func() bool {
+		return len(broker.Acked()) == 1
+	}, time.Second, time.Millisecond)
+	stop()
+
+	assert.Equal(t, 3, broker.Connects())
+	assert.Empty(t, broker.Rejected())
+	assert.Len(t, db.Events, 1)
+}
+
+// Test that the consumer reconnects when the connection to the broker is lost.
+func TestConsumerReconnect(t *testing.T) {
+	broker := test.NewMemoryBroker()
+	db := test.NewMemoryDatabase()
+	stop := runConsumer(t, broker, db)
+	broker.Publish(validEvent)
+	assert.Eventually(t, func() bool {
+		return len(broker.Acked()) == 1
+	}, time.Second, time.Millisecond)
+	broker.Disconnect()
+	broker.Publish(otherEvent)
+	assert.Eventually(t, func() bool {
+		return len(broker.Acked()) == 2
+	}, time.Second, time.Millisecond)
+	stop()
+
+	assert.Equal(t, 2, broker.Connects())
+	assert.Len(t, db.Events, 2)
+}
@@ -0,0 +1,82 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/eiffel-community/eiffelevents-sdk-go"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+)
+
+// Decode validates that data is an Eiffel event of a known type and version
+// and decodes it. Integers are decoded as int64, like other tools storing
+// Eiffel events do, and other numbers as float64.
+func Decode(data []byte) (drivers.EiffelEvent, error) {
+	if _, err := eiffelevents.UnmarshalAny(data); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEvent, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var event map[string]interface{}
+	if err := decoder.Decode(&event); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidEvent, err)
+	}
+	return drivers.EiffelEvent(convertNumbers(event).(map[string]interface{})), nil
+}
+
+// convertNumbers replaces the json.Numbers in a decoded value with int64s
+// or float64s.
+func convertNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, elem := range v {
+			v[key] = convertNumbers(elem)
+		}
+	case []interface{}:
+		for i, elem := range v {
+			v[i] = convertNumbers(elem)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return value
+}
+
+// IngestJSON decodes an event with Decode and ingests it.
+func (i *Ingester) IngestJSON(ctx context.Context, data []byte) (Result, error) {
+	event, err := Decode(data)
+	if err != nil {
+		return "", err
+	}
+	return i.Ingest(ctx, event)
+}
+
+// Rejected returns true if the error is the reason an event was rejected,
+// as opposed to an error storing it, so that ingesting it again is pointless.
+func Rejected(err error) bool {
+	return errors.Is(err, ErrInvalidEvent) || errors.Is(err, ErrDuplicateID) ||
+		errors.Is(err, ErrConflict) || errors.Is(err, ErrUnverified)
+}
@@ -0,0 +1,154 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// ingest stores events sent to Goer, deciding what to do with events whose
+// IDs are already stored according to a duplicate policy. Producers that
+// retry on network errors send the same event more than once, so storing
+// every event received would leave the database with duplicate IDs.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/signature"
+)
+
+// Policy decides what happens to events whose IDs are already stored.
+type Policy string
+
+const (
+	// PolicyReject rejects every event whose ID is already stored.
+	PolicyReject Policy = "reject"
+	// PolicyIgnoreIdentical ignores events identical to the stored event
+	// with the same ID and rejects events that differ from it.
+	PolicyIgnoreIdentical Policy = "ignore-identical"
+	// PolicyFlagConflicts ignores identical events and accepts events
+	// that differ from the stored event, recording them as conflicts that
+	// integrity.Deduplicate reports. The stored event is kept and is what
+	// reading the event by ID returns.
+	PolicyFlagConflicts Policy = "flag-conflicts"
+)
+
+// ParsePolicy parses the name of a policy.
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case PolicyReject, PolicyIgnoreIdentical, PolicyFlagConflicts:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy %q", name)
+	}
+}
+
+// Result is what happened to an ingested event.
+type Result string
+
+const (
+	// ResultStored means that the event was stored.
+	ResultStored Result = "stored"
+	// ResultDuplicate means that an identical event was already stored.
+	ResultDuplicate Result = "duplicate"
+	// ResultConflict means that a different event with the same ID was
+	// already stored and the event was recorded as conflicting.
+	ResultConflict Result = "conflict"
+)
+
+var (
+	// ErrInvalidEvent is returned, wrapped, for events without meta.id or meta.type.
+	ErrInvalidEvent = errors.New("invalid event")
+	// ErrDuplicateID is returned, wrapped, when an event is rejected
+	// since its ID is already stored.
+	ErrDuplicateID = errors.New("an event with the same ID is already stored")
+	// ErrConflict is returned, wrapped, when an event is rejected since
+	// a different event with the same ID is already stored.
+	ErrConflict = errors.New("a different event with the same ID is already stored")
+	// ErrUnverified is returned, wrapped, when an event is rejected since
+	// its signature is missing or can't be verified.
+	ErrUnverified = errors.New("the event's signature can't be verified")
+)
+
+// Ingester stores events in a database.
+type Ingester struct {
+	Database drivers.Database
+	Writer   drivers.Writer
+	Policy   Policy
+	Logger   *log.Entry
+	// Verifier, if set, makes the ingester reject events that are
+	// unsigned or whose signatures it can't verify.
+	Verifier *signature.Verifier
+}
+
+// New creates an ingester for the database, which must implement drivers.Writer.
+func New(db drivers.Database, policy Policy, logger *log.Entry) (*Ingester, error) {
+	writer, ok := db.(drivers.Writer)
+	if !ok {
+		return nil, drivers.ErrReadOnly
+	}
+	return &Ingester{Database: db, Writer: writer, Policy: policy, Logger: logger}, nil
+}
+
+// Ingest stores an event unless its ID is already stored, in which case
+// the policy decides whether it's rejected with an error or ignored.
+//
+// Events with the same ID ingested at the same time may still both be
+// stored. Such duplicates can be found and cleaned up with
+// integrity.Deduplicate.
+func (i *Ingester) Ingest(ctx context.Context, event drivers.EiffelEvent) (Result, error) {
+	id := event.ID()
+	if id == "" || event.Type() == "" {
+		return "", fmt.Errorf("%w: meta.id and meta.type are required", ErrInvalidEvent)
+	}
+	if i.Verifier != nil {
+		verification := i.Verifier.Verify(event)
+		switch verification.Status {
+		case signature.StatusVerified:
+		case signature.StatusUnsigned:
+			return "", fmt.Errorf("%w: %q is unsigned", ErrUnverified, id)
+		case signature.StatusInvalid:
+			return "", fmt.Errorf("%w: %q: %s", ErrUnverified, id, verification.Reason)
+		}
+	}
+	stored, err := i.Database.GetEventByID(ctx, id)
+	if errors.Is(err, drivers.ErrNotFound) {
+		if err := i.Writer.InsertEvent(ctx, event); err != nil {
+			return "", err
+		}
+		return ResultStored, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if i.Policy == PolicyReject {
+		return "", fmt.Errorf("%w: %q", ErrDuplicateID, id)
+	}
+	if stored.Equal(event) {
+		i.Logger.Debugf("Ignoring duplicate event %q", id)
+		return ResultDuplicate, nil
+	}
+	if i.Policy == PolicyIgnoreIdentical {
+		return "", fmt.Errorf("%w: %q", ErrConflict, id)
+	}
+	if err := i.Writer.InsertConflict(ctx, event); err != nil {
+		return "", err
+	}
+	i.Logger.Warningf("Event %q conflicts with the stored event with the same ID and was recorded as a conflict", id)
+	return ResultConflict, nil
+}
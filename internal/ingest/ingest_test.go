@@ -0,0 +1,106 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ingest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/internal/signature"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+// Test that duplicates are handled according to the policy.
+func TestIngest(t *testing.T) {
+	stored := test.NewEvent("70000000-0000-4000-8000-000000000001", "EiffelActivityTriggeredEvent", 100,
+		map[string]interface{}{"name": "build"})
+	identical := test.NewEvent(stored.ID(), stored.Type(), 100, map[string]interface{}{"name": "build"})
+	different := test.NewEvent(stored.ID(), stored.Type(), 100, map[string]interface{}{"name": "test"})
+	other := test.NewEvent("70000000-0000-4000-8000-000000000002", "EiffelActivityTriggeredEvent", 200, nil)
+	tests := []struct {
+		name      string
+		policy    ingest.Policy
+		event     drivers.EiffelEvent
+		expected  ingest.Result
+		err       error
+		events    int
+		conflicts int
+	}{
+		{name: "Stored", policy: ingest.PolicyReject, event: other, expected: ingest.ResultStored, events: 2},
+		{name: "RejectIdentical", policy: ingest.PolicyReject, event: identical, err: ingest.ErrDuplicateID, events: 1},
+		{name: "IgnoreIdentical", policy: ingest.PolicyIgnoreIdentical, event: identical, expected: ingest.ResultDuplicate, events: 1},
+		{name: "IgnoreIdenticalRejectsDifferent", policy: ingest.PolicyIgnoreIdentical, event: different, err: ingest.ErrConflict, events: 1},
+		{name: "FlagConflictsIgnoresIdentical", policy: ingest.PolicyFlagConflicts, event: identical, expected: ingest.ResultDuplicate, events: 1},
+		{name: "FlagConflicts", policy: ingest.PolicyFlagConflicts, event: different, expected: ingest.ResultConflict, events: 1, conflicts: 1},
+		{name: "Invalid", policy: ingest.PolicyReject, event: drivers.EiffelEvent{"data": map[string]interface{}{}}, err: ingest.ErrInvalidEvent, events: 1},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := test.NewMemoryDatabase(stored)
+			ingester, err := ingest.New(db, testCase.policy, &log.Entry{Logger: log.New()})
+			require.NoError(t, err)
+			result, err := ingester.Ingest(context.Background(), testCase.event)
+			assert.True(t, errors.Is(err, testCase.err), err)
+			assert.Equal(t, testCase.expected, result)
+			assert.Len(t, db.Events, testCase.events)
+			assert.Len(t, db.Conflicts, testCase.conflicts)
+		})
+	}
+}
+
+// Test that events are rejected when signatures are required but can't be verified.
+func TestIngestRequireSignatures(t *testing.T) {
+	db := test.NewMemoryDatabase()
+	ingester, err := ingest.New(db, ingest.PolicyReject, &log.Entry{Logger: log.New()})
+	require.NoError(t, err)
+	ingester.Verifier = &signature.Verifier{}
+
+	unsigned := test.NewEvent("70000000-0000-4000-8000-000000000003", "EiffelActivityTriggeredEvent", 100, nil)
+	_, err = ingester.Ingest(context.Background(), unsigned)
+	assert.True(t, errors.Is(err, ingest.ErrUnverified), err)
+
+	invalid := test.NewEvent("70000000-0000-4000-8000-000000000004", "EiffelActivityTriggeredEvent", 100, nil)
+	invalid["meta"].(map[string]interface{})["security"] = map[string]interface{}{
+		"integrityProtection": map[string]interface{}{"alg": "EdDSA", "signature": "c2lnbmF0dXJl"},
+	}
+	_, err = ingester.Ingest(context.Background(), invalid)
+	assert.True(t, errors.Is(err, ingest.ErrUnverified), err)
+	assert.Empty(t, db.Events)
+}
+
+// Test that an ingester can't be created for a read-only database.
+func TestNewReadOnly(t *testing.T) {
+	_, err := ingest.New(mock_drivers.NewMockDatabase(gomock.NewController(t)), ingest.PolicyReject, &log.Entry{Logger: log.New()})
+	assert.True(t, errors.Is(err, drivers.ErrReadOnly))
+}
+
+// Test that only known policies are parsed.
+func TestParsePolicy(t *testing.T) {
+	policy, err := ingest.ParsePolicy("flag-conflicts")
+	require.NoError(t, err)
+	assert.Equal(t, ingest.PolicyFlagConflicts, policy)
+	_, err = ingest.ParsePolicy("overwrite")
+	assert.Error(t, err)
+}
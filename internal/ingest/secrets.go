@@ -0,0 +1,101 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ingest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// signaturePrefix is the optional prefix of webhook signatures, naming the
+// algorithm like GitHub's and GitLab's webhooks do.
+const signaturePrefix = "sha256="
+
+// MaxClockSkew is how far the timestamp of a webhook request may be from
+// the current time. Older requests are refused so that a captured request
+// can't be replayed later.
+const MaxClockSkew = 5 * time.Minute
+
+// ErrUnauthorized is returned when a webhook request is from an unknown
+// producer or its signature doesn't match the body.
+var ErrUnauthorized = errors.New("unknown producer, invalid signature or stale timestamp")
+
+// Secrets maps the names of the producers allowed to send events over
+// webhooks to the secrets that their requests are signed with.
+type Secrets map[string]string
+
+// LoadSecrets reads the producers' secrets from a JSON file with an object
+// mapping producer names to secrets.
+func LoadSecrets(path string) (Secrets, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	var secrets Secrets
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if secrets == nil {
+		return nil, fmt.Errorf("%s: expected an object mapping producers to secrets", path)
+	}
+	for producer, secret := range secrets {
+		if secret == "" {
+			return nil, fmt.Errorf("%s: empty secret for producer %q", path, producer)
+		}
+	}
+	return secrets, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 with the secret of the timestamp,
+// in seconds since the Unix epoch, and the body joined by a period.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that the signature, optionally prefixed with sha256=, is
+// the HMAC-SHA256 of the timestamp and body with the producer's secret and
+// that the timestamp is at most MaxClockSkew from now.
+func (s Secrets) Verify(producer string, timestamp string, body []byte, signature string, now time.Time) error {
+	secret, ok := s[producer]
+	if !ok || producer == "" {
+		return ErrUnauthorized
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrUnauthorized
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return ErrUnauthorized
+	}
+	expected := Sign(secret, timestamp, body)
+	signature = strings.ToLower(strings.TrimPrefix(signature, signaturePrefix))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrUnauthorized
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package ingest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+)
+
+// Test that secrets are loaded from JSON files without empty secrets.
+func TestLoadSecrets(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name     string
+		content  string
+		expected ingest.Secrets
+	}{
+		{name: "Valid", content: `{"jenkins": "s3cr3t", "gitlab": "0th3r"}`, expected: ingest.Secrets{"jenkins": "s3cr3t", "gitlab": "0th3r"}},
+		{name: "EmptySecret", content: `{"jenkins": ""}`},
+		{name: "NotAnObject", content: `["jenkins"]`},
+		{name: "Null", content: `null`},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(dir, testCase.name+".json")
+			require.NoError(t, os.WriteFile(path, []byte(testCase.content), 0o600))
+			secrets, err := ingest.LoadSecrets(path)
+			if testCase.expected == nil {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, secrets)
+		})
+	}
+	_, err := ingest.LoadSecrets(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+// Test that only recent signatures of the timestamp and body with the
+// producer's secret are accepted.
+func TestVerifySecret(t *testing.T) {
+	secrets := ingest.Secrets{"jenkins": "s3cr3t", "gitlab": "0th3r"}
+	now := time.Unix(1629449650, 0)
+	timestamp := "1629449650"
+	body := []byte(`[{"meta": {}}]`)
+	signature := ingest.Sign("s3cr3t", timestamp, body)
+	tests := []struct {
+		name      string
+		producer  string
+		timestamp string
+		body      []byte
+		signature string
+		now       time.Time
+		valid     bool
+	}{
+		{name: "Valid", producer: "jenkins", timestamp: timestamp, body: body, signature: signature, now: now, valid: true},
+		{name: "Prefixed", producer: "jenkins", timestamp: timestamp, body: body, signature: "sha256=" + signature, now: now, valid: true},
+		{name: "SlightlyLate", producer: "jenkins", timestamp: timestamp, body: body, signature: signature, now: now.Add(ingest.MaxClockSkew), valid: true},
+		{name: "SlightlyEarly", producer: "jenkins", timestamp: timestamp, body: body, signature: signature, now: now.Add(-time.Minute), valid: true},
+		{name: "Stale", producer: "jenkins", timestamp: timestamp, body: body, signature: signature, now: now.Add(ingest.MaxClockSkew + time.Second)},
+		{name: "FromTheFuture", producer: "jenkins", timestamp: timestamp, body: body, signature: signature, now: now.Add(-ingest.MaxClockSkew - time.Second)},
+		{name: "ModifiedTimestamp", producer: "jenkins", timestamp: "1629449651", body: body, signature: signature, now: now},
+		{name: "NoTimestamp", producer: "jenkins", body: body, signature: signature, now: now},
+		{name: "OtherProducer", producer: "gitlab", timestamp: timestamp, body: body, signature: signature, now: now},
+		{name: "UnknownProducer", producer: "travis", timestamp: timestamp, body: body, signature: signature, now: now},
+		{name: "NoProducer", timestamp: timestamp, body: body, signature: signature, now: now},
+		{name: "ModifiedBody", producer: "jenkins", timestamp: timestamp, body: []byte("[]"), signature: signature, now: now},
+		{name: "NoSignature", producer: "jenkins", timestamp: timestamp, body: body, now: now},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := secrets.Verify(testCase.producer, testCase.timestamp, testCase.body, testCase.signature, testCase.now)
+			if testCase.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ingest.ErrUnauthorized)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package integrity
+
+import (
+	"context"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+)
+
+// Duplicate is the stored copies of an event ID used by more than one event.
+type Duplicate struct {
+	ID string `json:"id"`
+	// Kept is the first stored copy, which is kept when cleaning up.
+	Kept drivers.StoredEvent `json:"kept"`
+	// Identical are the copies identical to the kept one, which are
+	// deleted when cleaning up.
+	Identical []drivers.StoredEvent `json:"identical"`
+	// Conflicting are the copies that differ from the kept one, including
+	// those recorded as conflicts when they were ingested. Which of them
+	// is right can't be decided automatically so they're never deleted.
+	Conflicting []drivers.StoredEvent `json:"conflicting"`
+}
+
+// DeduplicationReport is the result of a deduplication pass.
+type DeduplicationReport struct {
+	Duplicates []Duplicate `json:"duplicates"`
+	// Cleaned is true if the identical copies were deleted.
+	Cleaned bool `json:"cleaned"`
+	// Deleted is the number of copies deleted.
+	Deleted int `json:"deleted"`
+}
+
+// OK returns true if no duplicates remain in the database.
+func (r DeduplicationReport) OK() bool {
+	for _, duplicate := range r.Duplicates {
+		if len(duplicate.Conflicting) > 0 || (!r.Cleaned && len(duplicate.Identical) > 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Deduplicate finds the event IDs used by more than one stored event and
+// compares the copies with the first stored one. If clean is true the copies
+// identical to the first one are deleted, so that reading the event no longer
+// depends on which copy the database happens to find first. The database
+// must implement drivers.Writer. Events recorded as conflicts when they were
+// ingested are reported as conflicting copies of the stored events.
+func Deduplicate(ctx context.Context, db drivers.Database, clean bool) (DeduplicationReport, error) {
+	writer, ok := db.(drivers.Writer)
+	if !ok {
+		return DeduplicationReport{}, drivers.ErrReadOnly
+	}
+	_, _, duplicateIDs, err := scanIDs(ctx, db)
+	if err != nil {
+		return DeduplicationReport{}, err
+	}
+
+	report := DeduplicationReport{Duplicates: []Duplicate{}, Cleaned: clean}
+	for _, duplicateID := range duplicateIDs {
+		stored, err := writer.GetStoredEvents(ctx, duplicateID.ID)
+		if err != nil {
+			return DeduplicationReport{}, err
+		}
+		if len(stored) < 2 {
+			// Cleaned up since the scan.
+			continue
+		}
+		duplicate := Duplicate{
+			ID:          duplicateID.ID,
+			Kept:        stored[0],
+			Identical:   []drivers.StoredEvent{},
+			Conflicting: []drivers.StoredEvent{},
+		}
+		for _, other := range stored[1:] {
+			if other.Event.Equal(duplicate.Kept.Event) {
+				duplicate.Identical = append(duplicate.Identical, other)
+			} else {
+				duplicate.Conflicting = append(duplicate.Conflicting, other)
+			}
+		}
+		if clean && len(duplicate.Identical) > 0 {
+			if err := writer.DeleteStoredEvents(ctx, duplicate.Identical); err != nil {
+				return DeduplicationReport{}, err
+			}
+			report.Deleted += len(duplicate.Identical)
+		}
+		report.Duplicates = append(report.Duplicates, duplicate)
+	}
+
+	if err := addConflicts(ctx, writer, &report); err != nil {
+		return DeduplicationReport{}, err
+	}
+	return report, nil
+}
+
+// addConflicts adds the recorded conflicts to the report, adding the
+// duplicates of IDs that are only stored once.
+func addConflicts(ctx context.Context, writer drivers.Writer, report *DeduplicationReport) error {
+	conflicts, err := writer.GetConflicts(ctx)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]int, len(report.Duplicates))
+	for i, duplicate := range report.Duplicates {
+		byID[duplicate.ID] = i
+	}
+	for _, conflict := range conflicts {
+		id := conflict.Event.ID()
+		i, ok := byID[id]
+		if !ok {
+			stored, err := writer.GetStoredEvents(ctx, id)
+			if err != nil {
+				return err
+			}
+			if len(stored) == 0 {
+				// The stored event was deleted since.
+				continue
+			}
+			i = len(report.Duplicates)
+			byID[id] = i
+			report.Duplicates = append(report.Duplicates, Duplicate{
+				ID:          id,
+				Kept:        stored[0],
+				Identical:   []drivers.StoredEvent{},
+				Conflicting: []drivers.StoredEvent{},
+			})
+		}
+		report.Duplicates[i].Conflicting = append(report.Duplicates[i].Conflicting, conflict)
+	}
+	return nil
+}
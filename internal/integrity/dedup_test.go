@@ -0,0 +1,91 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package integrity_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/integrity"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+var (
+	original  = test.NewEvent("61000000-0000-4000-8000-000000000001", "EiffelArtifactCreatedEvent", 100, map[string]interface{}{"identity": "pkg:a"})
+	retried   = test.NewEvent(original.ID(), "EiffelArtifactCreatedEvent", 100, map[string]interface{}{"identity": "pkg:a"})
+	changed   = test.NewEvent(original.ID(), "EiffelArtifactCreatedEvent", 100, map[string]interface{}{"identity": "pkg:b"})
+	unrelated = test.NewEvent("61000000-0000-4000-8000-000000000002", "EiffelArtifactCreatedEvent", 200, nil)
+)
+
+// Test that duplicates are only reported unless cleaning up is requested.
+func TestDeduplicateReport(t *testing.T) {
+	db := test.NewMemoryDatabase(original, unrelated, retried, changed)
+	report, err := integrity.Deduplicate(context.Background(), db, false)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, 0, report.Deleted)
+	require.Len(t, report.Duplicates, 1)
+	assert.Equal(t, original, report.Duplicates[0].Kept.Event)
+	assert.Len(t, report.Duplicates[0].Identical, 1)
+	assert.Len(t, report.Duplicates[0].Conflicting, 1)
+	assert.Len(t, db.Events, 4)
+}
+
+// Test that identical copies are deleted and conflicting ones kept.
+func TestDeduplicateClean(t *testing.T) {
+	db := test.NewMemoryDatabase(original, unrelated, retried, changed)
+	report, err := integrity.Deduplicate(context.Background(), db, true)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, 1, report.Deleted)
+	assert.Equal(t, []drivers.EiffelEvent{original, unrelated, changed}, db.Events)
+
+	db = test.NewMemoryDatabase(original, unrelated, retried)
+	report, err = integrity.Deduplicate(context.Background(), db, true)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, []drivers.EiffelEvent{original, unrelated}, db.Events)
+}
+
+// Test that recorded conflicts are reported with the stored events and never deleted.
+func TestDeduplicateConflicts(t *testing.T) {
+	db := test.NewMemoryDatabase(original, unrelated, retried)
+	db.Conflicts = []drivers.EiffelEvent{changed, test.NewEvent(unrelated.ID(), "EiffelArtifactCreatedEvent", 300, nil)}
+	report, err := integrity.Deduplicate(context.Background(), db, true)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, 1, report.Deleted)
+	require.Len(t, report.Duplicates, 2)
+	assert.Equal(t, original, report.Duplicates[0].Kept.Event)
+	assert.Equal(t, []drivers.StoredEvent{{Event: changed, Location: "conflicts", Ref: 0}}, report.Duplicates[0].Conflicting)
+	assert.Equal(t, unrelated, report.Duplicates[1].Kept.Event)
+	assert.Empty(t, report.Duplicates[1].Identical)
+	assert.Len(t, report.Duplicates[1].Conflicting, 1)
+	assert.Len(t, db.Conflicts, 2)
+}
+
+// Test that deduplication requires a database that events can be deleted from.
+func TestDeduplicateReadOnly(t *testing.T) {
+	_, err := integrity.Deduplicate(context.Background(), mock_drivers.NewMockDatabase(gomock.NewController(t)), false)
+	assert.True(t, errors.Is(err, drivers.ErrReadOnly))
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// integrity checks the links between the events in a database, finding
+// links to events that never arrived, links to events of the wrong type
+// and events stored more than once.
+package integrity
+
+import (
+	"context"
+	"sort"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// DefaultMaxItems is the default number of problems of each kind to list in a report.
+const DefaultMaxItems = 1000
+
+// LinkProblem is a problem with a link.
+type LinkProblem struct {
+	// Source is the ID of the event with the link.
+	Source     string       `json:"source"`
+	SourceType string       `json:"sourceType"`
+	Link       drivers.Link `json:"link"`
+	// TargetType is the type of the link's target, if it exists.
+	TargetType string `json:"targetType,omitempty"`
+}
+
+// LinkProblems are the problems of a kind found with links. Count is the
+// total number of problems, which may be more than the listed ones.
+type LinkProblems struct {
+	Count int           `json:"count"`
+	Items []LinkProblem `json:"items"`
+}
+
+// DuplicateID is an event ID that's used by more than one stored event.
+type DuplicateID struct {
+	ID    string   `json:"id"`
+	Count int      `json:"count"`
+	Types []string `json:"types"`
+}
+
+// DuplicateIDs are the duplicate event IDs found. Count is the total number
+// of duplicate IDs, which may be more than the listed ones.
+type DuplicateIDs struct {
+	Count int           `json:"count"`
+	Items []DuplicateID `json:"items"`
+}
+
+// Report is the result of an integrity check.
+type Report struct {
+	// Events is the number of events checked.
+	Events int `json:"events"`
+	// DanglingLinks are links to events that aren't in the database.
+	// Links with a domain ID target other event repositories and are
+	// never considered dangling.
+	DanglingLinks LinkProblems `json:"danglingLinks"`
+	// IllegalLinks are links to events of types that the Eiffel link
+	// rules don't allow for the link type.
+	IllegalLinks LinkProblems `json:"illegalLinks"`
+	DuplicateIDs DuplicateIDs `json:"duplicateIds"`
+}
+
+// OK returns true if no problems were found.
+func (r Report) OK() bool {
+	return r.DanglingLinks.Count == 0 && r.IllegalLinks.Count == 0 && r.DuplicateIDs.Count == 0
+}
+
+// Check the integrity of all events in the database, listing at most
+// maxItems problems of each kind, or all of them if maxItems is negative.
+//
+// The events are read twice, first to learn the type of every event ID
+// and then to check the links, so memory use grows with the number of
+// events but not with the number of links.
+func Check(ctx context.Context, db drivers.Database, maxItems int) (Report, error) {
+	report := Report{
+		DanglingLinks: LinkProblems{Items: []LinkProblem{}},
+		IllegalLinks:  LinkProblems{Items: []LinkProblem{}},
+		DuplicateIDs:  DuplicateIDs{Items: []DuplicateID{}},
+	}
+	events, types, duplicates, err := scanIDs(ctx, db)
+	if err != nil {
+		return Report{}, err
+	}
+	report.Events = events
+
+	err = forEachEvent(ctx, db, func(event drivers.EiffelEvent) {
+		for _, link := range event.Links() {
+			problem := LinkProblem{Source: event.ID(), SourceType: event.Type(), Link: link}
+			targetType, exists := types[link.Target]
+			switch {
+			case !exists && link.DomainID == "":
+				report.DanglingLinks.add(problem, maxItems)
+			case exists && !legalTarget(link.Type, event.Type(), targetType):
+				problem.TargetType = targetType
+				report.IllegalLinks.add(problem, maxItems)
+			}
+		}
+	})
+	if err != nil {
+		return Report{}, err
+	}
+
+	report.DuplicateIDs.Count = len(duplicates)
+	report.DuplicateIDs.Items = append(report.DuplicateIDs.Items, duplicates...)
+	if maxItems >= 0 && len(report.DuplicateIDs.Items) > maxItems {
+		report.DuplicateIDs.Items = report.DuplicateIDs.Items[:maxItems]
+	}
+	return report, nil
+}
+
+// scanIDs reads all events in the database, returning their number, the
+// type of every event ID and the IDs used by more than one event, sorted.
+func scanIDs(ctx context.Context, db drivers.Database) (int, map[string]string, []DuplicateID, error) {
+	events := 0
+	types := map[string]string{}
+	duplicates := map[string]*DuplicateID{}
+	err := forEachEvent(ctx, db, func(event drivers.EiffelEvent) {
+		events++
+		id := event.ID()
+		firstType, seen := types[id]
+		if !seen {
+			types[id] = event.Type()
+			return
+		}
+		duplicate, ok := duplicates[id]
+		if !ok {
+			duplicate = &DuplicateID{ID: id, Count: 1, Types: []string{firstType}}
+			duplicates[id] = duplicate
+		}
+		duplicate.Count++
+		duplicate.Types = append(duplicate.Types, event.Type())
+	})
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	sorted := make([]DuplicateID, 0, len(duplicates))
+	for _, duplicate := range duplicates {
+		sorted = append(sorted, *duplicate)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return events, types, sorted, nil
+}
+
+// add a problem, listing it if there's room for it.
+func (p *LinkProblems) add(problem LinkProblem, maxItems int) {
+	p.Count++
+	if maxItems < 0 || len(p.Items) < maxItems {
+		p.Items = append(p.Items, problem)
+	}
+}
+
+// forEachEvent calls fn with every event in the database.
+func forEachEvent(ctx context.Context, db drivers.Database, fn func(drivers.EiffelEvent)) error {
+	stream, _, err := db.GetEvents(ctx, requests.MultipleEventsRequest{
+		Unpaged: true,
+		Count:   requests.CountNone,
+	})
+	if err != nil {
+		return err
+	}
+	for stream.Next(ctx) {
+		fn(stream.Event())
+	}
+	err = stream.Err()
+	if closeErr := stream.Close(ctx); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,83 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package integrity_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/integrity"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+const missingID = "60000000-0000-4000-8000-000000000000"
+
+var (
+	triggered = test.NewEvent("60000000-0000-4000-8000-000000000001", "EiffelActivityTriggeredEvent", 100, nil)
+	artifact  = test.NewEvent("60000000-0000-4000-8000-000000000002", "EiffelArtifactCreatedEvent", 200, nil,
+		drivers.Link{Type: "CONTEXT", Target: triggered.ID()},
+		drivers.Link{Type: "CAUSE", Target: missingID},
+		drivers.Link{Type: "CAUSE", Target: missingID, DomainID: "other.domain"})
+	newVersion = test.NewEvent("60000000-0000-4000-8000-000000000003", "EiffelArtifactCreatedEvent", 300, nil,
+		drivers.Link{Type: "PREVIOUS_VERSION", Target: artifact.ID()},
+		drivers.Link{Type: "CONTEXT", Target: artifact.ID()},
+		drivers.Link{Type: "ARTIFACT", Target: triggered.ID()})
+	duplicate = test.NewEvent(triggered.ID(), "EiffelActivityStartedEvent", 400, nil,
+		drivers.Link{Type: "ACTIVITY_EXECUTION", Target: triggered.ID()})
+)
+
+// Test that dangling links, illegal links and duplicate IDs are all found.
+func TestCheck(t *testing.T) {
+	report, err := integrity.Check(context.Background(), test.NewMemoryDatabase(triggered, artifact, newVersion, duplicate), -1)
+	require.NoError(t, err)
+	assert.False(t, report.OK())
+	assert.Equal(t, 4, report.Events)
+	assert.Equal(t, integrity.LinkProblems{Count: 1, Items: []integrity.LinkProblem{
+		{Source: artifact.ID(), SourceType: artifact.Type(), Link: artifact.Links()[1]},
+	}}, report.DanglingLinks)
+	assert.Equal(t, integrity.LinkProblems{Count: 2, Items: []integrity.LinkProblem{
+		{Source: newVersion.ID(), SourceType: newVersion.Type(), Link: newVersion.Links()[1], TargetType: "EiffelArtifactCreatedEvent"},
+		{Source: newVersion.ID(), SourceType: newVersion.Type(), Link: newVersion.Links()[2], TargetType: "EiffelActivityTriggeredEvent"},
+	}}, report.IllegalLinks)
+	assert.Equal(t, integrity.DuplicateIDs{Count: 1, Items: []integrity.DuplicateID{
+		{ID: triggered.ID(), Count: 2, Types: []string{"EiffelActivityTriggeredEvent", "EiffelActivityStartedEvent"}},
+	}}, report.DuplicateIDs)
+}
+
+// Test that the number of listed problems is limited but the counts aren't.
+func TestCheckMaxItems(t *testing.T) {
+	report, err := integrity.Check(context.Background(), test.NewMemoryDatabase(triggered, artifact, newVersion, duplicate), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.IllegalLinks.Count)
+	assert.Len(t, report.IllegalLinks.Items, 1)
+
+	report, err = integrity.Check(context.Background(), test.NewMemoryDatabase(triggered, artifact, newVersion, duplicate), 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.DuplicateIDs.Count)
+	assert.Empty(t, report.DuplicateIDs.Items)
+}
+
+// Test that a consistent database passes the check.
+func TestCheckOK(t *testing.T) {
+	report, err := integrity.Check(context.Background(), test.NewMemoryDatabase(triggered), integrity.DefaultMaxItems)
+	require.NoError(t, err)
+	assert.True(t, report.OK())
+	assert.Equal(t, 1, report.Events)
+}
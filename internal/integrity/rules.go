@@ -0,0 +1,70 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package integrity
+
+// sameType marks link types whose targets must be of the same type as
+// the linking event.
+const sameType = "<same type>"
+
+// legalTargets maps link types to the event types their targets may have,
+// according to the Eiffel link rules. Link types that may target any event,
+// like CAUSE, and link types that aren't known are left out.
+var legalTargets = map[string][]string{
+	"CONTEXT":                     {"EiffelActivityTriggeredEvent", "EiffelTestSuiteStartedEvent"},
+	"FLOW_CONTEXT":                {"EiffelFlowContextDefinedEvent"},
+	"ACTIVITY_EXECUTION":          {"EiffelActivityTriggeredEvent"},
+	"PREVIOUS_ACTIVITY_EXECUTION": {"EiffelActivityTriggeredEvent"},
+	"PREVIOUS_VERSION":            {sameType},
+	"COMPOSITION":                 {"EiffelCompositionDefinedEvent"},
+	"ENVIRONMENT":                 {"EiffelEnvironmentDefinedEvent"},
+	"ARTIFACT":                    {"EiffelArtifactCreatedEvent"},
+	"SUBJECT": {
+		"EiffelArtifactCreatedEvent",
+		"EiffelCompositionDefinedEvent",
+		"EiffelSourceChangeCreatedEvent",
+		"EiffelSourceChangeSubmittedEvent",
+	},
+	"ELEMENT": {
+		"EiffelArtifactCreatedEvent",
+		"EiffelCompositionDefinedEvent",
+		"EiffelSourceChangeSubmittedEvent",
+	},
+	"BASE":                  {"EiffelSourceChangeSubmittedEvent"},
+	"CHANGE":                {"EiffelSourceChangeCreatedEvent"},
+	"TEST_SUITE_EXECUTION":  {"EiffelTestSuiteStartedEvent"},
+	"TEST_CASE_EXECUTION":   {"EiffelTestCaseTriggeredEvent"},
+	"IUT":                   {"EiffelArtifactCreatedEvent", "EiffelCompositionDefinedEvent"},
+	"TERC":                  {"EiffelTestExecutionRecipeCollectionCreatedEvent"},
+	"MODIFIED_ANNOUNCEMENT": {"EiffelAnnouncementPublishedEvent"},
+	"SUB_CONFIDENCE_LEVEL":  {"EiffelConfidenceLevelModifiedEvent"},
+	"REUSED_ARTIFACT":       {"EiffelArtifactCreatedEvent"},
+	"VERIFICATION_BASIS":    {"EiffelTestCaseFinishedEvent", "EiffelTestSuiteFinishedEvent"},
+}
+
+// legalTarget returns true if an event of the source type may link to an
+// event of the target type with the link type.
+func legalTarget(linkType string, sourceType string, targetType string) bool {
+	targets, ok := legalTargets[linkType]
+	if !ok {
+		return true
+	}
+	for _, legal := range targets {
+		if legal == targetType || (legal == sameType && sourceType == targetType) {
+			return true
+		}
+	}
+	return false
+}
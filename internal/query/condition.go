@@ -18,6 +18,8 @@
 // using pigeon as well as adding functions to the query.go package.
 package query
 
+import "fmt"
+
 //go:generate pigeon -o query.go query.peg
 
 type Condition struct {
@@ -27,6 +29,23 @@ type Condition struct {
 	TypeConv string
 }
 
+// ParseConditions parses a query string like "meta.type=EiffelActivityTriggeredEvent&data.name"
+// into its conditions. Comparison operators and values are URL encoded, as in the query part of a URL.
+func ParseConditions(rawQuery string) ([]Condition, error) {
+	if rawQuery == "" {
+		return nil, nil
+	}
+	res, err := Parse("nofile", []byte(rawQuery))
+	if err != nil {
+		return nil, err
+	}
+	conditions, ok := res.([]Condition)
+	if !ok {
+		return nil, fmt.Errorf("query parser unexpectedly returned a %T value from the query %q", res, rawQuery)
+	}
+	return conditions, nil
+}
+
 // toIfaceSlice converts an interface to a slice of interfaces.
 func toIfaceSlice(v interface{}) []interface{} {
 	if v == nil {
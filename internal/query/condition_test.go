@@ -0,0 +1,53 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that query strings are parsed into conditions.
+func TestParseConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawQuery string
+		expected []Condition
+		wantErr  bool
+	}{
+		{name: "Empty", rawQuery: "", expected: nil},
+		{name: "Equal", rawQuery: "meta.type=EiffelActivityTriggeredEvent", expected: []Condition{
+			{Field: "meta.type", Op: "=", Value: "EiffelActivityTriggeredEvent"},
+		}},
+		{name: "Multiple", rawQuery: "int(meta.time)%3E=10&!data.name", expected: []Condition{
+			{Field: "meta.time", Op: ">=", Value: "10", TypeConv: "int"},
+			{Field: "data.name", Op: "exists", Value: "false", TypeConv: "bool"},
+		}},
+		{name: "MissingField", rawQuery: "=value", wantErr: true},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			conditions, err := ParseConditions(testCase.rawQuery)
+			if testCase.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, conditions)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package query
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Match evaluates the condition against the value of a field, using the same
+// semantics as the database query the condition would otherwise be turned into:
+// values are compared as strings unless a type conversion is given, comparisons
+// between different types never match, and array fields match if any element does.
+// exists tells whether the field is present at all.
+func (c Condition) Match(value interface{}, exists bool) bool {
+	if c.Op == "exists" {
+		want, err := strconv.ParseBool(c.Value)
+		return err == nil && want == exists
+	}
+	if !exists {
+		// A missing field is only different from everything.
+		return c.Op == "!="
+	}
+	if v := reflect.ValueOf(value); v.Kind() == reflect.Slice || v.Kind() == reflect.Array {
+		// For != no element may be equal to the value,
+		// for all other operators any element may match.
+		if c.Op == "!=" {
+			equal := c
+			equal.Op = "="
+			return !equal.Match(value, exists)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if c.matchScalar(v.Index(i).Interface()) {
+				return true
+			}
+		}
+		return false
+	}
+	return c.matchScalar(value)
+}
+
+// matchScalar evaluates the condition against a non-array value.
+func (c Condition) matchScalar(value interface{}) bool {
+	var cmp int
+	switch c.TypeConv {
+	case "int", "double":
+		want, err := strconv.ParseFloat(c.Value, 64)
+		if err != nil {
+			return false
+		}
+		got, ok := toFloat(value)
+		if !ok {
+			return c.Op == "!="
+		}
+		switch {
+		case got < want:
+			cmp = -1
+		case got > want:
+			cmp = 1
+		}
+	case "bool":
+		want, err := strconv.ParseBool(c.Value)
+		if err != nil {
+			return false
+		}
+		got, ok := value.(bool)
+		if !ok {
+			return c.Op == "!="
+		}
+		if got != want {
+			// Booleans aren't ordered; false sorts before true.
+			cmp = 1
+			if !got {
+				cmp = -1
+			}
+		}
+	default:
+		got, ok := value.(string)
+		if !ok {
+			return c.Op == "!="
+		}
+		cmp = strings.Compare(got, c.Value)
+	}
+	switch c.Op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return false
+	}
+}
+
+// toFloat converts a numeric value of any type to a float64.
+func toFloat(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package query
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that conditions are evaluated against field values like the database would.
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition Condition
+		value     interface{}
+		exists    bool
+		expected  bool
+	}{
+		{name: "StringEqual", condition: Condition{Field: "f", Op: "=", Value: "a"}, value: "a", exists: true, expected: true},
+		{name: "StringNotEqual", condition: Condition{Field: "f", Op: "!=", Value: "a"}, value: "b", exists: true, expected: true},
+		{name: "StringLess", condition: Condition{Field: "f", Op: "<", Value: "b"}, value: "a", exists: true, expected: true},
+		{name: "StringAgainstNumber", condition: Condition{Field: "f", Op: "=", Value: "1"}, value: int64(1), exists: true, expected: false},
+		{name: "IntGreater", condition: Condition{Field: "f", Op: ">", Value: "10", TypeConv: "int"}, value: int64(11), exists: true, expected: true},
+		{name: "IntLessOrEqual", condition: Condition{Field: "f", Op: "<=", Value: "10", TypeConv: "int"}, value: int32(11), exists: true, expected: false},
+		{name: "DoubleAgainstFloat", condition: Condition{Field: "f", Op: ">=", Value: "1.5", TypeConv: "double"}, value: 1.5, exists: true, expected: true},
+		{name: "IntAgainstString", condition: Condition{Field: "f", Op: "=", Value: "1", TypeConv: "int"}, value: "1", exists: true, expected: false},
+		{name: "BoolEqual", condition: Condition{Field: "f", Op: "=", Value: "true", TypeConv: "bool"}, value: true, exists: true, expected: true},
+		{name: "Exists", condition: Condition{Field: "f", Op: "exists", Value: "true", TypeConv: "bool"}, value: "x", exists: true, expected: true},
+		{name: "NotExists", condition: Condition{Field: "f", Op: "exists", Value: "false", TypeConv: "bool"}, exists: false, expected: true},
+		{name: "MissingEqual", condition: Condition{Field: "f", Op: "=", Value: "a"}, exists: false, expected: false},
+		{name: "MissingNotEqual", condition: Condition{Field: "f", Op: "!=", Value: "a"}, exists: false, expected: true},
+		{name: "ArrayAnyEqual", condition: Condition{Field: "f", Op: "=", Value: "b"}, value: []interface{}{"a", "b"}, exists: true, expected: true},
+		{name: "ArrayNoneEqual", condition: Condition{Field: "f", Op: "!=", Value: "b"}, value: []interface{}{"a", "b"}, exists: true, expected: false},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, testCase.condition.Match(testCase.value, testCase.exists))
+		})
+	}
+}
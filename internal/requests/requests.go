@@ -20,16 +20,119 @@ package requests
 
 import "github.com/eiffel-community/eiffel-goer/internal/query"
 
+// Count strategies for the total number of items matching a MultipleEventsRequest.
+const (
+	// CountExact counts every matching event.
+	CountExact = "exact"
+	// CountEstimated uses cheaper counts that may be approximate but still
+	// tell whether there are more pages after the requested one.
+	CountEstimated = "estimated"
+	// CountNone doesn't count the matching events at all.
+	CountNone = "none"
+)
+
 type MultipleEventsRequest struct {
-	Shallow       bool  `schema:"shallow"` // TODO: Unused
-	PageNo        int32 `schema:"pageNo"`
-	PageSize      int32 `schema:"pageSize"`
-	PageStartItem int32 `schema:"pageStartItem"`
-	Lazy          bool  `schema:"lazy"`
-	Readable      bool  `schema:"readable"` // TODO: Unused
-	Conditions    []query.Condition
+	Shallow       bool   `schema:"shallow"` // TODO: Unused
+	PageNo        int32  `schema:"pageNo"`
+	PageSize      int32  `schema:"pageSize"`
+	PageStartItem int32  `schema:"pageStartItem"`
+	Lazy          bool   `schema:"lazy"`
+	Readable      bool   `schema:"readable"` // TODO: Unused
+	Count         string `schema:"count"`
+	Format        string `schema:"format"`
+	Columns       string `schema:"columns"`
+	// Latest leaves out events that have newer versions, linking to
+	// them with PREVIOUS_VERSION links.
+	Latest bool `schema:"latest"`
+	// Verified leaves out events whose signatures can't be verified.
+	Verified bool `schema:"verified"`
+	// Unpaged makes the database return all matching events instead of
+	// a single page. It's used for streaming exports and can't be set
+	// from the query string.
+	Unpaged    bool `schema:"-"`
+	Conditions []query.Condition
+}
+
+// ValidCount returns true if the request's count strategy is known.
+func (r MultipleEventsRequest) ValidCount() bool {
+	switch r.Count {
+	case CountExact, CountEstimated, CountNone:
+		return true
+	default:
+		return false
+	}
 }
 
 type SingleEventRequest struct {
 	Shallow bool `schema:"shallow"` // TODO: Unused
 }
+
+type StreamRequest struct {
+	// LastEventID is an alternative to the Last-Event-ID header for
+	// clients that can't set headers.
+	LastEventID string `schema:"lastEventId"`
+}
+
+type LinkedByRequest struct {
+	// Type is a comma separated list of link types. Links of all types
+	// are included if it's empty.
+	Type string `schema:"type"`
+}
+
+type ActivitiesRequest struct {
+	PageNo   int32 `schema:"pageNo"`
+	PageSize int32 `schema:"pageSize"`
+	// Name is the data.name of the activities to include. Activities
+	// with any name are included if it's empty.
+	Name string `schema:"name"`
+	// From and To limit the activities to those triggered within the
+	// time range, in milliseconds since the epoch. Zero means no limit.
+	From int64 `schema:"from"`
+	To   int64 `schema:"to"`
+}
+
+type ArtifactsRequest struct {
+	// Identity is the data.identity of the artifacts, usually a purl.
+	Identity string `schema:"identity"`
+}
+
+type ChangesRequest struct {
+	// Commit is the git commit SHA of the source changes.
+	Commit string `schema:"commit"`
+	// ChangeID is the Gerrit change ID of the source changes.
+	ChangeID string `schema:"changeId"`
+	// LinkTypes is a comma separated list of link types to follow
+	// downstream from the source changes.
+	LinkTypes string `schema:"linkTypes"`
+	Levels    int32  `schema:"levels"`
+	Limit     int32  `schema:"limit"`
+}
+
+type TestSuiteSummaryRequest struct {
+	Levels int32 `schema:"levels"`
+	Limit  int32 `schema:"limit"`
+}
+
+type IntegrityRequest struct {
+	// Limit is the maximum number of problems of each kind to list,
+	// or -1 to list all of them.
+	Limit int `schema:"limit"`
+}
+
+type RetentionRequest struct {
+	// Policy is the retention policy to check instead of the configured one.
+	Policy string `schema:"policy"`
+}
+
+type SearchRequest struct {
+	Limit    int32  `schema:"limit"`
+	Levels   int32  `schema:"levels"`
+	Tree     bool   `schema:"tree"`     // TODO: Unused
+	Shallow  bool   `schema:"shallow"`  // TODO: Unused
+	Readable bool   `schema:"readable"` // TODO: Unused
+	Format   string `schema:"format"`
+	// DLT and ULT are the link types to follow downstream and upstream,
+	// read from the request body.
+	DLT []string `schema:"-" json:"dlt"`
+	ULT []string `schema:"-" json:"ult"`
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package responses
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Response formats that can be negotiated.
+const (
+	FormatJSON   = "json"
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	// Graph formats, see the graph package.
+	FormatDOT       = "dot"
+	FormatMermaid   = "mermaid"
+	FormatCytoscape = "cytoscape"
+)
+
+// ContentTypes maps response formats to their media types.
+var ContentTypes = map[string]string{
+	FormatJSON:    "application/json",
+	FormatNDJSON:  "application/x-ndjson",
+	FormatCSV:     "text/csv",
+	FormatDOT:     "text/vnd.graphviz",
+	FormatMermaid: "text/vnd.mermaid",
+	// Cytoscape.js JSON is plain JSON, so it can only be requested
+	// with the format query parameter.
+	FormatCytoscape: "application/json",
+}
+
+// NegotiateFormat picks the response format for a request among the offered
+// formats. An explicit "format" query parameter takes precedence over the
+// Accept header, and the first offered format is the default. An error is
+// returned if none of the offered formats is acceptable to the client.
+func NegotiateFormat(r *http.Request, offered ...string) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		for _, f := range offered {
+			if f == format {
+				return f, nil
+			}
+		}
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return offered[0], nil
+	}
+	best := ""
+	bestQuality := 0.0
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		for _, f := range offered {
+			if quality > bestQuality && mediaTypeMatches(mediaType, ContentTypes[f]) {
+				best, bestQuality = f, quality
+			}
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("none of the media types in %q are supported", accept)
+	}
+	return best, nil
+}
+
+// mediaTypeMatches returns true if a media range from an Accept header,
+// possibly containing wildcards, matches a media type.
+func mediaTypeMatches(mediaRange string, mediaType string) bool {
+	if mediaRange == "*/*" || mediaRange == mediaType {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	}
+	return false
+}
+
+// RespondWithNDJSONStream writes a newline-delimited JSON response with a status code to the
+// HTTP ResponseWriter, one line per item read from the stream. Like RespondWithJSONStream, errors
+// occurring after the status code has been written are returned to the caller.
+func RespondWithNDJSONStream(ctx context.Context, w http.ResponseWriter, code int, items ItemStream) error {
+	w.Header().Set("Content-Type", ContentTypes[FormatNDJSON])
+	w.WriteHeader(code)
+	buf := bufio.NewWriterSize(w, streamBufferSize)
+	encoder := json.NewEncoder(buf)
+	for items.Next(ctx) {
+		if err := encoder.Encode(items.Item()); err != nil {
+			return err
+		}
+	}
+	if err := items.Err(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// RespondWithCSVStream writes a CSV response with a status code to the HTTP ResponseWriter.
+// The first row contains the header and the following rows the items read from the stream,
+// which must be string slices. Like RespondWithJSONStream, errors occurring after the
+// status code has been written are returned to the caller.
+func RespondWithCSVStream(ctx context.Context, w http.ResponseWriter, code int, header []string, rows ItemStream) error {
+	w.Header().Set("Content-Type", ContentTypes[FormatCSV]+"; charset=utf-8")
+	w.WriteHeader(code)
+	buf := bufio.NewWriterSize(w, streamBufferSize)
+	writer := csv.NewWriter(buf)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for rows.Next(ctx) {
+		row, ok := rows.Item().([]string)
+		if !ok {
+			return fmt.Errorf("CSV row has unexpected type %T", rows.Item())
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
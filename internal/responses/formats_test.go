@@ -0,0 +1,81 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package responses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that the response format is picked from the format parameter or the Accept header.
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		accept   string
+		expected string
+		err      bool
+	}{
+		{name: "Default", url: "/events", expected: FormatJSON},
+		{name: "FormatParameter", url: "/events?format=csv", accept: "application/json", expected: FormatCSV},
+		{name: "UnknownFormatParameter", url: "/events?format=xml", err: true},
+		{name: "Accept", url: "/events", accept: "application/x-ndjson", expected: FormatNDJSON},
+		{name: "AcceptQuality", url: "/events", accept: "application/json;q=0.5, text/csv", expected: FormatCSV},
+		{name: "AcceptWildcard", url: "/events", accept: "text/html, */*;q=0.8", expected: FormatJSON},
+		{name: "AcceptSubtypeWildcard", url: "/events", accept: "text/*", expected: FormatCSV},
+		{name: "NotAcceptable", url: "/events", accept: "text/html", err: true},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, testCase.url, nil)
+			if testCase.accept != "" {
+				request.Header.Set("Accept", testCase.accept)
+			}
+			format, err := NegotiateFormat(request, FormatJSON, FormatNDJSON, FormatCSV)
+			if testCase.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, testCase.expected, format)
+			}
+		})
+	}
+}
+
+// Test that RespondWithNDJSONStream writes one JSON document per line.
+func TestRespondWithNDJSONStream(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+	items := &sliceItems{items: []interface{}{map[string]int{"a": 1}, "b"}, current: -1}
+	assert.NoError(t, RespondWithNDJSONStream(context.Background(), responseRecorder, 200, items))
+	assert.Equal(t, "application/x-ndjson", responseRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"a\":1}\n\"b\"\n", responseRecorder.Body.String())
+}
+
+// Test that RespondWithCSVStream writes a header followed by the rows.
+func TestRespondWithCSVStream(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+	items := &sliceItems{items: []interface{}{[]string{"1", "a,b"}}, current: -1}
+	assert.NoError(t, RespondWithCSVStream(context.Background(), responseRecorder, 200, []string{"x", "y"}, items))
+	assert.Equal(t, "text/csv; charset=utf-8", responseRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "x,y\n1,\"a,b\"\n", responseRecorder.Body.String())
+
+	items = &sliceItems{items: []interface{}{"not a row"}, current: -1}
+	assert.Error(t, RespondWithCSVStream(context.Background(), httptest.NewRecorder(), 200, []string{"x"}, items))
+}
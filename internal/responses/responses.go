@@ -16,10 +16,29 @@
 package responses
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 )
 
+// streamBufferSize is the size of the buffer used when streaming responses.
+const streamBufferSize = 32 * 1024
+
+// ItemStream is a sequence of items that are encoded into a response
+// one by one as they're read.
+type ItemStream interface {
+	// Next advances the stream to the next item and returns false
+	// when there are no more items or an error has occurred.
+	Next(context.Context) bool
+	// Item returns the current item.
+	Item() interface{}
+	// Err returns the error, if any, that made Next return false.
+	Err() error
+}
+
 // RespondWithJSON writes a JSON response with a status code to the HTTP ResponseWriter.
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, _ := json.Marshal(payload) //nolint:errchkjson
@@ -29,9 +48,63 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	_, _ = w.Write(response)
 }
 
+// RespondWithText writes a text response of a media type with a status code to the HTTP ResponseWriter.
+func RespondWithText(w http.ResponseWriter, code int, mediaType string, text string) {
+	w.Header().Set("Content-Type", mediaType+"; charset=utf-8")
+	w.WriteHeader(code)
+	_, _ = w.Write([]byte(text))
+}
+
 // RespondWithError writes a response with an error message and status code to the HTTP ResponseWriter.
 func RespondWithError(w http.ResponseWriter, code int, message string) {
 	w.WriteHeader(code)
 	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 	_, _ = w.Write([]byte(message))
 }
+
+// RespondWithJSONStream writes a JSON object response with a status code to the HTTP ResponseWriter.
+// The object contains the fields of envelope, which must encode to a JSON object, plus an array
+// named itemsKey with the items read from the stream. The items are encoded one by one so the
+// full response never has to be held in memory. Errors occurring after the status code has been
+// written can't be reported to the client, so they are returned to the caller instead.
+func RespondWithJSONStream(ctx context.Context, w http.ResponseWriter, code int, envelope interface{}, itemsKey string, items ItemStream) error {
+	head, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+	head = bytes.TrimSpace(head)
+	if len(head) < 2 || head[0] != '{' || head[len(head)-1] != '}' {
+		return errors.New("response envelope is not a JSON object")
+	}
+	key, err := json.Marshal(itemsKey)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	buf := bufio.NewWriterSize(w, streamBufferSize)
+	_, _ = buf.Write(head[:len(head)-1])
+	if len(head) > 2 {
+		_ = buf.WriteByte(',')
+	}
+	_, _ = buf.Write(key)
+	_, _ = buf.WriteString(":[")
+	for first := true; items.Next(ctx); first = false {
+		item, err := json.Marshal(items.Item())
+		if err != nil {
+			return err
+		}
+		if !first {
+			_ = buf.WriteByte(',')
+		}
+		if _, err := buf.Write(item); err != nil {
+			return err
+		}
+	}
+	if err := items.Err(); err != nil {
+		return err
+	}
+	_, _ = buf.WriteString("]}")
+	return buf.Flush()
+}
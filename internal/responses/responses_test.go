@@ -16,12 +16,34 @@
 package responses
 
 import (
+	"context"
+	"errors"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// sliceItems is an ItemStream over a slice, optionally failing at the end.
+type sliceItems struct {
+	items   []interface{}
+	current int
+	err     error
+}
+
+func (s *sliceItems) Next(_ context.Context) bool {
+	s.current++
+	return s.current < len(s.items)
+}
+
+func (s *sliceItems) Item() interface{} {
+	return s.items[s.current]
+}
+
+func (s *sliceItems) Err() error {
+	return s.err
+}
+
 // Test that RespondWithJSON writes the correct HTTP code, message and adds a content type header.
 func TestRespondWithJSON(t *testing.T) {
 	responseRecorder := httptest.NewRecorder()
@@ -38,3 +60,49 @@ func TestRespondWithError(t *testing.T) {
 	assert.Equal(t, 400, responseRecorder.Result().StatusCode) //nolint:bodyclose
 	assert.Equal(t, "Bad Request", responseRecorder.Body.String())
 }
+
+// Test that RespondWithJSONStream merges the envelope and the streamed items into one JSON object.
+func TestRespondWithJSONStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope interface{}
+		items    []interface{}
+		expected string
+	}{
+		{name: "Envelope", envelope: map[string]int{"pageNo": 1}, items: []interface{}{"a", map[string]string{"b": "c"}}, expected: `{"pageNo": 1, "items": ["a", {"b": "c"}]}`},
+		{name: "EmptyEnvelope", envelope: struct{}{}, items: []interface{}{1, 2}, expected: `{"items": [1, 2]}`},
+		{name: "NoItems", envelope: map[string]int{"pageNo": 1}, items: nil, expected: `{"pageNo": 1, "items": []}`},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			err := RespondWithJSONStream(context.Background(), responseRecorder, 200, testCase.envelope, "items", &sliceItems{items: testCase.items, current: -1})
+			assert.NoError(t, err)
+			assert.Equal(t, "application/json", responseRecorder.Header().Get("Content-Type"))
+			assert.JSONEq(t, testCase.expected, responseRecorder.Body.String())
+		})
+	}
+}
+
+// Test that RespondWithJSONStream returns errors from the stream and rejects non-object envelopes.
+func TestRespondWithJSONStreamErrors(t *testing.T) {
+	streamErr := errors.New("stream broke")
+	err := RespondWithJSONStream(context.Background(), httptest.NewRecorder(), 200, struct{}{}, "items", &sliceItems{current: -1, err: streamErr})
+	assert.ErrorIs(t, err, streamErr)
+
+	err = RespondWithJSONStream(context.Background(), httptest.NewRecorder(), 200, []int{1}, "items", &sliceItems{current: -1})
+	assert.Error(t, err)
+}
+
+// Test that server-sent events are written in the event stream format.
+func TestServerSentEvents(t *testing.T) {
+	responseRecorder := httptest.NewRecorder()
+	events, err := StartServerSentEvents(responseRecorder)
+	assert.NoError(t, err)
+	assert.NoError(t, events.Send("id1", map[string]string{"a": "b"}))
+	assert.NoError(t, events.KeepAlive())
+	assert.Error(t, events.Send("id\n2", nil))
+	assert.Equal(t, "text/event-stream", responseRecorder.Header().Get("Content-Type"))
+	assert.Equal(t, "id: id1\ndata: {\"a\":\"b\"}\n\n: keepalive\n\n", responseRecorder.Body.String())
+	assert.True(t, responseRecorder.Flushed)
+}
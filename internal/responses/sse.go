@@ -0,0 +1,71 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package responses
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ServerSentEvents writes a stream of server-sent events to an HTTP ResponseWriter.
+type ServerSentEvents struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// StartServerSentEvents writes the response header for a stream of server-sent events
+// to the HTTP ResponseWriter, which must support flushing.
+func StartServerSentEvents(w http.ResponseWriter) (*ServerSentEvents, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("response writer doesn't support flushing")
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep reverse proxies like nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	return &ServerSentEvents{w: w, flusher: flusher}, nil
+}
+
+// Send writes an event with an ID and a JSON encoded payload and flushes it to the client.
+func (s *ServerSentEvents) Send(id string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(id, "\r\n") {
+		return fmt.Errorf("invalid server-sent event ID %q", id)
+	}
+	if _, err := fmt.Fprintf(s.w, "id: %s\ndata: %s\n\n", id, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// KeepAlive writes a comment to keep the connection from timing out while there are no events.
+func (s *ServerSentEvents) KeepAlive() error {
+	if _, err := fmt.Fprint(s.w, ": keepalive\n\n"); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// retention removes the events that are older than their types are kept
+// for according to a retention policy, archiving them before they're deleted.
+package retention
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+)
+
+const (
+	// DefaultType is the type of the rule for the events of all types
+	// without rules of their own.
+	DefaultType = "*"
+	// forever is how long events are kept by rules without a maximum age.
+	forever = "forever"
+	day     = 24 * time.Hour
+)
+
+// Rule keeps the events of a type for a time.
+type Rule struct {
+	Type string
+	// MaxAge is how long events are kept, with zero meaning forever.
+	MaxAge time.Duration
+}
+
+// Policy is a set of rules with at most one rule per type and the rule for
+// DefaultType, if any, last. Events of types without rules are kept forever.
+type Policy []Rule
+
+// ParsePolicy parses comma separated rules like EiffelTestCaseStartedEvent=90d,
+// where the age is a number of days, a duration like 36h or forever.
+func ParsePolicy(spec string) (Policy, error) {
+	var policy Policy
+	seen := map[string]struct{}{}
+	for _, entry := range strings.Split(spec, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid retention rule %q, expected type=age", entry)
+		}
+		eventType := strings.TrimSpace(parts[0])
+		if _, ok := seen[eventType]; ok {
+			return nil, fmt.Errorf("more than one retention rule for %s", eventType)
+		}
+		seen[eventType] = struct{}{}
+		maxAge, err := parseAge(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention rule %q: %w", entry, err)
+		}
+		policy = append(policy, Rule{Type: eventType, MaxAge: maxAge})
+	}
+	sort.Slice(policy, func(i, j int) bool {
+		if policy[i].Type == DefaultType || policy[j].Type == DefaultType {
+			return policy[j].Type == DefaultType && policy[i].Type != DefaultType
+		}
+		return policy[i].Type < policy[j].Type
+	})
+	return policy, nil
+}
+
+// parseAge parses how long a rule keeps events.
+func parseAge(value string) (time.Duration, error) {
+	if value == forever {
+		return 0, nil
+	}
+	var age time.Duration
+	if days := strings.TrimSuffix(value, "d"); days != value {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", days)
+		}
+		age = time.Duration(n) * day
+	} else {
+		var err error
+		if age, err = time.ParseDuration(value); err != nil {
+			return 0, err
+		}
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("the age %q isn't positive", value)
+	}
+	return age, nil
+}
+
+// formatAge formats how long a rule keeps events like parseAge parses it.
+func formatAge(age time.Duration) string {
+	switch {
+	case age == 0:
+		return forever
+	case age%day == 0:
+		return strconv.FormatInt(int64(age/day), 10) + "d"
+	default:
+		return age.String()
+	}
+}
+
+// conditions returns the conditions selecting the events that the rule
+// applies to with meta.time before the time in milliseconds since the epoch.
+func (p Policy) conditions(rule Rule, before int64) []query.Condition {
+	var conditions []query.Condition
+	if rule.Type == DefaultType {
+		for _, other := range p {
+			if other.Type != DefaultType {
+				conditions = append(conditions, query.Condition{Field: "meta.type", Op: "!=", Value: other.Type})
+			}
+		}
+	} else {
+		conditions = append(conditions, query.Condition{Field: "meta.type", Op: "=", Value: rule.Type})
+	}
+	return append(conditions, query.Condition{
+		Field:    "meta.time",
+		Op:       "<",
+		Value:    strconv.FormatInt(before, 10),
+		TypeConv: "int",
+	})
+}
@@ -0,0 +1,211 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package retention
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/archive"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// RuleReport is what a rule removes.
+type RuleReport struct {
+	Type   string `json:"type"`
+	MaxAge string `json:"maxAge"`
+	// Before is the time, in milliseconds since the epoch, before which
+	// events expire. It's left out for rules keeping events forever.
+	Before int64 `json:"before,omitempty"`
+	// Expired is the number of expired events by type.
+	Expired map[string]int `json:"expired"`
+}
+
+// Report is the result of applying, or checking, a retention policy.
+type Report struct {
+	DryRun  bool         `json:"dryRun"`
+	Rules   []RuleReport `json:"rules"`
+	Expired int          `json:"expired"`
+	// Archive is the file that the expired events were archived in.
+	Archive string `json:"archive,omitempty"`
+	// Deleted is the number of stored copies of the expired events deleted.
+	Deleted int `json:"deleted"`
+}
+
+// Check reports the events that the policy would remove at the time without
+// removing them.
+func Check(ctx context.Context, db drivers.Database, policy Policy, now time.Time) (Report, error) {
+	report := Report{DryRun: true}
+	err := forEachExpired(ctx, db, policy, now, &report, func(int, drivers.EiffelEvent) error {
+		return nil
+	})
+	return report, err
+}
+
+// Apply archives the events that have expired at the time in a gzipped NDJSON
+// file in the directory and then deletes them from the database, which must
+// implement drivers.Writer. Nothing is deleted unless the archive is complete
+// and only the copies of the archived events that match the rule they were
+// archived by are deleted.
+func Apply(ctx context.Context, db drivers.Database, policy Policy, now time.Time, dir string) (Report, error) {
+	report := Report{}
+	writer, ok := db.(drivers.Writer)
+	if !ok {
+		return report, drivers.ErrReadOnly
+	}
+
+	var file *os.File
+	var events *archive.Writer
+	// The IDs of the archived events by rule, since an ID may have copies
+	// expired by different rules.
+	ids := make([][]string, len(policy))
+	seen := make([]map[string]struct{}, len(policy))
+	err := forEachExpired(ctx, db, policy, now, &report, func(rule int, event drivers.EiffelEvent) error {
+		if file == nil {
+			report.Archive = filepath.Join(dir, "events-"+now.UTC().Format("20060102T150405Z")+".ndjson.gz")
+			var err error
+			file, err = os.OpenFile(filepath.Clean(report.Archive), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+			if err != nil {
+				return err
+			}
+			events = archive.NewWriter(file, true)
+		}
+		if seen[rule] == nil {
+			seen[rule] = map[string]struct{}{}
+		}
+		if _, ok := seen[rule][event.ID()]; !ok {
+			seen[rule][event.ID()] = struct{}{}
+			ids[rule] = append(ids[rule], event.ID())
+		}
+		return events.Write(event)
+	})
+	if file != nil {
+		err = closeArchive(file, events, err)
+	}
+	if err != nil {
+		return report, err
+	}
+
+	for i, rule := range policy {
+		if len(ids[i]) == 0 {
+			continue
+		}
+		deleted, err := writer.DeleteEvents(ctx, ids[i], policy.conditions(rule, report.Rules[i].Before))
+		report.Deleted += deleted
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// closeArchive flushes and closes an archive file, returning err if it's
+// set or else the first error closing it.
+func closeArchive(file *os.File, events *archive.Writer, err error) error {
+	errs := []error{err}
+	if events != nil {
+		errs = append(errs, events.Close())
+	}
+	errs = append(errs, file.Sync(), file.Close())
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// forEachExpired calls fn with the index of the rule and every event that
+// has expired by it at the time, counting them in the report.
+func forEachExpired(ctx context.Context, db drivers.Database, policy Policy, now time.Time, report *Report, fn func(int, drivers.EiffelEvent) error) error {
+	report.Rules = make([]RuleReport, 0, len(policy))
+	for i, rule := range policy {
+		ruleReport := RuleReport{Type: rule.Type, MaxAge: formatAge(rule.MaxAge), Expired: map[string]int{}}
+		if rule.MaxAge == 0 {
+			report.Rules = append(report.Rules, ruleReport)
+			continue
+		}
+		ruleReport.Before = now.Add(-rule.MaxAge).UnixNano() / int64(time.Millisecond)
+		stream, _, err := db.GetEvents(ctx, requests.MultipleEventsRequest{
+			Unpaged:    true,
+			Count:      requests.CountNone,
+			Conditions: policy.conditions(rule, ruleReport.Before),
+		})
+		if err != nil {
+			return err
+		}
+		for stream.Next(ctx) {
+			event := stream.Event()
+			ruleReport.Expired[event.Type()]++
+			report.Expired++
+			if err = fn(i, event); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = stream.Err()
+		}
+		if closeErr := stream.Close(ctx); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		report.Rules = append(report.Rules, ruleReport)
+	}
+	return nil
+}
+
+// Job applies a retention policy periodically.
+type Job struct {
+	Database drivers.Database
+	Policy   Policy
+	Dir      string
+	Interval time.Duration
+	Logger   *log.Entry
+}
+
+// Run applies the policy right away and then at every interval until the
+// context is done.
+func (j *Job) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for {
+		j.apply(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply applies the policy once and logs the result.
+func (j *Job) apply(ctx context.Context) {
+	report, err := Apply(ctx, j.Database, j.Policy, time.Now(), j.Dir)
+	switch {
+	case err != nil:
+		j.Logger.Errorf("Error applying the retention policy: %s", err)
+	case report.Archive != "":
+		j.Logger.Infof("Archived %d expired events in %s and deleted %d stored copies",
+			report.Expired, report.Archive, report.Deleted)
+	}
+}
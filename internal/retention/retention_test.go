@@ -0,0 +1,200 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package retention
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/archive"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+var now = time.Date(2021, 8, 20, 12, 0, 0, 0, time.UTC)
+
+// daysAgo returns the time in milliseconds the number of days before now.
+func daysAgo(days int) int64 {
+	return now.Add(-time.Duration(days)*day).UnixNano() / int64(time.Millisecond)
+}
+
+// events returns a database with test case and artifact events of different ages.
+func events() *test.MemoryDatabase {
+	return test.NewMemoryDatabase(
+		test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.TestCaseStarted, daysAgo(100), nil),
+		test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", drivers.TestCaseStarted, daysAgo(10), nil),
+		test.NewEvent("9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", drivers.ArtifactCreated, daysAgo(1000), nil),
+		test.NewEvent("71000000-0000-4000-8000-000000000001", drivers.ActivityTriggered, daysAgo(400), nil),
+		test.NewEvent("71000000-0000-4000-8000-000000000002", drivers.ActivityTriggered, daysAgo(200), nil),
+	)
+}
+
+// Test that policies are parsed with the default rule last.
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected Policy
+	}{
+		{
+			name: "Rules",
+			spec: "*=365d, EiffelTestCaseStartedEvent=90d,EiffelArtifactCreatedEvent=forever,EiffelActivityTriggeredEvent=36h",
+			expected: Policy{
+				{Type: "EiffelActivityTriggeredEvent", MaxAge: 36 * time.Hour},
+				{Type: "EiffelArtifactCreatedEvent", MaxAge: 0},
+				{Type: "EiffelTestCaseStartedEvent", MaxAge: 90 * day},
+				{Type: "*", MaxAge: 365 * day},
+			},
+		},
+		{name: "Empty", spec: ""},
+		{name: "Duplicate", spec: "EiffelTestCaseStartedEvent=90d,EiffelTestCaseStartedEvent=30d"},
+		{name: "NoAge", spec: "EiffelTestCaseStartedEvent"},
+		{name: "NoType", spec: "=90d"},
+		{name: "InvalidDays", spec: "EiffelTestCaseStartedEvent=ninetyd"},
+		{name: "NegativeAge", spec: "EiffelTestCaseStartedEvent=-1h"},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			policy, err := ParsePolicy(testCase.spec)
+			if testCase.expected == nil && testCase.spec != "" {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expected, policy)
+		})
+	}
+}
+
+// Test that ages are formatted like they're parsed.
+func TestFormatAge(t *testing.T) {
+	assert.Equal(t, "forever", formatAge(0))
+	assert.Equal(t, "90d", formatAge(90*day))
+	assert.Equal(t, "36h0m0s", formatAge(36*time.Hour))
+}
+
+// Test that a dry run reports the expired events by rule and type without deleting them.
+func TestCheck(t *testing.T) {
+	db := events()
+	policy, err := ParsePolicy("EiffelTestCaseStartedEvent=90d,EiffelArtifactCreatedEvent=forever,*=365d")
+	require.NoError(t, err)
+	report, err := Check(context.Background(), db, policy, now)
+	require.NoError(t, err)
+	assert.Equal(t, Report{
+		DryRun: true,
+		Rules: []RuleReport{
+			{Type: drivers.ArtifactCreated, MaxAge: "forever", Expired: map[string]int{}},
+			{Type: drivers.TestCaseStarted, MaxAge: "90d", Before: daysAgo(90), Expired: map[string]int{drivers.TestCaseStarted: 1}},
+			{Type: "*", MaxAge: "365d", Before: daysAgo(365), Expired: map[string]int{drivers.ActivityTriggered: 1}},
+		},
+		Expired: 2,
+	}, report)
+	assert.Len(t, db.Events, 5)
+}
+
+// Test that expired events are archived and then deleted.
+func TestApply(t *testing.T) {
+	db := events()
+	dir := t.TempDir()
+	policy, err := ParsePolicy("EiffelTestCaseStartedEvent=90d,*=365d")
+	require.NoError(t, err)
+	report, err := Apply(context.Background(), db, policy, now, dir)
+	require.NoError(t, err)
+	assert.False(t, report.DryRun)
+	assert.Equal(t, 3, report.Expired)
+	assert.Equal(t, 3, report.Deleted)
+	assert.Equal(t, filepath.Join(dir, "events-20210820T120000Z.ndjson.gz"), report.Archive)
+	require.Len(t, db.Events, 2)
+
+	// The archive can be imported.
+	file, err := os.Open(report.Archive)
+	require.NoError(t, err)
+	defer file.Close()
+	restored := test.NewMemoryDatabase()
+	ingester, err := ingest.New(restored, ingest.PolicyReject, &log.Entry{Logger: log.New()})
+	require.NoError(t, err)
+	imported, err := archive.Import(context.Background(), ingester, file)
+	require.NoError(t, err)
+	assert.Equal(t, 3, imported.Events)
+	assert.True(t, imported.OK())
+
+	// Nothing is archived when nothing has expired.
+	report, err = Apply(context.Background(), db, policy, now, dir)
+	require.NoError(t, err)
+	assert.Empty(t, report.Archive)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+// Test that only the copies of expired events that the rules matched are deleted.
+func TestApplyOnlyDeletesExpiredCopies(t *testing.T) {
+	db := events()
+	expired := db.Events[0]
+	recent := test.NewEvent(expired.ID(), drivers.TestCaseStarted, daysAgo(1), map[string]interface{}{"retried": true})
+	otherType := test.NewEvent(expired.ID(), drivers.ActivityTriggered, daysAgo(400), nil)
+	db.Events = append(db.Events, recent, otherType)
+	policy, err := ParsePolicy("EiffelTestCaseStartedEvent=90d,EiffelActivityTriggeredEvent=365d")
+	require.NoError(t, err)
+	report, err := Apply(context.Background(), db, policy, now, t.TempDir())
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Expired)
+	assert.Equal(t, 3, report.Deleted)
+	assert.Contains(t, db.Events, recent)
+	assert.NotContains(t, db.Events, expired)
+	assert.NotContains(t, db.Events, otherType)
+}
+
+// Test that nothing is deleted if the events can't be archived or deleted.
+func TestApplyErrors(t *testing.T) {
+	policy, err := ParsePolicy("*=90d")
+	require.NoError(t, err)
+	db := events()
+	_, err = Apply(context.Background(), db, policy, now, filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+	assert.Len(t, db.Events, 5)
+
+	_, err = Apply(context.Background(), mock_drivers.NewMockDatabase(gomock.NewController(t)), policy, now, t.TempDir())
+	assert.ErrorIs(t, err, drivers.ErrReadOnly)
+}
+
+// Test that the job applies the policy before waiting for the interval.
+func TestJob(t *testing.T) {
+	db := events()
+	dir := t.TempDir()
+	policy, err := ParsePolicy("*=90d")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	job := &Job{Database: db, Policy: policy, Dir: dir, Interval: time.Hour, Logger: &log.Entry{Logger: log.New()}}
+	job.Run(ctx)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.True(t, strings.HasSuffix(entries[0].Name(), ".ndjson.gz"))
+	assert.Empty(t, db.Events)
+}
@@ -0,0 +1,47 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package signature
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test canonicalization with the example from RFC 8785.
+func TestCanonicalize(t *testing.T) {
+	var value interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`), &value))
+	canonical, err := Canonicalize(value)
+	require.NoError(t, err)
+	assert.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, string(canonical))
+}
+
+// Test that keys are sorted by UTF-16 code units and that integers of any type are numbers.
+func TestCanonicalizeKeysAndIntegers(t *testing.T) {
+	canonical, err := Canonicalize(map[string]interface{}{"\U0001F600": int64(1), "\uFB33": int32(2), "a": uint8(3)})
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":3,\"\U0001F600\":1,\"\uFB33\":2}", string(canonical))
+
+	_, err = Canonicalize(map[int]interface{}{1: "one"})
+	assert.Error(t, err)
+}
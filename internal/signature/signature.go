@@ -0,0 +1,237 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// signature verifies the signatures of Eiffel events, set in
+// meta.security.integrityProtection, against configured public keys.
+//
+// An event is signed by serializing it with an empty signature according to
+// the JSON Canonicalization Scheme and signing the result with one of the JSON
+// Web Algorithms in meta.security.integrityProtection.alg. Public keys that
+// events carry in meta.security.integrityProtection.publicKey aren't trusted
+// since anyone that can change the event can change its key too.
+package signature
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	// Register the hash functions used by the algorithms.
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+)
+
+// Status is the outcome of verifying the signature of an event.
+type Status string
+
+const (
+	// StatusVerified means that the signature was made with one of the keys.
+	StatusVerified Status = "verified"
+	// StatusUnsigned means that the event has no signature.
+	StatusUnsigned Status = "unsigned"
+	// StatusInvalid means that the signature couldn't be verified, either
+	// since the event was changed after it was signed or since it was
+	// signed with a key or algorithm that isn't known.
+	StatusInvalid Status = "invalid"
+)
+
+// Verification is the result of verifying the signature of an event.
+type Verification struct {
+	ID             string `json:"id"`
+	Status         Status `json:"status"`
+	AuthorIdentity string `json:"authorIdentity,omitempty"`
+	Algorithm      string `json:"alg,omitempty"`
+	// Key is the ID of the key that the signature was made with.
+	Key string `json:"key,omitempty"`
+	// Reason explains why the signature couldn't be verified.
+	Reason string `json:"reason,omitempty"`
+}
+
+// algorithm is a JSON Web Algorithm (RFC 7518) for signing.
+type algorithm struct {
+	hash   crypto.Hash
+	verify func(key crypto.PublicKey, hash crypto.Hash, digest []byte, message []byte, signature []byte) bool
+}
+
+// algorithms are the supported algorithms by name. The HMAC algorithms
+// aren't supported since they need the secret key to verify signatures.
+var algorithms = map[string]algorithm{
+	"RS256": {crypto.SHA256, verifyPKCS1v15},
+	"RS384": {crypto.SHA384, verifyPKCS1v15},
+	"RS512": {crypto.SHA512, verifyPKCS1v15},
+	"PS256": {crypto.SHA256, verifyPSS},
+	"PS384": {crypto.SHA384, verifyPSS},
+	"PS512": {crypto.SHA512, verifyPSS},
+	"ES256": {crypto.SHA256, verifyECDSA},
+	"ES384": {crypto.SHA384, verifyECDSA},
+	"ES512": {crypto.SHA512, verifyECDSA},
+	"EdDSA": {0, verifyEd25519},
+}
+
+func verifyPKCS1v15(key crypto.PublicKey, hash crypto.Hash, digest []byte, _ []byte, signature []byte) bool {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	return ok && rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature) == nil
+}
+
+func verifyPSS(key crypto.PublicKey, hash crypto.Hash, digest []byte, _ []byte, signature []byte) bool {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	return ok && rsa.VerifyPSS(rsaKey, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+}
+
+// verifyECDSA accepts both the JWS encoding of signatures, the big-endian
+// r and s concatenated, and the ASN.1 encoding used by most other tools.
+func verifyECDSA(key crypto.PublicKey, _ crypto.Hash, digest []byte, _ []byte, signature []byte) bool {
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+	size := (ecKey.Curve.Params().BitSize + 7) / 8
+	if len(signature) == 2*size {
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if ecdsa.Verify(ecKey, digest, r, s) {
+			return true
+		}
+	}
+	return ecdsa.VerifyASN1(ecKey, digest, signature)
+}
+
+func verifyEd25519(key crypto.PublicKey, _ crypto.Hash, _ []byte, message []byte, signature []byte) bool {
+	edKey, ok := key.(ed25519.PublicKey)
+	return ok && ed25519.Verify(edKey, message, signature)
+}
+
+// Verifier verifies the signatures of events against a set of keys. A nil
+// Verifier has no keys.
+type Verifier struct {
+	Keys []Key
+}
+
+// Load creates a verifier with the keys in the PEM files and the JWKS file,
+// if any.
+func Load(pemFiles []string, jwksFile string) (*Verifier, error) {
+	verifier := &Verifier{}
+	for _, path := range pemFiles {
+		keys, err := LoadPEMFile(path)
+		if err != nil {
+			return nil, err
+		}
+		verifier.Keys = append(verifier.Keys, keys...)
+	}
+	if jwksFile != "" {
+		keys, err := LoadJWKSFile(jwksFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier.Keys = append(verifier.Keys, keys...)
+	}
+	return verifier, nil
+}
+
+// Message returns what an event is signed over: the event with an empty
+// signature, canonicalized.
+func Message(event drivers.EiffelEvent) ([]byte, error) {
+	// Copy the event through JSON, which also turns numbers into float64
+	// like the canonicalization does.
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var unsigned map[string]interface{}
+	if err := json.Unmarshal(data, &unsigned); err != nil {
+		return nil, err
+	}
+	meta, _ := unsigned["meta"].(map[string]interface{})
+	security, _ := meta["security"].(map[string]interface{})
+	if integrityProtection, ok := security["integrityProtection"].(map[string]interface{}); ok {
+		integrityProtection["signature"] = ""
+	}
+	return Canonicalize(unsigned)
+}
+
+// Verify the signature of an event.
+func (v *Verifier) Verify(event drivers.EiffelEvent) Verification {
+	verification := Verification{
+		ID:             event.ID(),
+		AuthorIdentity: event.StringField("meta.security.authorIdentity"),
+		Algorithm:      event.StringField("meta.security.integrityProtection.alg"),
+	}
+	encoded := event.StringField("meta.security.integrityProtection.signature")
+	if encoded == "" {
+		verification.Status = StatusUnsigned
+		return verification
+	}
+	verification.Status = StatusInvalid
+	alg, ok := algorithms[verification.Algorithm]
+	if !ok {
+		verification.Reason = fmt.Sprintf("unsupported algorithm %q", verification.Algorithm)
+		return verification
+	}
+	if v == nil || len(v.Keys) == 0 {
+		verification.Reason = "no keys are configured"
+		return verification
+	}
+	signature, err := decodeSignature(encoded)
+	if err != nil {
+		verification.Reason = fmt.Sprintf("malformed signature: %s", err)
+		return verification
+	}
+	message, err := Message(event)
+	if err != nil {
+		verification.Reason = err.Error()
+		return verification
+	}
+	var digest []byte
+	if alg.hash != 0 {
+		hash := alg.hash.New()
+		hash.Write(message)
+		digest = hash.Sum(nil)
+	}
+	for _, key := range v.Keys {
+		if alg.verify(key.PublicKey, alg.hash, digest, message, signature) {
+			verification.Status = StatusVerified
+			verification.Key = key.ID
+			return verification
+		}
+	}
+	verification.Reason = "the signature wasn't made with any of the configured keys over the event's content"
+	return verification
+}
+
+// Verified returns true if the signature of the event could be verified.
+func (v *Verifier) Verified(event drivers.EiffelEvent) bool {
+	return v.Verify(event).Status == StatusVerified
+}
+
+// decodeSignature decodes a signature in standard or URL-safe base64,
+// with or without padding.
+func decodeSignature(encoded string) ([]byte, error) {
+	var err error
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		var signature []byte
+		if signature, err = encoding.DecodeString(encoded); err == nil {
+			return signature, nil
+		}
+	}
+	return nil, err
+}
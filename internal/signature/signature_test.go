@@ -0,0 +1,169 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package signature_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/signature"
+	"github.com/eiffel-community/eiffel-goer/test"
+)
+
+// signedEvent returns an event signed with the algorithm by the sign function.
+func signedEvent(t *testing.T, alg string, sign func(message []byte) []byte) drivers.EiffelEvent {
+	event := test.NewEvent("80000000-0000-4000-8000-000000000001", "EiffelArtifactCreatedEvent", 1000,
+		map[string]interface{}{"identity": "pkg:generic/goer@1.0.0"})
+	event["meta"].(map[string]interface{})["security"] = map[string]interface{}{
+		"authorIdentity":      "CN=builder",
+		"integrityProtection": map[string]interface{}{"alg": alg, "signature": ""},
+	}
+	message, err := signature.Message(event)
+	require.NoError(t, err)
+	event["meta"].(map[string]interface{})["security"].(map[string]interface{})["integrityProtection"].(map[string]interface{})["signature"] = base64.StdEncoding.EncodeToString(sign(message))
+	return event
+}
+
+func sha256Sum(message []byte) []byte {
+	sum := sha256.Sum256(message)
+	return sum[:]
+}
+
+// Test that signatures made with the supported key types are verified and
+// that changed events aren't.
+func TestVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	verifier := &signature.Verifier{Keys: []signature.Key{
+		{ID: "rsa", PublicKey: &rsaKey.PublicKey},
+		{ID: "ec", PublicKey: &ecKey.PublicKey},
+		{ID: "ed", PublicKey: edPublic},
+	}}
+
+	tests := []struct {
+		name  string
+		event drivers.EiffelEvent
+		key   string
+	}{
+		{name: "RS256", key: "rsa", event: signedEvent(t, "RS256", func(message []byte) []byte {
+			s, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sha256Sum(message))
+			require.NoError(t, err)
+			return s
+		})},
+		{name: "PS256", key: "rsa", event: signedEvent(t, "PS256", func(message []byte) []byte {
+			s, err := rsa.SignPSS(rand.Reader, rsaKey, crypto.SHA256, sha256Sum(message), nil)
+			require.NoError(t, err)
+			return s
+		})},
+		{name: "ES256", key: "ec", event: signedEvent(t, "ES256", func(message []byte) []byte {
+			s, err := ecdsa.SignASN1(rand.Reader, ecKey, sha256Sum(message))
+			require.NoError(t, err)
+			return s
+		})},
+		{name: "EdDSA", key: "ed", event: signedEvent(t, "EdDSA", func(message []byte) []byte {
+			return ed25519.Sign(edPrivate, message)
+		})},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			verification := verifier.Verify(testCase.event)
+			assert.Equal(t, signature.StatusVerified, verification.Status, verification.Reason)
+			assert.Equal(t, testCase.key, verification.Key)
+			assert.Equal(t, "CN=builder", verification.AuthorIdentity)
+
+			// Events read from a database have other types than the signed ones.
+			data, err := json.Marshal(testCase.event)
+			require.NoError(t, err)
+			var decoded drivers.EiffelEvent
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			assert.True(t, verifier.Verified(decoded))
+
+			decoded["data"].(map[string]interface{})["identity"] = "pkg:generic/goer@6.6.6"
+			assert.Equal(t, signature.StatusInvalid, verifier.Verify(decoded).Status)
+		})
+	}
+}
+
+// Test the verification of events that can't be verified.
+func TestVerifyUnverifiable(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signed := signedEvent(t, "RS256", func(message []byte) []byte {
+		s, err := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, sha256Sum(message))
+		require.NoError(t, err)
+		return s
+	})
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	verifier := &signature.Verifier{Keys: []signature.Key{{ID: "other", PublicKey: &otherKey.PublicKey}}}
+
+	assert.Equal(t, signature.StatusInvalid, verifier.Verify(signed).Status)
+	assert.Equal(t, signature.StatusInvalid, (*signature.Verifier)(nil).Verify(signed).Status)
+	assert.Equal(t, signature.StatusInvalid, verifier.Verify(signedEvent(t, "HS256", func([]byte) []byte { return []byte("mac") })).Status)
+
+	unsigned := test.NewEvent("80000000-0000-4000-8000-000000000002", "EiffelArtifactCreatedEvent", 1000, nil)
+	assert.Equal(t, signature.StatusUnsigned, verifier.Verify(unsigned).Status)
+	assert.False(t, verifier.Verified(unsigned))
+}
+
+// Test that keys are loaded from PEM and JWKS files.
+func TestLoad(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	pemFile := filepath.Join(dir, "builder.pem")
+	require.NoError(t, os.WriteFile(pemFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	jwksFile := filepath.Join(dir, "keys.json")
+	require.NoError(t, os.WriteFile(jwksFile, []byte(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"tester","x":"`+
+		base64.RawURLEncoding.EncodeToString(edPublic)+`"}]}`), 0o600))
+
+	verifier, err := signature.Load([]string{pemFile}, jwksFile)
+	require.NoError(t, err)
+	assert.Equal(t, []signature.Key{
+		{ID: pemFile, PublicKey: &ecKey.PublicKey},
+		{ID: "tester", PublicKey: edPublic},
+	}, verifier.Keys)
+
+	_, err = signature.Load([]string{jwksFile}, "")
+	assert.Error(t, err)
+	_, err = signature.Load(nil, pemFile)
+	assert.Error(t, err)
+}
@@ -0,0 +1,284 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// DefaultBufferSize is how many events may be waiting for a subscriber of
+// a Hub before it's considered too slow and dropped.
+const DefaultBufferSize = 256
+
+// ErrSlowSubscriber ends subscriptions whose subscribers didn't keep up
+// with the new events.
+var ErrSlowSubscriber = errors.New("the subscriber fell behind the new events")
+
+// Hub follows the events stored in a database with a single stream, while
+// it has subscribers, and fans them out to the subscribers. Any number of
+// subscribers thereby cost a single change feed or poll of the database.
+type Hub struct {
+	Database drivers.Database
+	Logger   *log.Entry
+	// BufferSize is how many events may be waiting for a subscriber.
+	BufferSize int
+
+	mu   sync.Mutex
+	feed *feed
+}
+
+// feed is a stream of events shared by the subscribers of a hub.
+type feed struct {
+	cancel      context.CancelFunc
+	subscribers map[*Subscriber]struct{}
+}
+
+// Subscriber receives the new events from a Hub.
+type Subscriber struct {
+	hub    *Hub
+	feed   *feed
+	events chan drivers.EiffelEvent
+	err    error
+}
+
+// NewHub returns a hub for the database.
+func NewHub(db drivers.Database, logger *log.Entry) *Hub {
+	return &Hub{Database: db, Logger: logger, BufferSize: DefaultBufferSize}
+}
+
+// Subscribe returns a subscriber receiving the events stored from now on.
+// The stream of events is opened by the first subscriber.
+func (h *Hub) Subscribe() (*Subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.feed == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		events, err := Events(ctx, h.Database, nil, "", h.Logger)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		h.feed = &feed{cancel: cancel, subscribers: map[*Subscriber]struct{}{}}
+		go h.run(ctx, h.feed, events)
+	}
+	s := &Subscriber{hub: h, feed: h.feed, events: make(chan drivers.EiffelEvent, h.BufferSize)}
+	h.feed.subscribers[s] = struct{}{}
+	return s, nil
+}
+
+// run sends the events to the feed's subscribers until the stream ends,
+// dropping subscribers whose buffers are full rather than waiting for them.
+func (h *Hub) run(ctx context.Context, f *feed, events drivers.EventStream) {
+	for events.Next(ctx) {
+		event := events.Event()
+		h.mu.Lock()
+		for s := range f.subscribers {
+			select {
+			case s.events <- event:
+			default:
+				h.end(s, ErrSlowSubscriber)
+			}
+		}
+		h.mu.Unlock()
+	}
+	err := events.Err()
+	if closeErr := events.Close(context.Background()); closeErr != nil {
+		h.Logger.Errorf("Error closing event stream: %s", closeErr)
+	}
+	if ctx.Err() != nil {
+		// The last subscriber left.
+		return
+	}
+	if err == nil {
+		err = errors.New("the stream of events ended")
+	}
+	h.Logger.Errorf("Error following events: %s", err)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range f.subscribers {
+		h.end(s, fmt.Errorf("stopped following events: %w", err))
+	}
+	if h.feed == f {
+		h.feed = nil
+	}
+}
+
+// end ends a subscription with the error, closing the subscriber's channel.
+// The hub must be locked.
+func (h *Hub) end(s *Subscriber, err error) {
+	delete(s.feed.subscribers, s)
+	s.err = err
+	close(s.events)
+}
+
+// Events returns the channel of new events. It's closed when the
+// subscription ends, after which Err tells why.
+func (s *Subscriber) Events() <-chan drivers.EiffelEvent {
+	return s.events
+}
+
+// Err returns the error that ended the subscription, or nil if it's still
+// active or was closed by the subscriber. It's only safe to call after the
+// events channel has been closed.
+func (s *Subscriber) Err() error {
+	return s.err
+}
+
+// Close ends the subscription. The stream of events is closed along with
+// the last subscription.
+func (s *Subscriber) Close() {
+	h := s.hub
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := s.feed.subscribers[s]; ok {
+		h.end(s, nil)
+	}
+	if len(s.feed.subscribers) == 0 && h.feed == s.feed {
+		s.feed.cancel()
+		h.feed = nil
+	}
+}
+
+// Follow returns a stream of the events matching the conditions that are
+// stored from now on, like Events, but through a subscription to the hub.
+// If lastEventID is the ID of a stored event, the matching events stored
+// after it are replayed first. The stream ends with ErrSlowSubscriber if
+// it isn't read as fast as new events arrive.
+func (h *Hub) Follow(ctx context.Context, conditions []query.Condition, lastEventID string) (drivers.EventStream, error) {
+	// Subscribe before replaying so that no events are missed in between.
+	subscriber, err := h.Subscribe()
+	if err != nil {
+		return nil, err
+	}
+	s := &followStream{subscriber: subscriber, conditions: conditions, seen: map[string]struct{}{}}
+	if lastEventID != "" {
+		if err := s.replay(ctx, h.Database, lastEventID, h.Logger); err != nil {
+			subscriber.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// followStream is a drivers.EventStream of the events received by a
+// subscriber that match some conditions.
+type followStream struct {
+	subscriber *Subscriber
+	conditions []query.Condition
+	// pending are replayed events that haven't been returned yet.
+	pending []drivers.EiffelEvent
+	// seen has the IDs of the replayed events, which the subscriber may
+	// receive too.
+	seen map[string]struct{}
+
+	event drivers.EiffelEvent
+	err   error
+}
+
+// replay queues the matching events stored since the event with an ID, in
+// meta.time order. Events stored within the same millisecond as that event
+// may be replayed too.
+func (s *followStream) replay(ctx context.Context, db drivers.Database, lastEventID string, logger *log.Entry) error {
+	lastEvent, err := db.GetEventByID(ctx, lastEventID)
+	if err != nil {
+		logger.Warningf("Can't resume after %q, following new events only: %s", lastEventID, err)
+		return nil
+	}
+	conditions := append([]query.Condition{{
+		Field:    "meta.time",
+		Op:       ">=",
+		Value:    strconv.FormatInt(lastEvent.Time(), 10),
+		TypeConv: "int",
+	}}, s.conditions...)
+	events, _, err := db.GetEvents(ctx, requests.MultipleEventsRequest{
+		PageNo:     1,
+		Count:      requests.CountNone,
+		Unpaged:    true,
+		Conditions: conditions,
+	})
+	if err != nil {
+		return err
+	}
+	replayed, err := drivers.Collect(ctx, events)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(replayed, func(i, j int) bool {
+		return replayed[i].Time() < replayed[j].Time()
+	})
+	for _, event := range replayed {
+		if event.ID() == lastEventID {
+			continue
+		}
+		s.seen[event.ID()] = struct{}{}
+		s.pending = append(s.pending, event)
+	}
+	return nil
+}
+
+// Next waits for the next matching event.
+func (s *followStream) Next(ctx context.Context) bool {
+	s.event = nil
+	if len(s.pending) > 0 {
+		s.event = s.pending[0]
+		s.pending = s.pending[1:]
+		return true
+	}
+	for {
+		select {
+		case event, ok := <-s.subscriber.Events():
+			if !ok {
+				s.err = s.subscriber.Err()
+				return false
+			}
+			if _, seen := s.seen[event.ID()]; seen || !event.Matches(s.conditions) {
+				continue
+			}
+			s.event = event
+			return true
+		case <-ctx.Done():
+			s.err = ctx.Err()
+			return false
+		}
+	}
+}
+
+// Event returns the current event.
+func (s *followStream) Event() drivers.EiffelEvent {
+	return s.event
+}
+
+// Err returns the error that made Next return false.
+func (s *followStream) Err() error {
+	return s.err
+}
+
+// Close the subscription.
+func (s *followStream) Close(_ context.Context) error {
+	s.subscriber.Close()
+	return nil
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package watch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+// chanStream is a stream of the events sent on a channel, ending with err
+// when the channel is closed.
+type chanStream struct {
+	events chan drivers.EiffelEvent
+	event  drivers.EiffelEvent
+	err    error
+}
+
+func (s *chanStream) Next(ctx context.Context) bool {
+	select {
+	case event, ok := <-s.events:
+		s.event = event
+		return ok
+	case <-ctx.Done():
+		s.err = ctx.Err()
+		return false
+	}
+}
+
+func (s *chanStream) Event() drivers.EiffelEvent      { return s.event }
+func (s *chanStream) Err() error                      { return s.err }
+func (s *chanStream) Close(ctx context.Context) error { return nil }
+
+// chanFeedDB is a database whose change feed is a chanStream.
+type chanFeedDB struct {
+	*mock_drivers.MockDatabase
+	live    *chanStream
+	watched int
+}
+
+func (db *chanFeedDB) Watch(_ context.Context, _ []query.Condition) (drivers.EventStream, error) {
+	db.watched++
+	return db.live, nil
+}
+
+func newHub(t *testing.T, bufferSize int) (*Hub, *chanFeedDB) {
+	db := &chanFeedDB{
+		MockDatabase: mock_drivers.NewMockDatabase(gomock.NewController(t)),
+		live:         &chanStream{events: make(chan drivers.EiffelEvent)},
+	}
+	hub := NewHub(db, log.NewEntry(log.New()))
+	hub.BufferSize = bufferSize
+	return hub, db
+}
+
+// receive reads an event from a subscriber.
+func receive(t *testing.T, s *Subscriber) drivers.EiffelEvent {
+	t.Helper()
+	select {
+	case event, ok := <-s.Events():
+		require.True(t, ok, "subscription ended: %v", s.Err())
+		return event
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no event received")
+		return nil
+	}
+}
+
+// ended waits for a subscription to end and returns why.
+func ended(t *testing.T, s *Subscriber) error {
+	t.Helper()
+	for {
+		select {
+		case _, ok := <-s.Events():
+			if !ok {
+				return s.Err()
+			}
+		case <-time.After(5 * time.Second):
+			require.FailNow(t, "subscription didn't end")
+			return nil
+		}
+	}
+}
+
+// Test that all subscribers share a single stream of events.
+func TestHubFanOut(t *testing.T) {
+	hub, db := newHub(t, DefaultBufferSize)
+	first, err := hub.Subscribe()
+	require.NoError(t, err)
+	second, err := hub.Subscribe()
+	require.NoError(t, err)
+	assert.Equal(t, 1, db.watched)
+
+	db.live.events <- event("a", 100)
+	assert.Equal(t, "a", receive(t, first).ID())
+	assert.Equal(t, "a", receive(t, second).ID())
+
+	first.Close()
+	assert.NoError(t, ended(t, first))
+	db.live.events <- event("b", 200)
+	assert.Equal(t, "b", receive(t, second).ID())
+
+	second.Close()
+	assert.NoError(t, ended(t, second))
+	assert.Nil(t, hub.feed)
+}
+
+// Test that subscribers that fall behind are dropped without holding up the others.
+func TestHubSlowSubscriber(t *testing.T) {
+	hub, db := newHub(t, 1)
+	slow, err := hub.Subscribe()
+	require.NoError(t, err)
+	fast, err := hub.Subscribe()
+	require.NoError(t, err)
+
+	db.live.events <- event("a", 100)
+	assert.Equal(t, "a", receive(t, fast).ID())
+	db.live.events <- event("b", 200)
+	assert.Equal(t, "b", receive(t, fast).ID())
+
+	assert.ErrorIs(t, ended(t, slow), ErrSlowSubscriber)
+	slow.Close()
+	db.live.events <- event("c", 300)
+	assert.Equal(t, "c", receive(t, fast).ID())
+	fast.Close()
+}
+
+// Test that the subscriptions end when the stream fails, and that the next
+// subscriber opens a new stream.
+func TestHubStreamError(t *testing.T) {
+	hub, db := newHub(t, DefaultBufferSize)
+	first, err := hub.Subscribe()
+	require.NoError(t, err)
+
+	failure := errors.New("connection lost")
+	db.live.err = failure
+	close(db.live.events)
+	assert.ErrorIs(t, ended(t, first), failure)
+	first.Close()
+
+	db.live = &chanStream{events: make(chan drivers.EiffelEvent)}
+	second, err := hub.Subscribe()
+	require.NoError(t, err)
+	assert.Equal(t, 2, db.watched)
+	db.live.events <- event("a", 100)
+	assert.Equal(t, "a", receive(t, second).ID())
+	second.Close()
+}
+
+// Test that following replays the matching events after the last event
+// before the new ones from the hub, without returning any event twice.
+func TestHubFollow(t *testing.T) {
+	ctx := context.Background()
+	hub, db := newHub(t, DefaultBufferSize)
+	filter := []query.Condition{{Field: "meta.id", Op: "!=", Value: "skipped"}}
+	db.EXPECT().GetEventByID(gomock.Any(), "last").Return(event("last", 100), nil)
+	db.EXPECT().GetEvents(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
+			assert.Equal(t, filter[0], request.Conditions[1])
+			return drivers.NewSliceStream([]drivers.EiffelEvent{event("b", 150), event("last", 100), event("a", 120)}), -1, nil
+		})
+
+	stream, err := hub.Follow(ctx, filter, "last")
+	require.NoError(t, err)
+	go func() {
+		db.live.events <- event("b", 150)
+		db.live.events <- event("skipped", 200)
+		db.live.events <- event("c", 200)
+	}()
+	assert.Equal(t, []string{"a", "b", "c"}, collectIDs(ctx, t, stream, 3))
+	assert.NoError(t, stream.Close(ctx))
+	assert.Nil(t, hub.feed)
+}
@@ -0,0 +1,205 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// watch follows the events stored in a database as they arrive, using the
+// database's change feed when it has one and polling on meta.time otherwise.
+package watch
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// PollInterval is how often databases without a change feed are polled for new events.
+// The variable is exported to assist with testing of this and other packages.
+var PollInterval = 2 * time.Second
+
+// Events returns a never-ending stream of the events matching the conditions
+// that are stored from now on. If lastEventID is the ID of a stored event, the
+// events stored after it are replayed first so that a client can resume where
+// it left off. Events stored within the same millisecond as the last event may
+// be delivered again.
+func Events(ctx context.Context, db drivers.Database, conditions []query.Condition, lastEventID string, logger *log.Entry) (drivers.EventStream, error) {
+	s := &stream{
+		db:         db,
+		conditions: conditions,
+		logger:     logger,
+		seen:       map[string]int64{},
+		since:      time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	resuming := false
+	if lastEventID != "" {
+		lastEvent, err := db.GetEventByID(ctx, lastEventID)
+		if err != nil {
+			logger.Warningf("Can't resume after %q, following new events only: %s", lastEventID, err)
+		} else {
+			resuming = true
+			s.since = lastEvent.Time()
+			s.seen[lastEventID] = s.since
+		}
+	}
+
+	if feed, ok := db.(drivers.ChangeFeed); ok {
+		live, err := feed.Watch(ctx, conditions)
+		if err == nil {
+			s.live = live
+			// The change feed only has events stored after it was opened,
+			// so replay older ones by polling once.
+			if resuming {
+				s.poll(ctx)
+			}
+			return s, nil
+		}
+		logger.Infof("Change feed unavailable, polling for new events instead: %s", err)
+	}
+	if !resuming {
+		// Without a change feed we start polling from now on, and there's
+		// no need to wait for the first poll.
+		s.polled = true
+	}
+	return s, nil
+}
+
+// stream is a drivers.EventStream following new events in a database.
+type stream struct {
+	db         drivers.Database
+	conditions []query.Condition
+	logger     *log.Entry
+
+	// live is the database's change feed, or nil when polling.
+	live drivers.EventStream
+	// pending are polled events that haven't been returned yet.
+	pending []drivers.EiffelEvent
+	// seen maps the IDs of the polled events to their meta.time, to avoid
+	// returning them again from the next poll or the change feed.
+	seen map[string]int64
+	// since is the meta.time from which to poll for events.
+	since int64
+	// polled is true if the database has been polled at least once.
+	polled bool
+
+	event drivers.EiffelEvent
+	err   error
+}
+
+// Next waits for the next event.
+func (s *stream) Next(ctx context.Context) bool {
+	s.event = nil
+	for {
+		if len(s.pending) > 0 {
+			s.event = s.pending[0]
+			s.pending = s.pending[1:]
+			return true
+		}
+		if s.live != nil {
+			if !s.live.Next(ctx) {
+				s.err = s.live.Err()
+				return false
+			}
+			if _, seen := s.seen[s.live.Event().ID()]; seen {
+				continue
+			}
+			s.event = s.live.Event()
+			return true
+		}
+		if s.polled {
+			select {
+			case <-ctx.Done():
+				s.err = ctx.Err()
+				return false
+			case <-time.After(PollInterval):
+			}
+		}
+		s.polled = true
+		s.poll(ctx)
+	}
+}
+
+// poll fetches the events stored since the last poll and queues the ones
+// that haven't been seen before in meta.time order. Errors are logged and
+// otherwise ignored so that the next poll can try again.
+func (s *stream) poll(ctx context.Context) {
+	conditions := append([]query.Condition{{
+		Field:    "meta.time",
+		Op:       ">=",
+		Value:    strconv.FormatInt(s.since, 10),
+		TypeConv: "int",
+	}}, s.conditions...)
+	events, _, err := s.db.GetEvents(ctx, requests.MultipleEventsRequest{
+		PageNo:     1,
+		Count:      requests.CountNone,
+		Unpaged:    true,
+		Conditions: conditions,
+	})
+	if err != nil {
+		s.logger.Warningf("Error polling for new events: %s", err)
+		return
+	}
+	polled, err := drivers.Collect(ctx, events)
+	if err != nil {
+		s.logger.Warningf("Error polling for new events: %s", err)
+		return
+	}
+	sort.SliceStable(polled, func(i, j int) bool {
+		return polled[i].Time() < polled[j].Time()
+	})
+	for _, event := range polled {
+		if _, seen := s.seen[event.ID()]; seen {
+			continue
+		}
+		s.seen[event.ID()] = event.Time()
+		s.pending = append(s.pending, event)
+		if event.Time() > s.since {
+			s.since = event.Time()
+		}
+	}
+	// When polling, only events at the start of the next poll can be
+	// returned again. The events from the single poll made before following
+	// a change feed are all kept since the feed may return any of them.
+	if s.live == nil {
+		for id, eventTime := range s.seen {
+			if eventTime < s.since {
+				delete(s.seen, id)
+			}
+		}
+	}
+}
+
+// Event returns the current event.
+func (s *stream) Event() drivers.EiffelEvent {
+	return s.event
+}
+
+// Err returns the error that made Next return false.
+func (s *stream) Err() error {
+	return s.err
+}
+
+// Close the stream and the change feed, if any.
+func (s *stream) Close(ctx context.Context) error {
+	if s.live != nil {
+		return s.live.Close(ctx)
+	}
+	return nil
+}
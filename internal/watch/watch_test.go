@@ -0,0 +1,114 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package watch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+func event(id string, time int64) drivers.EiffelEvent {
+	return drivers.EiffelEvent{"meta": map[string]interface{}{"id": id, "time": time}}
+}
+
+// collectIDs reads n events from a stream and returns their IDs.
+func collectIDs(ctx context.Context, t *testing.T, stream drivers.EventStream, n int) []string {
+	t.Helper()
+	var ids []string
+	for i := 0; i < n; i++ {
+		require.True(t, stream.Next(ctx), "stream ended after %d events: %v", i, stream.Err())
+		ids = append(ids, stream.Event().ID())
+	}
+	return ids
+}
+
+// Test that databases without a change feed are polled from the time of the
+// last event, without returning any event twice.
+func TestEventsPolling(t *testing.T) {
+	PollInterval = time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctrl := gomock.NewController(t)
+	mockDB := mock_drivers.NewMockDatabase(ctrl)
+	filter := []query.Condition{{Field: "meta.type", Op: "=", Value: "EiffelActivityTriggeredEvent"}}
+
+	mockDB.EXPECT().GetEventByID(gomock.Any(), "last").Return(event("last", 100), nil)
+	var polledSince []string
+	polls := [][]drivers.EiffelEvent{
+		{event("c", 150), event("last", 100), event("b", 100)},
+		{event("c", 150), event("d", 200)},
+	}
+	mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
+			assert.True(t, request.Unpaged)
+			assert.Equal(t, filter[0], request.Conditions[1])
+			polledSince = append(polledSince, request.Conditions[0].Value)
+			var events []drivers.EiffelEvent
+			if len(polls) > 0 {
+				events, polls = polls[0], polls[1:]
+			}
+			return drivers.NewSliceStream(events), -1, nil
+		}).MinTimes(2)
+
+	stream, err := Events(ctx, mockDB, filter, "last", log.NewEntry(log.New()))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "d"}, collectIDs(ctx, t, stream, 3))
+	assert.Equal(t, []string{"100", "150"}, polledSince[:2])
+
+	cancel()
+	assert.False(t, stream.Next(ctx))
+	assert.ErrorIs(t, stream.Err(), context.Canceled)
+	assert.NoError(t, stream.Close(ctx))
+}
+
+// changeFeedDB is a database with a change feed.
+type changeFeedDB struct {
+	*mock_drivers.MockDatabase
+	live []drivers.EiffelEvent
+}
+
+func (db *changeFeedDB) Watch(_ context.Context, _ []query.Condition) (drivers.EventStream, error) {
+	return drivers.NewSliceStream(db.live), nil
+}
+
+// Test that databases with a change feed replay missed events before following the feed.
+func TestEventsChangeFeed(t *testing.T) {
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	db := &changeFeedDB{
+		MockDatabase: mock_drivers.NewMockDatabase(ctrl),
+		live:         []drivers.EiffelEvent{event("b", 150), event("c", 200)},
+	}
+	db.EXPECT().GetEventByID(gomock.Any(), "last").Return(event("last", 100), nil)
+	db.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return(
+		drivers.NewSliceStream([]drivers.EiffelEvent{event("last", 100), event("a", 120), event("b", 150)}), int64(-1), nil)
+
+	stream, err := Events(ctx, db, nil, "last", log.NewEntry(log.New()))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, collectIDs(ctx, t, stream, 3))
+	assert.False(t, stream.Next(ctx))
+}
@@ -17,6 +17,8 @@ package application
 
 import (
 	"context"
+	"errors"
+	"sync"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
@@ -24,6 +26,11 @@ import (
 	"github.com/eiffel-community/eiffel-goer/internal/config"
 	"github.com/eiffel-community/eiffel-goer/internal/database"
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest/amqp"
+	"github.com/eiffel-community/eiffel-goer/internal/retention"
+	"github.com/eiffel-community/eiffel-goer/internal/signature"
+	"github.com/eiffel-community/eiffel-goer/pkg/graphql"
 	"github.com/eiffel-community/eiffel-goer/pkg/server"
 	v1api "github.com/eiffel-community/eiffel-goer/pkg/v1/api"
 )
@@ -35,6 +42,15 @@ type Application struct {
 	Server   server.Server
 	V1       *v1api.V1Application
 	Logger   *log.Entry
+	// Verifier verifies the signatures of events, if keys are configured.
+	Verifier *signature.Verifier
+	// Ingester ingests the events sent to the webhook, if it's enabled,
+	// by producers with the WebhookSecrets.
+	Ingester       *ingest.Ingester
+	WebhookSecrets ingest.Secrets
+
+	stopBackground []context.CancelFunc
+	background     sync.WaitGroup
 }
 
 // Get a new Goer application.
@@ -59,7 +75,7 @@ func Get(ctx context.Context, cfg config.Config, logger *log.Entry) (*Applicatio
 func (app *Application) getDB(ctx context.Context) (drivers.Database, error) {
 	db, err := database.Get(
 		ctx,
-		app.Config.DBConnectionString(),
+		app.Config,
 		app.Logger,
 	)
 	if err != nil {
@@ -68,17 +84,141 @@ func (app *Application) getDB(ctx context.Context) (drivers.Database, error) {
 	return db, nil
 }
 
+// LoadVerifier loads the public keys that event signatures are verified against.
+func (app *Application) LoadVerifier() error {
+	verifier, err := signature.Load(app.Config.PublicKeyFiles(), app.Config.JWKSFile())
+	if err != nil {
+		return err
+	}
+	app.Verifier = verifier
+	return nil
+}
+
+// NewIngester creates an ingester that stores events according to the configured
+// duplicate policy, rejecting unverified events if signatures are required.
+func (app *Application) NewIngester() (*ingest.Ingester, error) {
+	policy, err := ingest.ParsePolicy(app.Config.DuplicatePolicy())
+	if err != nil {
+		return nil, err
+	}
+	ingester, err := ingest.New(app.Database, policy, app.Logger)
+	if err != nil {
+		return nil, err
+	}
+	if app.Config.RequireSignatures() {
+		ingester.Verifier = app.Verifier
+		if ingester.Verifier == nil {
+			ingester.Verifier = &signature.Verifier{}
+		}
+	}
+	return ingester, nil
+}
+
+// LoadWebhook loads the producers' secrets and creates the ingester for the
+// webhook if it's enabled.
+func (app *Application) LoadWebhook() error {
+	if app.Config.WebhookSecretsFile() == "" {
+		return nil
+	}
+	secrets, err := ingest.LoadSecrets(app.Config.WebhookSecretsFile())
+	if err != nil {
+		return err
+	}
+	ingester, err := app.NewIngester()
+	if err != nil {
+		return err
+	}
+	app.Ingester = ingester
+	app.WebhookSecrets = secrets
+	return nil
+}
+
+// StartConsumer starts consuming events from the configured AMQP broker in the
+// background until the application is stopped. It does nothing if no broker is configured.
+func (app *Application) StartConsumer(ctx context.Context) error {
+	if app.Config.AMQPURL() == "" {
+		return nil
+	}
+	ingester, err := app.NewIngester()
+	if err != nil {
+		return err
+	}
+	broker := &amqp.Broker{
+		URL:      app.Config.AMQPURL(),
+		Exchange: app.Config.AMQPExchange(),
+		Queue:    app.Config.AMQPQueue(),
+		Bindings: app.Config.AMQPBindings(),
+	}
+	app.startBackground(ctx, ingest.NewConsumer(broker, ingester, app.Logger).Run)
+	return nil
+}
+
+// StartRetention starts applying the retention policy in the background until
+// the application is stopped. It does nothing if no policy is configured.
+func (app *Application) StartRetention(ctx context.Context) error {
+	if app.Config.RetentionPolicy() == "" {
+		return nil
+	}
+	policy, err := retention.ParsePolicy(app.Config.RetentionPolicy())
+	if err != nil {
+		return err
+	}
+	if app.Config.ArchiveDir() == "" {
+		return errors.New("a retention policy requires an archive directory for the expired events")
+	}
+	if _, ok := app.Database.(drivers.Writer); !ok {
+		return drivers.ErrReadOnly
+	}
+	job := &retention.Job{
+		Database: app.Database,
+		Policy:   policy,
+		Dir:      app.Config.ArchiveDir(),
+		Interval: app.Config.RetentionInterval(),
+		Logger:   app.Logger,
+	}
+	app.startBackground(ctx, job.Run)
+	return nil
+}
+
+// startBackground runs a function in the background until Stop is called.
+func (app *Application) startBackground(ctx context.Context, run func(context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	app.stopBackground = append(app.stopBackground, cancel)
+	app.background.Add(1)
+	go func() {
+		defer app.background.Done()
+		run(ctx)
+	}()
+}
+
 // LoadV1Routes loads routes for the /v1/ endpoint.
 func (app *Application) LoadV1Routes() {
 	app.V1 = &v1api.V1Application{
 		Config:   app.Config,
 		Database: app.Database,
 		Logger:   app.Logger,
+		Verifier: app.Verifier,
+
+		Ingester:       app.Ingester,
+		WebhookSecrets: app.WebhookSecrets,
 	}
 	subrouter := app.Router.PathPrefix("/v1").Name("v1").Subrouter()
 	app.V1.AddRoutes(subrouter)
 }
 
+// LoadGraphQLRoutes loads the route for the /graphql endpoint if it's enabled.
+func (app *Application) LoadGraphQLRoutes() error {
+	if !app.Config.EnableGraphQL() {
+		return nil
+	}
+	handler, err := graphql.Get(app.Config, app.Database, app.Logger)
+	if err != nil {
+		return err
+	}
+	app.Router.HandleFunc("/graphql", handler.Serve).Methods("GET", "POST", "OPTIONS").Name("graphql")
+	return nil
+}
+
 // Start connects to the database and starts the webserver.
 // This is a blocking function, waiting for the webserver to shut down.
 func (app *Application) Start(ctx context.Context) error {
@@ -95,7 +235,12 @@ func (app *Application) Start(ctx context.Context) error {
 	return srv.Error()
 }
 
-// Stop the application and close the database connection.
+// Stop the application, waiting for the consumer and other background jobs
+// to stop before closing the database connection.
 func (app *Application) Stop(ctx context.Context) error {
+	for _, cancel := range app.stopBackground {
+		cancel()
+	}
+	app.background.Wait()
 	return app.Database.Close(ctx)
 }
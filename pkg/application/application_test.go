@@ -18,12 +18,19 @@ package application
 import (
 	"context"
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
 	"github.com/eiffel-community/eiffel-goer/test"
 	"github.com/eiffel-community/eiffel-goer/test/mock_config"
 	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
@@ -40,7 +47,7 @@ func TestGet(t *testing.T) {
 	mockDB := mock_drivers.NewMockDatabase(ctrl)
 
 	mockDriver.EXPECT().SupportsScheme("mongodb").Return(true)
-	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
+	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
 	test.SetDatabaseDriver(mockDriver)
 	defer test.ResetDatabaseDriver()
 
@@ -88,7 +95,7 @@ func TestGetDB(t *testing.T) {
 	mockDriver := mock_drivers.NewMockDatabaseDriver(ctrl)
 	mockDB := mock_drivers.NewMockDatabase(ctrl)
 	mockDriver.EXPECT().SupportsScheme("mongodb").Return(true)
-	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
+	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
 	test.SetDatabaseDriver(mockDriver)
 	defer test.ResetDatabaseDriver()
 
@@ -106,11 +113,13 @@ func TestLoadV1Routes(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	mockCfg := mock_config.NewMockConfig(ctrl)
 	mockCfg.EXPECT().DBConnectionString().Return("mongodb://testdb/testdb").Times(2)
+	mockCfg.EXPECT().EnableAdmin().Return(false)
+	mockCfg.EXPECT().AllowedOrigins().Return(nil)
 
 	mockDriver := mock_drivers.NewMockDatabaseDriver(ctrl)
 	mockDB := mock_drivers.NewMockDatabase(ctrl)
 	mockDriver.EXPECT().SupportsScheme("mongodb").Return(true)
-	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
+	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
 	test.SetDatabaseDriver(mockDriver)
 	defer test.ResetDatabaseDriver()
 
@@ -121,6 +130,159 @@ func TestLoadV1Routes(t *testing.T) {
 	assert.NotNil(t, app.Router.Get("v1"))
 }
 
+// Test that the application adds the GraphQL route only when it's enabled.
+func TestLoadGraphQLRoutes(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		ctrl := gomock.NewController(t)
+		mockCfg := mock_config.NewMockConfig(ctrl)
+		mockCfg.EXPECT().EnableGraphQL().Return(enabled)
+		app := &Application{
+			Config:   mockCfg,
+			Database: mock_drivers.NewMockDatabase(ctrl),
+			Router:   mux.NewRouter(),
+			Logger:   &log.Entry{},
+		}
+		assert.NoError(t, app.LoadGraphQLRoutes())
+		assert.Equal(t, enabled, app.Router.Get("graphql") != nil)
+	}
+}
+
+// Test that the application loads the configured keys and fails on missing key files.
+func TestLoadVerifier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().PublicKeyFiles().Return(nil)
+	mockCfg.EXPECT().JWKSFile().Return("")
+	app := &Application{Config: mockCfg}
+	assert.NoError(t, app.LoadVerifier())
+	assert.NotNil(t, app.Verifier)
+	assert.Empty(t, app.Verifier.Keys)
+
+	mockCfg.EXPECT().PublicKeyFiles().Return([]string{"/nonexistent/key.pem"})
+	mockCfg.EXPECT().JWKSFile().Return("")
+	assert.Error(t, app.LoadVerifier())
+}
+
+// Test that ingesters use the configured policy, verify signatures only if
+// required and can't be created for read-only databases.
+func TestNewIngester(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	app := &Application{Config: mockCfg, Database: test.NewMemoryDatabase()}
+
+	mockCfg.EXPECT().DuplicatePolicy().Return("reject")
+	mockCfg.EXPECT().RequireSignatures().Return(false)
+	ingester, err := app.NewIngester()
+	assert.NoError(t, err)
+	assert.Equal(t, ingest.PolicyReject, ingester.Policy)
+	assert.Nil(t, ingester.Verifier)
+
+	mockCfg.EXPECT().DuplicatePolicy().Return("ignore-identical")
+	mockCfg.EXPECT().RequireSignatures().Return(true)
+	ingester, err = app.NewIngester()
+	assert.NoError(t, err)
+	assert.NotNil(t, ingester.Verifier)
+
+	mockCfg.EXPECT().DuplicatePolicy().Return("unknown")
+	_, err = app.NewIngester()
+	assert.Error(t, err)
+
+	app.Database = mock_drivers.NewMockDatabase(ctrl)
+	mockCfg.EXPECT().DuplicatePolicy().Return("reject")
+	_, err = app.NewIngester()
+	assert.ErrorIs(t, err, drivers.ErrReadOnly)
+}
+
+// Test that the webhook is only loaded when enabled and fails on missing secrets files.
+func TestLoadWebhook(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	app := &Application{Config: mockCfg, Database: test.NewMemoryDatabase()}
+
+	mockCfg.EXPECT().WebhookSecretsFile().Return("")
+	assert.NoError(t, app.LoadWebhook())
+	assert.Nil(t, app.WebhookSecrets)
+
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"jenkins": "s3cr3t"}`), 0o600))
+	mockCfg.EXPECT().WebhookSecretsFile().Return(path).Times(2)
+	mockCfg.EXPECT().DuplicatePolicy().Return("reject")
+	mockCfg.EXPECT().RequireSignatures().Return(false)
+	assert.NoError(t, app.LoadWebhook())
+	assert.Equal(t, ingest.Secrets{"jenkins": "s3cr3t"}, app.WebhookSecrets)
+	assert.NotNil(t, app.Ingester)
+
+	mockCfg.EXPECT().WebhookSecretsFile().Return("/nonexistent/secrets.json").Times(2)
+	assert.Error(t, app.LoadWebhook())
+}
+
+// Test that no consumer is started unless an AMQP broker is configured.
+func TestStartConsumerDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().AMQPURL().Return("")
+	app := &Application{Config: mockCfg}
+	assert.NoError(t, app.StartConsumer(context.Background()))
+	assert.Empty(t, app.stopBackground)
+}
+
+// Test that Stop waits for the consumer to stop before closing the database.
+func TestStopConsumer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mock_drivers.NewMockDatabase(ctrl)
+	broker := test.NewMemoryBroker()
+	ingester := &ingest.Ingester{Database: test.NewMemoryDatabase(), Writer: test.NewMemoryDatabase(), Logger: &log.Entry{Logger: log.New()}}
+	app := &Application{Database: mockDB}
+	stopped := false
+	app.startBackground(context.Background(), func(ctx context.Context) {
+		ingest.NewConsumer(broker, ingester, &log.Entry{Logger: log.New()}).Run(ctx)
+		stopped = true
+	})
+
+	mockDB.EXPECT().Close(gomock.Any()).Return(nil)
+	assert.NoError(t, app.Stop(context.Background()))
+	assert.True(t, stopped, "the consumer was still running after Stop")
+}
+
+// Test that the retention job is only started with a policy, an archive
+// directory and a writable database.
+func TestStartRetention(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		dir     string
+		db      drivers.Database
+		started bool
+	}{
+		{name: "Disabled", policy: ""},
+		{name: "Enabled", policy: "*=90d", dir: "archive", db: test.NewMemoryDatabase(), started: true},
+		{name: "InvalidPolicy", policy: "*=never", dir: "archive", db: test.NewMemoryDatabase()},
+		{name: "NoArchive", policy: "*=90d", db: test.NewMemoryDatabase()},
+		{name: "ReadOnly", policy: "*=90d", dir: "archive", db: mock_drivers.NewMockDatabase(gomock.NewController(t))},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCfg := mock_config.NewMockConfig(ctrl)
+			mockCfg.EXPECT().RetentionPolicy().Return(testCase.policy).AnyTimes()
+			mockCfg.EXPECT().ArchiveDir().Return(testCase.dir).AnyTimes()
+			mockCfg.EXPECT().RetentionInterval().Return(time.Hour).AnyTimes()
+			app := &Application{Config: mockCfg, Database: testCase.db, Logger: &log.Entry{Logger: log.New()}}
+			// Stopped right away, the job applies the policy once.
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			err := app.StartRetention(ctx)
+			app.background.Wait()
+			if testCase.started || testCase.policy == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+			assert.Equal(t, testCase.started, len(app.stopBackground) == 1)
+		})
+	}
+}
+
 // Test that the application starts the WebServer & connects to the Database.
 func TestStart(t *testing.T) {
 	ctrl := gomock.NewController(t)
@@ -131,7 +293,7 @@ func TestStart(t *testing.T) {
 	ctx := context.Background()
 
 	mockDriver.EXPECT().SupportsScheme("mongodb").Return(true)
-	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
+	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
 	test.SetDatabaseDriver(mockDriver)
 	defer test.ResetDatabaseDriver()
 
@@ -163,7 +325,7 @@ func TestStartFail(t *testing.T) {
 	ctx := context.Background()
 
 	mockDriver.EXPECT().SupportsScheme("mongodb").Return(true)
-	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
+	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
 	test.SetDatabaseDriver(mockDriver)
 	defer test.ResetDatabaseDriver()
 
@@ -192,7 +354,7 @@ func TestStop(t *testing.T) {
 	ctx := context.Background()
 
 	mockDriver.EXPECT().SupportsScheme("mongodb").Return(true)
-	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
+	mockDriver.EXPECT().Get(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(mockDB, nil)
 	test.SetDatabaseDriver(mockDriver)
 	defer test.ResetDatabaseDriver()
 
@@ -0,0 +1,68 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package graphql
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+)
+
+// dataType returns the GraphQL type of the data object of an event type,
+// generated from the data field of the SDK struct of the event type.
+// Nested objects are named after the path to them, e.g.
+// ArtifactCreatedDataFileInformation.
+func dataType(name string, event interface{}) graphql.Output {
+	field, _ := reflect.TypeOf(event).FieldByName("Data")
+	return outputType(name+"Data", field.Type)
+}
+
+// outputType returns the GraphQL type of the values of a Go type. Integers
+// are floats since GraphQL's Int only has 32 bits, and values of any type
+// are passed through as JSON.
+func outputType(name string, t reflect.Type) graphql.Output {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return outputType(name, t.Elem())
+	case reflect.String:
+		return graphql.String
+	case reflect.Bool:
+		return graphql.Boolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return graphql.Float
+	case reflect.Slice, reflect.Array:
+		return graphql.NewList(outputType(name, t.Elem()))
+	case reflect.Struct:
+		fields := graphql.Fields{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := strings.Split(field.Tag.Get("json"), ",")[0]
+			if field.PkgPath != "" || key == "" || key == "-" {
+				continue
+			}
+			fields[key] = &graphql.Field{Type: outputType(name+field.Name, field.Type)}
+		}
+		if len(fields) == 0 {
+			return jsonScalar
+		}
+		return graphql.NewObject(graphql.ObjectConfig{Name: name, Fields: fields})
+	default:
+		return jsonScalar
+	}
+}
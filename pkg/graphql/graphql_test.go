@@ -0,0 +1,391 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+const (
+	artifactID = "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"
+	clmID      = "e04cf9d3-4d57-471e-bd65-f8fc20d21d84"
+	missingID  = "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f"
+)
+
+var (
+	artifact = drivers.EiffelEvent{
+		"meta":  map[string]interface{}{"id": artifactID, "type": "EiffelArtifactCreatedEvent", "version": "3.0.0", "time": float64(1629449650361)},
+		"data":  map[string]interface{}{"identity": "pkg:maven/my.namespace/my-name@1.0.0"},
+		"links": []interface{}{},
+	}
+	clm = drivers.EiffelEvent{
+		"meta": map[string]interface{}{"id": clmID, "type": "EiffelConfidenceLevelModifiedEvent", "version": "3.0.0", "time": float64(1629449650362)},
+		"data": map[string]interface{}{"name": "stable", "value": "SUCCESS"},
+		"links": []interface{}{
+			map[string]interface{}{"type": "SUBJECT", "target": artifactID},
+			map[string]interface{}{"type": "SUBJECT", "target": missingID},
+			map[string]interface{}{"type": "CAUSE", "target": artifactID},
+		},
+	}
+)
+
+// do sends a GraphQL query to the handler with a POST request and returns the decoded response.
+func do(t *testing.T, handler *Handler, query string) map[string]interface{} {
+	body, err := json.Marshal(graphQLRequest{Query: query})
+	require.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	responseRecorder := httptest.NewRecorder()
+	handler.Serve(responseRecorder, request)
+	require.Equal(t, http.StatusOK, responseRecorder.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &response))
+	return response
+}
+
+func newHandler(t *testing.T, db drivers.Database) *Handler {
+	ctrl := gomock.NewController(t)
+	handler, err := Get(mock_config.NewMockConfig(ctrl), db, &log.Entry{Logger: log.New()})
+	require.NoError(t, err)
+	return handler
+}
+
+// Test that typed queries resolve links to the linked events, looking them up in a single batch.
+func TestTypedQueryWithLinks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mock_drivers.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetEvents(gomock.Any(), requests.MultipleEventsRequest{
+		PageNo:   1,
+		PageSize: 1,
+		Count:    requests.CountEstimated,
+		Conditions: []query.Condition{
+			{Field: "data.name", Op: "=", Value: "stable"},
+			{Field: "meta.type", Op: "=", Value: "EiffelConfidenceLevelModifiedEvent"},
+		},
+	}).Return(drivers.NewSliceStream([]drivers.EiffelEvent{clm}), int64(2), nil)
+	mockDB.EXPECT().GetEventsByIDs(gomock.Any(), []string{artifactID, missingID}).Return([]drivers.EiffelEvent{artifact}, nil).Times(1)
+
+	response := do(t, newHandler(t, mockDB), `{
+		confidenceLevelModified(search: "{'data.name': 'stable'}", first: 1) {
+			edges {
+				cursor
+				node {
+					meta { id type }
+					data { value }
+					links(type: "SUBJECT") {
+						__typename
+						... on EiffelArtifactCreatedEvent { data { identity } }
+					}
+					linkRefs { type target event { meta { id } } }
+				}
+			}
+			pageInfo { hasNextPage hasPreviousPage endCursor }
+		}
+	}`)
+	require.Nil(t, response["errors"])
+	expected := fmt.Sprintf(`{"data": {"confidenceLevelModified": {
+		"edges": [{
+			"cursor": %[1]q,
+			"node": {
+				"meta": {"id": %[2]q, "type": "EiffelConfidenceLevelModifiedEvent"},
+				"data": {"value": "SUCCESS"},
+				"links": [{"__typename": "EiffelArtifactCreatedEvent", "data": {"identity": "pkg:maven/my.namespace/my-name@1.0.0"}}],
+				"linkRefs": [
+					{"type": "SUBJECT", "target": %[3]q, "event": {"meta": {"id": %[3]q}}},
+					{"type": "SUBJECT", "target": %[4]q, "event": null},
+					{"type": "CAUSE", "target": %[3]q, "event": {"meta": {"id": %[3]q}}}
+				]
+			}
+		}],
+		"pageInfo": {"hasNextPage": true, "hasPreviousPage": false, "endCursor": %[1]q}
+	}}}`, encodeCursor(0), clmID, artifactID, missingID)
+	actual, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, expected, string(actual))
+}
+
+// Test that the typed data objects resolve nested objects and lists, also as
+// decoded by the MongoDB driver, and that events of other types have JSON data.
+func TestTypedData(t *testing.T) {
+	finished := drivers.EiffelEvent{
+		"meta": primitive.M{"id": artifactID, "type": "EiffelTestCaseFinishedEvent", "version": "3.0.0", "time": int64(1629449650361)},
+		"data": primitive.M{
+			"outcome": primitive.M{
+				"verdict": "PASSED",
+				"metrics": primitive.A{primitive.M{"name": "duration", "value": int32(12)}},
+			},
+			"persistentLogs": primitive.A{primitive.M{"name": "log", "uri": "https://logs.example.com/1"}},
+		},
+		"links": primitive.A{},
+	}
+	custom := drivers.EiffelEvent{
+		"meta":  map[string]interface{}{"id": clmID, "type": "MyCustomEvent", "version": "1.0.0", "time": float64(1629449650362)},
+		"data":  map[string]interface{}{"anything": []interface{}{"goes"}},
+		"links": []interface{}{},
+	}
+	ctrl := gomock.NewController(t)
+	mockDB := mock_drivers.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetEventByID(gomock.Any(), artifactID).Return(finished, nil)
+	mockDB.EXPECT().GetEventByID(gomock.Any(), clmID).Return(custom, nil)
+
+	response := do(t, newHandler(t, mockDB), fmt.Sprintf(`{
+		finished: event(id: %q) {
+			meta { time }
+			... on EiffelTestCaseFinishedEvent {
+				data {
+					outcome { verdict conclusion metrics { name value } }
+					persistentLogs { uri }
+				}
+			}
+		}
+		custom: event(id: %q) { ... on GenericEiffelEvent { data } }
+	}`, artifactID, clmID))
+	require.Nil(t, response["errors"])
+	actual, err := json.Marshal(response)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data": {
+		"finished": {
+			"meta": {"time": 1629449650361},
+			"data": {
+				"outcome": {"verdict": "PASSED", "conclusion": null, "metrics": [{"name": "duration", "value": 12}]},
+				"persistentLogs": [{"uri": "https://logs.example.com/1"}]
+			}
+		},
+		"custom": {"data": {"anything": ["goes"]}}
+	}}`, string(actual))
+}
+
+// Test that searches are parsed as JSON conditions or in the REST API's query language.
+func TestParseSearch(t *testing.T) {
+	tests := []struct {
+		name     string
+		search   string
+		expected []query.Condition
+		wantErr  string
+	}{
+		{name: "Empty", search: ""},
+		{name: "QueryLanguage", search: "data.name=stable&int(meta.time)%3E=10", expected: []query.Condition{
+			{Field: "data.name", Op: "=", Value: "stable"},
+			{Field: "meta.time", Op: ">=", Value: "10", TypeConv: "int"},
+		}},
+		{name: "JSONEquality", search: `{"data.name": "stable", "data.count": 2, "data.ratio": 0.5, "data.ok": true}`, expected: []query.Condition{
+			{Field: "data.count", Op: "=", Value: "2", TypeConv: "int"},
+			{Field: "data.name", Op: "=", Value: "stable"},
+			{Field: "data.ok", Op: "=", Value: "true", TypeConv: "bool"},
+			{Field: "data.ratio", Op: "=", Value: "0.5", TypeConv: "double"},
+		}},
+		{name: "JSONOperators", search: `{"meta.time": {"$gte": 10, "$lt": 20}, "data.name": {"$ne": "stable", "$exists": true}}`, expected: []query.Condition{
+			{Field: "data.name", Op: "exists", Value: "true", TypeConv: "bool"},
+			{Field: "data.name", Op: "!=", Value: "stable"},
+			{Field: "meta.time", Op: ">=", Value: "10", TypeConv: "int"},
+			{Field: "meta.time", Op: "<", Value: "20", TypeConv: "int"},
+		}},
+		{name: "JSONAnd", search: `{"$and": [{"meta.time": {"$gt": 1}}, {"meta.time": {"$lte": 2}}]}`, expected: []query.Condition{
+			{Field: "meta.time", Op: ">", Value: "1", TypeConv: "int"},
+			{Field: "meta.time", Op: "<=", Value: "2", TypeConv: "int"},
+		}},
+		{name: "SingleQuotes", search: `{'meta.id': 'abc'}`, expected: []query.Condition{
+			{Field: "meta.id", Op: "=", Value: "abc"},
+		}},
+		{name: "ApostropheInDoubleQuotes", search: `{'data.name': "Bob's build"}`, expected: []query.Condition{
+			{Field: "data.name", Op: "=", Value: "Bob's build"},
+		}},
+		{name: "EscapesInSingleQuotes", search: `{'data.name': 'Bob\'s "build"\n'}`, expected: []query.Condition{
+			{Field: "data.name", Op: "=", Value: "Bob's \"build\"\n"},
+		}},
+		{name: "UnterminatedString", search: `{'data.name': 'Bob}`, wantErr: "unterminated string"},
+		{name: "UnsupportedOperator", search: `{"data.name": {"$regex": "^stable"}}`, wantErr: `unsupported operator "$regex"`},
+		{name: "UnsupportedTopLevelOperator", search: `{"$or": []}`, wantErr: `unsupported operator "$or"`},
+		{name: "UnsupportedValue", search: `{"data.name": null}`, wantErr: `unsupported value of "data.name"`},
+		{name: "InvalidAnd", search: `{"$and": {"a": "b"}}`, wantErr: "$and must be an array of objects"},
+		{name: "InvalidExists", search: `{"a": {"$exists": 1}}`, wantErr: `$exists of "a" must be a boolean`},
+		{name: "InvalidJSON", search: `{"a": `, wantErr: "unexpected EOF"},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			conditions, err := parseSearch(testCase.search)
+			if testCase.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), testCase.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, conditions)
+		})
+	}
+}
+
+// Test that link targets are fetched in a single batch also when their events
+// are selected through fragments.
+func TestLinkRefsThroughFragments(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"InlineFragment", `{ event(id: %q) { linkRefs { ... on EiffelLink { event { meta { id } } } } } }`},
+		{"FragmentSpread", `{ event(id: %q) { linkRefs { ...target } } } fragment target on EiffelLink { event { meta { id } } }`},
+		{"NestedFragments", `{ event(id: %q) { linkRefs { ...outer } } } fragment outer on EiffelLink { ... { ...inner } } fragment inner on EiffelLink { event { meta { id } } }`},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockDB := mock_drivers.NewMockDatabase(ctrl)
+			mockDB.EXPECT().GetEventByID(gomock.Any(), clmID).Return(clm, nil)
+			mockDB.EXPECT().GetEventsByIDs(gomock.Any(), []string{artifactID, missingID}).Return([]drivers.EiffelEvent{artifact}, nil).Times(1)
+
+			response := do(t, newHandler(t, mockDB), fmt.Sprintf(testCase.query, clmID))
+			require.Nil(t, response["errors"])
+			linkRefs := response["data"].(map[string]interface{})["event"].(map[string]interface{})["linkRefs"].([]interface{})
+			assert.Len(t, linkRefs, 3)
+		})
+	}
+}
+
+// Test that the events after a cursor are fetched as aligned pages, with
+// a second page only when the requested events span two pages.
+func TestConnectionPaging(t *testing.T) {
+	type page struct {
+		request  requests.MultipleEventsRequest
+		returned []drivers.EiffelEvent
+	}
+	tests := []struct {
+		name     string
+		after    int
+		first    int
+		pages    []page
+		expected []string
+	}{
+		{
+			name:  "Aligned",
+			after: 1,
+			first: 2,
+			pages: []page{
+				{requests.MultipleEventsRequest{PageNo: 2, PageSize: 2, Count: requests.CountEstimated}, []drivers.EiffelEvent{artifact, clm}},
+			},
+			expected: []string{artifactID, clmID},
+		},
+		{
+			name:  "Unaligned",
+			after: 0,
+			first: 2,
+			pages: []page{
+				{requests.MultipleEventsRequest{PageNo: 1, PageSize: 2, Count: requests.CountEstimated}, []drivers.EiffelEvent{clm, artifact}},
+				{requests.MultipleEventsRequest{PageNo: 2, PageSize: 2, Count: requests.CountNone}, []drivers.EiffelEvent{clm, artifact}},
+			},
+			expected: []string{artifactID, clmID},
+		},
+		{
+			name:  "UnalignedFarOffset",
+			after: 99999,
+			first: 3,
+			pages: []page{
+				{requests.MultipleEventsRequest{PageNo: 33334, PageSize: 3, Count: requests.CountEstimated}, []drivers.EiffelEvent{clm, artifact, clm}},
+				{requests.MultipleEventsRequest{PageNo: 33335, PageSize: 3, Count: requests.CountNone}, []drivers.EiffelEvent{artifact}},
+			},
+			expected: []string{artifactID, clmID, artifactID},
+		},
+		{
+			name:  "UnalignedLastPage",
+			after: 0,
+			first: 2,
+			pages: []page{
+				{requests.MultipleEventsRequest{PageNo: 1, PageSize: 2, Count: requests.CountEstimated}, []drivers.EiffelEvent{clm}},
+			},
+			expected: nil,
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockDB := mock_drivers.NewMockDatabase(ctrl)
+			for _, page := range testCase.pages {
+				mockDB.EXPECT().GetEvents(gomock.Any(), page.request).Return(
+					drivers.NewSliceStream(page.returned), int64(4), nil)
+			}
+			response := do(t, newHandler(t, mockDB), fmt.Sprintf(
+				`{ events(first: %d, after: %q) { edges { node { meta { id } } } pageInfo { hasPreviousPage } } }`,
+				testCase.first, encodeCursor(testCase.after)))
+			require.Nil(t, response["errors"])
+			events := response["data"].(map[string]interface{})["events"].(map[string]interface{})
+			var ids []string
+			for _, e := range events["edges"].([]interface{}) {
+				meta := e.(map[string]interface{})["node"].(map[string]interface{})["meta"].(map[string]interface{})
+				ids = append(ids, meta["id"].(string))
+			}
+			assert.Equal(t, testCase.expected, ids)
+			assert.Equal(t, true, events["pageInfo"].(map[string]interface{})["hasPreviousPage"])
+		})
+	}
+}
+
+// Test that single events are looked up by ID and that GET requests work.
+func TestEventByIDWithGet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockDB := mock_drivers.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetEventByID(gomock.Any(), artifactID).Return(artifact, nil)
+	mockDB.EXPECT().GetEventByID(gomock.Any(), missingID).Return(nil, drivers.ErrNotFound)
+	handler := newHandler(t, mockDB)
+
+	query := url.Values{
+		"query":     {`query($id: ID!, $missing: ID!) { event(id: $id) { __typename meta { time } } missing: event(id: $missing) { __typename } }`},
+		"variables": {fmt.Sprintf(`{"id": %q, "missing": %q}`, artifactID, missingID)},
+	}
+	request := httptest.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil).WithContext(context.Background())
+	responseRecorder := httptest.NewRecorder()
+	handler.Serve(responseRecorder, request)
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.JSONEq(t,
+		`{"data": {"event": {"__typename": "EiffelArtifactCreatedEvent", "meta": {"time": 1629449650361}}, "missing": null}}`,
+		responseRecorder.Body.String())
+}
+
+// Test that invalid requests and searches are rejected.
+func TestErrors(t *testing.T) {
+	handler := newHandler(t, mock_drivers.NewMockDatabase(gomock.NewController(t)))
+
+	responseRecorder := httptest.NewRecorder()
+	handler.Serve(responseRecorder, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	assert.Equal(t, http.StatusBadRequest, responseRecorder.Code)
+
+	response := do(t, handler, `{ events(search: "=value") { edges { cursor } } }`)
+	require.NotNil(t, response["errors"])
+	assert.Contains(t, fmt.Sprint(response["errors"]), "invalid search")
+
+	response = do(t, handler, `{ events(search: "{'data.name': {'$regex': 'x'}}") { edges { cursor } } }`)
+	assert.Contains(t, fmt.Sprint(response["errors"]), "invalid search: unsupported operator")
+
+	response = do(t, handler, `{ events(first: 0) { edges { cursor } } }`)
+	assert.Contains(t, fmt.Sprint(response["errors"]), "first must be between 1 and 1000")
+}
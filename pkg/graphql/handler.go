@@ -0,0 +1,212 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+
+	"github.com/graphql-go/graphql"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+// maxRequestSize is the largest request body accepted.
+const maxRequestSize = 1024 * 1024
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+	schema   graphql.Schema
+}
+
+// Get a new handler for the GraphQL endpoint.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) (*Handler, error) {
+	schema, err := NewSchema()
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		Config:   cfg,
+		Database: db,
+		Logger:   logger,
+		schema:   schema,
+	}, nil
+}
+
+// graphQLRequest is a GraphQL request as sent in the body of POST requests.
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Serve handles GET and POST requests against the /graphql endpoint.
+// To execute a GraphQL query from the query string or the request body.
+func (h *Handler) Serve(w http.ResponseWriter, r *http.Request) {
+	var request graphQLRequest
+	if r.Method == http.MethodPost {
+		body := http.MaxBytesReader(w, r.Body, maxRequestSize)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType == "application/graphql" {
+			query, err := io.ReadAll(body)
+			if err != nil {
+				responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+				return
+			}
+			request.Query = string(query)
+		} else if err := json.NewDecoder(body).Decode(&request); err != nil {
+			responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+			return
+		}
+	} else {
+		params := r.URL.Query()
+		request.Query = params.Get("query")
+		request.OperationName = params.Get("operationName")
+		if variables := params.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+				return
+			}
+		}
+	}
+	if request.Query == "" {
+		responses.RespondWithError(w, http.StatusBadRequest, "A query is required")
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  request.Query,
+		OperationName:  request.OperationName,
+		VariableValues: request.Variables,
+		Context:        withLoader(r.Context(), h.Database),
+	})
+	for _, err := range result.Errors {
+		h.Logger.Debugf("GraphQL error: %s", err.Message)
+	}
+	// As is customary for GraphQL, errors are reported in the
+	// response body and the status code is always 200.
+	responses.RespondWithJSON(w, http.StatusOK, result)
+}
+
+type loaderKey struct{}
+
+// loader fetches events by ID once per GraphQL request, since the same
+// events are often linked from many of the events in a response.
+type loader struct {
+	db     drivers.Database
+	mu     sync.Mutex
+	events map[string]drivers.EiffelEvent
+}
+
+// withLoader returns a context with a new loader for the database.
+func withLoader(ctx context.Context, db drivers.Database) context.Context {
+	return context.WithValue(ctx, loaderKey{}, &loader{db: db, events: map[string]drivers.EiffelEvent{}})
+}
+
+// loaderFromContext returns the loader of a GraphQL request.
+func loaderFromContext(ctx context.Context) *loader {
+	return ctx.Value(loaderKey{}).(*loader)
+}
+
+// add an event fetched by other means to the loader.
+func (l *loader) add(event drivers.EiffelEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events[event.ID()] = event
+}
+
+// get an event by ID. Returns nil if there's no such event.
+func (l *loader) get(ctx context.Context, id string) (drivers.EiffelEvent, error) {
+	l.mu.Lock()
+	event, ok := l.events[id]
+	l.mu.Unlock()
+	if ok {
+		return event, nil
+	}
+	event, err := l.db.GetEventByID(ctx, id)
+	if err != nil {
+		if !errors.Is(err, drivers.ErrNotFound) {
+			return nil, err
+		}
+		event = nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events[id] = event
+	return event, nil
+}
+
+// getMany gets the events with the given IDs, fetching the ones that haven't
+// been fetched before in a single batch. Missing events are left out.
+func (l *loader) getMany(ctx context.Context, ids []string) ([]drivers.EiffelEvent, error) {
+	l.mu.Lock()
+	var unknown []string
+	seen := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		_, known := l.events[id]
+		_, duplicate := seen[id]
+		if !known && !duplicate {
+			unknown = append(unknown, id)
+		}
+		seen[id] = struct{}{}
+	}
+	l.mu.Unlock()
+	if len(unknown) > 0 {
+		fetched, err := l.db.GetEventsByIDs(ctx, unknown)
+		if err != nil {
+			return nil, err
+		}
+		l.mu.Lock()
+		for _, id := range unknown {
+			l.events[id] = nil
+		}
+		for _, event := range fetched {
+			l.events[event.ID()] = event
+		}
+		l.mu.Unlock()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	events := make([]drivers.EiffelEvent, 0, len(ids))
+	for _, id := range ids {
+		if event := l.events[id]; event != nil {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// findEvent resolves an event by ID, returning nil if there's no such event.
+func findEvent(ctx context.Context, id string) (interface{}, error) {
+	event, err := loaderFromContext(ctx).get(ctx, id)
+	if err != nil || event == nil {
+		// Return an untyped nil so the field resolves to null.
+		return nil, err
+	}
+	return event, nil
+}
@@ -0,0 +1,526 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// graphql serves the events in a database through a GraphQL API modeled
+// after the Eiffel GraphQL API. Every Eiffel event type has an object type
+// implementing the EiffelEvent interface and a query field returning a
+// Relay style connection, e.g. artifactCreated for EiffelArtifactCreatedEvent.
+// Links are resolved to the linked events so that they can be selected by type:
+//
+//	{
+//	  confidenceLevelModified(search: "{'data.name': 'stable'}", first: 10) {
+//	    edges {
+//	      node {
+//	        data { value }
+//	        links(type: "SUBJECT") {
+//	          ... on EiffelArtifactCreatedEvent { data { identity } }
+//	        }
+//	      }
+//	    }
+//	  }
+//	}
+//
+// The types of the data objects are generated from the latest version of
+// each event type in the Eiffel SDK, so fields only found in older versions
+// of an event type can't be selected. The search argument takes either
+// MongoDB style JSON conditions, like the Eiffel GraphQL API, or the query
+// language of the REST API's /events endpoint; see parseSearch.
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/eiffel-community/eiffelevents-sdk-go"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+const (
+	// defaultFirst is the default number of events per connection page.
+	defaultFirst = 100
+	// maxFirst is the largest number of events per connection page.
+	maxFirst = 1000
+	// cursorPrefix is prepended to the offsets in cursors before they're encoded.
+	cursorPrefix = "offset:"
+)
+
+// eventTypes are the Eiffel event types that get their own object types,
+// with the SDK structs of their latest versions that the types of their data
+// objects are generated from. Events of other types are returned as
+// GenericEiffelEvent.
+var eventTypes = []struct {
+	name  string
+	event interface{}
+}{
+	{"EiffelActivityCanceledEvent", eiffelevents.ActivityCanceledV3{}},
+	{"EiffelActivityFinishedEvent", eiffelevents.ActivityFinishedV3{}},
+	{"EiffelActivityStartedEvent", eiffelevents.ActivityStartedV4{}},
+	{"EiffelActivityTriggeredEvent", eiffelevents.ActivityTriggeredV4{}},
+	{"EiffelAnnouncementPublishedEvent", eiffelevents.AnnouncementPublishedV3{}},
+	{"EiffelArtifactCreatedEvent", eiffelevents.ArtifactCreatedV3{}},
+	{"EiffelArtifactPublishedEvent", eiffelevents.ArtifactPublishedV3{}},
+	{"EiffelArtifactReusedEvent", eiffelevents.ArtifactReusedV3{}},
+	{"EiffelCompositionDefinedEvent", eiffelevents.CompositionDefinedV3{}},
+	{"EiffelConfidenceLevelModifiedEvent", eiffelevents.ConfidenceLevelModifiedV3{}},
+	{"EiffelEnvironmentDefinedEvent", eiffelevents.EnvironmentDefinedV3{}},
+	{"EiffelFlowContextDefinedEvent", eiffelevents.FlowContextDefinedV3{}},
+	{"EiffelIssueDefinedEvent", eiffelevents.IssueDefinedV3{}},
+	{"EiffelIssueVerifiedEvent", eiffelevents.IssueVerifiedV4{}},
+	{"EiffelSourceChangeCreatedEvent", eiffelevents.SourceChangeCreatedV4{}},
+	{"EiffelSourceChangeSubmittedEvent", eiffelevents.SourceChangeSubmittedV3{}},
+	{"EiffelTestCaseCanceledEvent", eiffelevents.TestCaseCanceledV3{}},
+	{"EiffelTestCaseFinishedEvent", eiffelevents.TestCaseFinishedV3{}},
+	{"EiffelTestCaseStartedEvent", eiffelevents.TestCaseStartedV3{}},
+	{"EiffelTestCaseTriggeredEvent", eiffelevents.TestCaseTriggeredV3{}},
+	{"EiffelTestExecutionRecipeCollectionCreatedEvent", eiffelevents.TestExecutionRecipeCollectionCreatedV4{}},
+	{"EiffelTestSuiteFinishedEvent", eiffelevents.TestSuiteFinishedV3{}},
+	{"EiffelTestSuiteStartedEvent", eiffelevents.TestSuiteStartedV3{}},
+}
+
+// connection is a page of events in a Relay style connection.
+type connection struct {
+	Edges    []edge   `json:"edges"`
+	PageInfo pageInfo `json:"pageInfo"`
+}
+
+type edge struct {
+	Node   drivers.EiffelEvent `json:"node"`
+	Cursor string              `json:"cursor"`
+}
+
+type pageInfo struct {
+	HasNextPage     bool   `json:"hasNextPage"`
+	HasPreviousPage bool   `json:"hasPreviousPage"`
+	StartCursor     string `json:"startCursor"`
+	EndCursor       string `json:"endCursor"`
+}
+
+// jsonScalar passes any JSON value through as is.
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Any JSON value.",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: parseLiteral,
+})
+
+// parseLiteral converts a literal value in a query to its Go value.
+func parseLiteral(value ast.Value) interface{} {
+	switch value := value.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.IntValue:
+		i, _ := strconv.ParseInt(value.Value, 10, 64)
+		return i
+	case *ast.FloatValue:
+		f, _ := strconv.ParseFloat(value.Value, 64)
+		return f
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(value.Values))
+		for _, item := range value.Values {
+			list = append(list, parseLiteral(item))
+		}
+		return list
+	case *ast.ObjectValue:
+		object := make(map[string]interface{}, len(value.Fields))
+		for _, field := range value.Fields {
+			object[field.Name.Value] = parseLiteral(field.Value)
+		}
+		return object
+	default:
+		return nil
+	}
+}
+
+// NewSchema builds the GraphQL schema.
+func NewSchema() (graphql.Schema, error) {
+	sourceType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "EiffelSource",
+		Description: "Where an event was sent from.",
+		Fields: graphql.Fields{
+			"domainId":   &graphql.Field{Type: graphql.String},
+			"host":       &graphql.Field{Type: graphql.String},
+			"name":       &graphql.Field{Type: graphql.String},
+			"serializer": &graphql.Field{Type: graphql.String},
+			"uri":        &graphql.Field{Type: graphql.String},
+		},
+	})
+	metaType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "EiffelMeta",
+		Description: "The meta object common to all events.",
+		Fields: graphql.Fields{
+			"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"type":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"version":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"time":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Description: "Milliseconds since the epoch."},
+			"tags":     &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+			"source":   &graphql.Field{Type: sourceType},
+			"security": &graphql.Field{Type: jsonScalar},
+		},
+	})
+
+	objectTypes := make(map[string]*graphql.Object, len(eventTypes))
+	var genericType *graphql.Object
+	// The fields are thunks since the event interface, the link type and
+	// the event fields refer to each other. The data field isn't part of
+	// the interface since its type depends on the event type.
+	var eventFields graphql.FieldsThunk
+	eventInterface := graphql.NewInterface(graphql.InterfaceConfig{
+		Name:        "EiffelEvent",
+		Description: "An Eiffel event of any type.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return eventFields()
+		}),
+		ResolveType: func(p graphql.ResolveTypeParams) *graphql.Object {
+			event, _ := p.Value.(drivers.EiffelEvent)
+			if objectType, ok := objectTypes[event.Type()]; ok {
+				return objectType
+			}
+			return genericType
+		},
+	})
+	linkType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "EiffelLink",
+		Description: "A link from an event to another event.",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"type":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+				"target":   &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+				"domainId": &graphql.Field{Type: graphql.String},
+				"event": &graphql.Field{
+					Type:        eventInterface,
+					Description: "The target event, or null if it isn't in this event repository.",
+					Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+						return findEvent(p.Context, p.Source.(drivers.Link).Target)
+					},
+				},
+			}
+		}),
+	})
+	eventFields = graphql.FieldsThunk(func() graphql.Fields {
+		return graphql.Fields{
+			"meta": &graphql.Field{
+				Type: graphql.NewNonNull(metaType),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					meta, _ := p.Source.(drivers.EiffelEvent).Field("meta")
+					return meta, nil
+				},
+			},
+			"links": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(eventInterface))),
+				Description: "The linked events, optionally only those of a link type. Links to events that aren't in this event repository are left out.",
+				Args: graphql.FieldConfigArgument{
+					"type": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					linkType, _ := p.Args["type"].(string)
+					var ids []string
+					for _, l := range p.Source.(drivers.EiffelEvent).Links() {
+						if linkType == "" || l.Type == linkType {
+							ids = append(ids, l.Target)
+						}
+					}
+					targets, err := loaderFromContext(p.Context).getMany(p.Context, ids)
+					if err != nil {
+						return nil, err
+					}
+					return targets, nil
+				},
+			},
+			"linkRefs": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(linkType))),
+				Description: "The links as they are in the event, including those to events in other event repositories.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					links := p.Source.(drivers.EiffelEvent).Links()
+					if selectsField(p.Info, "event") {
+						// Fetch all targets in one batch instead of one at
+						// a time when resolving the event of each link.
+						ids := make([]string, 0, len(links))
+						for _, l := range links {
+							ids = append(ids, l.Target)
+						}
+						if _, err := loaderFromContext(p.Context).getMany(p.Context, ids); err != nil {
+							return nil, err
+						}
+					}
+					return links, nil
+				},
+			},
+		}
+	})
+	newObject := func(name string, description string, data graphql.Output) *graphql.Object {
+		return graphql.NewObject(graphql.ObjectConfig{
+			Name:        name,
+			Description: description,
+			Interfaces:  []*graphql.Interface{eventInterface},
+			Fields: graphql.FieldsThunk(func() graphql.Fields {
+				fields := eventFields()
+				fields["data"] = &graphql.Field{Type: data, Description: "The data object."}
+				return fields
+			}),
+			IsTypeOf: func(p graphql.IsTypeOfParams) bool {
+				_, ok := p.Value.(drivers.EiffelEvent)
+				return ok
+			},
+		})
+	}
+	types := make([]graphql.Type, 0, len(eventTypes)+1)
+	for _, eventType := range eventTypes {
+		data := dataType(strings.TrimSuffix(strings.TrimPrefix(eventType.name, "Eiffel"), "Event"), eventType.event)
+		objectTypes[eventType.name] = newObject(eventType.name, fmt.Sprintf("An %s.", eventType.name), data)
+		types = append(types, objectTypes[eventType.name])
+	}
+	genericType = newObject("GenericEiffelEvent", "An event of a type without an object type of its own.", jsonScalar)
+	types = append(types, genericType)
+
+	pageInfoType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PageInfo",
+		Fields: graphql.Fields{
+			"hasNextPage":     &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"hasPreviousPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"startCursor":     &graphql.Field{Type: graphql.String},
+			"endCursor":       &graphql.Field{Type: graphql.String},
+		},
+	})
+	connectionArgs := graphql.FieldConfigArgument{
+		"search": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "MongoDB style JSON conditions, or conditions in the query language of the REST API's /events endpoint.",
+		},
+		"first": &graphql.ArgumentConfig{
+			Type:         graphql.Int,
+			DefaultValue: defaultFirst,
+			Description:  fmt.Sprintf("The number of events to return, at most %d.", maxFirst),
+		},
+		"after": &graphql.ArgumentConfig{
+			Type:        graphql.String,
+			Description: "Return the events after the one with this cursor.",
+		},
+	}
+	connectionField := func(name string, nodeType graphql.Output, eventType string) *graphql.Field {
+		edgeType := graphql.NewObject(graphql.ObjectConfig{
+			Name: name + "Edge",
+			Fields: graphql.Fields{
+				"node":   &graphql.Field{Type: graphql.NewNonNull(nodeType)},
+				"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			},
+		})
+		connectionType := graphql.NewObject(graphql.ObjectConfig{
+			Name: name + "Connection",
+			Fields: graphql.Fields{
+				"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(edgeType)))},
+				"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+			},
+		})
+		return &graphql.Field{
+			Type: graphql.NewNonNull(connectionType),
+			Args: connectionArgs,
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return findEvents(p, eventType)
+			},
+		}
+	}
+
+	queryFields := graphql.Fields{
+		"event": &graphql.Field{
+			Type:        eventInterface,
+			Description: "The event with an ID, or null if there's no such event.",
+			Args: graphql.FieldConfigArgument{
+				"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return findEvent(p.Context, p.Args["id"].(string))
+			},
+		},
+		"events": connectionField("EiffelEvent", eventInterface, ""),
+	}
+	for _, eventType := range eventTypes {
+		queryFields[fieldName(eventType.name)] = connectionField(eventType.name, objectTypes[eventType.name], eventType.name)
+	}
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name:   "Query",
+			Fields: queryFields,
+		}),
+		Types: types,
+	})
+}
+
+// fieldName returns the name of the query field for an event type,
+// e.g. artifactCreated for EiffelArtifactCreatedEvent.
+func fieldName(eventType string) string {
+	name := strings.TrimSuffix(strings.TrimPrefix(eventType, "Eiffel"), "Event")
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// selectsField returns true if the query selects a field by name in the
+// field being resolved, directly or through fragments.
+func selectsField(info graphql.ResolveInfo, name string) bool {
+	for _, field := range info.FieldASTs {
+		if selectionSetHasField(info, field.SelectionSet, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionSetHasField returns true if a selection set selects a field by
+// name, directly or through fragments. Validation has already rejected
+// fragments that spread themselves.
+func selectionSetHasField(info graphql.ResolveInfo, selectionSet *ast.SelectionSet, name string) bool {
+	if selectionSet == nil {
+		return false
+	}
+	for _, selection := range selectionSet.Selections {
+		switch selection := selection.(type) {
+		case *ast.Field:
+			if selection.Name != nil && selection.Name.Value == name {
+				return true
+			}
+		case *ast.InlineFragment:
+			if selectionSetHasField(info, selection.SelectionSet, name) {
+				return true
+			}
+		case *ast.FragmentSpread:
+			if selection.Name == nil {
+				continue
+			}
+			fragment, ok := info.Fragments[selection.Name.Value].(*ast.FragmentDefinition)
+			if ok && selectionSetHasField(info, fragment.SelectionSet, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findEvents resolves a connection field by querying the database for a page of events.
+func findEvents(p graphql.ResolveParams, eventType string) (interface{}, error) {
+	search, _ := p.Args["search"].(string)
+	conditions, err := parseSearch(search)
+	if err != nil {
+		return nil, fmt.Errorf("invalid search: %w", err)
+	}
+	if eventType != "" {
+		conditions = append(conditions, query.Condition{Field: "meta.type", Op: "=", Value: eventType})
+	}
+	first, _ := p.Args["first"].(int)
+	if first < 1 || first > maxFirst {
+		return nil, fmt.Errorf("first must be between 1 and %d", maxFirst)
+	}
+	offset := 0
+	if after, _ := p.Args["after"].(string); after != "" {
+		if offset, err = decodeCursor(after); err != nil {
+			return nil, err
+		}
+		offset++
+	}
+
+	// Pages are aligned to multiples of the page size. That's always the
+	// case when paging forward with the same page size, and otherwise the
+	// requested events span two pages, of which the unrequested events
+	// are skipped.
+	pageNo := int32(offset/first + 1)
+	events, total, err := getPage(p.Context, conditions, pageNo, first, requests.CountEstimated)
+	if err != nil {
+		return nil, err
+	}
+	skip := offset % first
+	if skip > 0 && len(events) == first {
+		next, _, err := getPage(p.Context, conditions, pageNo+1, first, requests.CountNone)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, next...)
+	}
+	if skip > len(events) {
+		skip = len(events)
+	}
+	events = events[skip:]
+	if len(events) > first {
+		events = events[:first]
+	}
+
+	result := connection{
+		Edges: make([]edge, 0, len(events)),
+		PageInfo: pageInfo{
+			HasNextPage:     total > int64(offset+len(events)),
+			HasPreviousPage: offset > 0,
+		},
+	}
+	for i, event := range events {
+		loaderFromContext(p.Context).add(event)
+		result.Edges = append(result.Edges, edge{Node: event, Cursor: encodeCursor(offset + i)})
+	}
+	if len(result.Edges) > 0 {
+		result.PageInfo.StartCursor = result.Edges[0].Cursor
+		result.PageInfo.EndCursor = result.Edges[len(result.Edges)-1].Cursor
+	}
+	return result, nil
+}
+
+// getPage gets a page of the events matching the conditions and the total
+// number of matching events, counted according to count.
+func getPage(ctx context.Context, conditions []query.Condition, pageNo int32, pageSize int, count string) ([]drivers.EiffelEvent, int64, error) {
+	stream, total, err := loaderFromContext(ctx).db.GetEvents(ctx, requests.MultipleEventsRequest{
+		PageNo:     pageNo,
+		PageSize:   int32(pageSize),
+		Count:      count,
+		Conditions: conditions,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	events, err := drivers.Collect(ctx, stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// encodeCursor returns the opaque cursor of the event at an offset in a connection.
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// decodeCursor returns the offset of the event that a cursor points to.
+func decodeCursor(cursor string) (int, error) {
+	invalid := errors.New("invalid cursor")
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), cursorPrefix) {
+		return 0, invalid
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, invalid
+	}
+	return offset, nil
+}
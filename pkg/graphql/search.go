@@ -0,0 +1,215 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+)
+
+// jsonOperators translates the comparison operators of JSON searches to the
+// operators of query conditions.
+var jsonOperators = map[string]string{
+	"$eq": "=", "$ne": "!=", "$gt": ">", "$gte": ">=", "$lt": "<", "$lte": "<=",
+}
+
+// parseSearch parses the search argument of connection fields. It's either
+// a query in the language of the REST API's /events endpoint or, like in the
+// Eiffel GraphQL API, a JSON object with MongoDB style conditions:
+//
+//	{"data.name": "stable", "meta.time": {"$gte": 1629449650361}}
+//
+// Only $eq, $ne, $gt, $gte, $lt, $lte, $exists and $and are supported.
+// Like in the Eiffel GraphQL API, strings may also be single quoted.
+func parseSearch(search string) ([]query.Condition, error) {
+	if !strings.HasPrefix(strings.TrimSpace(search), "{") {
+		return query.ParseConditions(search)
+	}
+	search, err := singleQuotesToJSON(search)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := decodeSearch(search)
+	if err != nil {
+		return nil, err
+	}
+	return jsonConditions(filter)
+}
+
+// singleQuotesToJSON rewrites the single quoted strings in a JSON search to
+// double quoted ones, leaving double quoted strings, and any apostrophes in
+// them, as they are. In single quoted strings \' is an apostrophe.
+func singleQuotesToJSON(search string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(search); i++ {
+		c := search[i]
+		if c != '"' && c != '\'' {
+			b.WriteByte(c)
+			continue
+		}
+		end := stringEnd(search, i)
+		if end < 0 {
+			return "", errors.New("unterminated string")
+		}
+		if c == '"' {
+			b.WriteString(search[i : end+1])
+			i = end
+			continue
+		}
+		b.WriteByte('"')
+		for j := i + 1; j < end; j++ {
+			switch {
+			case search[j] == '\\' && search[j+1] == '\'':
+				b.WriteByte('\'')
+				j++
+			case search[j] == '\\':
+				b.WriteString(search[j : j+2])
+				j++
+			case search[j] == '"':
+				b.WriteString(`\"`)
+			default:
+				b.WriteByte(search[j])
+			}
+		}
+		b.WriteByte('"')
+		i = end
+	}
+	return b.String(), nil
+}
+
+// stringEnd returns the index of the quote ending the string starting at
+// start, skipping escaped characters, or -1 if the string isn't terminated.
+func stringEnd(search string, start int) int {
+	for i := start + 1; i < len(search); i++ {
+		switch search[i] {
+		case '\\':
+			i++
+		case search[start]:
+			return i
+		}
+	}
+	return -1
+}
+
+// decodeSearch decodes a JSON search, keeping numbers as they're written.
+func decodeSearch(search string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(strings.NewReader(search))
+	decoder.UseNumber()
+	var filter map[string]interface{}
+	if err := decoder.Decode(&filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// jsonConditions returns the conditions of a JSON search, in the order of
+// their fields.
+func jsonConditions(filter map[string]interface{}) ([]query.Condition, error) {
+	fields := make([]string, 0, len(filter))
+	for field := range filter {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var conditions []query.Condition
+	for _, field := range fields {
+		value := filter[field]
+		if field == "$and" {
+			clauses, ok := value.([]interface{})
+			if !ok {
+				return nil, errors.New("$and must be an array of objects")
+			}
+			for _, clause := range clauses {
+				object, ok := clause.(map[string]interface{})
+				if !ok {
+					return nil, errors.New("$and must be an array of objects")
+				}
+				more, err := jsonConditions(object)
+				if err != nil {
+					return nil, err
+				}
+				conditions = append(conditions, more...)
+			}
+			continue
+		}
+		if strings.HasPrefix(field, "$") {
+			return nil, fmt.Errorf("unsupported operator %q", field)
+		}
+		operators, ok := value.(map[string]interface{})
+		if !ok {
+			operators = map[string]interface{}{"$eq": value}
+		}
+		more, err := fieldConditions(field, operators)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, more...)
+	}
+	return conditions, nil
+}
+
+// fieldConditions returns the conditions of the operators on a field in
+// a JSON search, in the order of the operators.
+func fieldConditions(field string, operators map[string]interface{}) ([]query.Condition, error) {
+	names := make([]string, 0, len(operators))
+	for name := range operators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	conditions := make([]query.Condition, 0, len(names))
+	for _, name := range names {
+		operand := operators[name]
+		if name == "$exists" {
+			exists, ok := operand.(bool)
+			if !ok {
+				return nil, fmt.Errorf("$exists of %q must be a boolean", field)
+			}
+			conditions = append(conditions, query.Condition{
+				Field: field, Op: "exists", Value: strconv.FormatBool(exists), TypeConv: "bool",
+			})
+			continue
+		}
+		op, ok := jsonOperators[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported operator %q", name)
+		}
+		condition := query.Condition{Field: field, Op: op}
+		switch operand := operand.(type) {
+		case string:
+			condition.Value = operand
+		case bool:
+			condition.Value = strconv.FormatBool(operand)
+			condition.TypeConv = "bool"
+		case json.Number:
+			condition.Value = operand.String()
+			condition.TypeConv = "double"
+			if _, err := operand.Int64(); err == nil {
+				condition.TypeConv = "int"
+			}
+		default:
+			return nil, fmt.Errorf("unsupported value of %q: only strings, numbers and booleans can be compared", field)
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
@@ -22,22 +22,74 @@ import (
 
 	"github.com/eiffel-community/eiffel-goer/internal/config"
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/internal/signature"
+	"github.com/eiffel-community/eiffel-goer/internal/watch"
+	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/activities"
+	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/admin"
+	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/artifacts"
+	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/changes"
 	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/events"
 	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/search"
+	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/subscriptions"
+	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/testsuites"
+	"github.com/eiffel-community/eiffel-goer/pkg/v1/handlers/webhook"
 )
 
 type V1Application struct {
 	Database drivers.Database
 	Config   config.Config
 	Logger   *log.Entry
+	Verifier *signature.Verifier
+	// Ingester and WebhookSecrets are set if the webhook should be served.
+	Ingester       *ingest.Ingester
+	WebhookSecrets ingest.Secrets
 }
 
 // Add routes for all handlers to the router.
 func (app *V1Application) AddRoutes(router *mux.Router) {
+	// The event stream and the subscriptions share one watcher of new events.
+	hub := watch.NewHub(app.Database, app.Logger)
 	eventHandler := events.Get(app.Config, app.Database, app.Logger)
+	eventHandler.Verifier = app.Verifier
+	eventHandler.Hub = hub
+	activityHandler := activities.Get(app.Config, app.Database, app.Logger)
+	artifactHandler := artifacts.Get(app.Config, app.Database, app.Logger)
+	changeHandler := changes.Get(app.Config, app.Database, app.Logger)
 	searchHandler := search.Get(app.Config, app.Database, app.Logger)
+	subscriptionHandler := subscriptions.Get(app.Config, app.Database, app.Logger)
+	subscriptionHandler.Hub = hub
+	testSuiteHandler := testsuites.Get(app.Config, app.Database, app.Logger)
 
 	router.HandleFunc("/events", eventHandler.ReadAll).Methods("GET", "OPTIONS")
+	router.HandleFunc("/events/stream", eventHandler.Stream).Methods("GET", "OPTIONS")
+	router.HandleFunc("/events/batch", eventHandler.ReadBatch).Methods("POST", "OPTIONS")
 	router.HandleFunc("/events/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}", eventHandler.Read).Methods("GET", "OPTIONS")
+	router.HandleFunc("/events/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}/linkedBy", eventHandler.LinkedBy).Methods("GET", "OPTIONS")
+	router.HandleFunc("/events/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}/versions", eventHandler.Versions).Methods("GET", "OPTIONS")
+	router.HandleFunc("/events/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}/verify", eventHandler.Verify).Methods("GET", "OPTIONS")
+	router.HandleFunc("/activities", activityHandler.ReadAll).Methods("GET", "OPTIONS")
+	router.HandleFunc("/activities/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}", activityHandler.Read).Methods("GET", "OPTIONS")
+	router.HandleFunc("/artifacts", artifactHandler.ReadAll).Methods("GET", "OPTIONS")
+	router.HandleFunc("/artifacts/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}/confidence", artifactHandler.Confidence).Methods("GET", "OPTIONS")
+	router.HandleFunc("/changes/lookup", changeHandler.Lookup).Methods("GET", "OPTIONS")
 	router.HandleFunc("/search/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}", searchHandler.UpstreamDownstream).Methods("POST", "OPTIONS")
+	router.HandleFunc("/testsuites/{id:[a-fA-F0-9]{8}-[a-fA-F0-9]{4}-4[a-fA-F0-9]{3}-[8|9|aA|bB][a-fA-F0-9]{3}-[a-fA-F0-9]{12}}/summary", testSuiteHandler.Summary).Methods("GET", "OPTIONS")
+	router.HandleFunc("/ws", subscriptionHandler.Connect).Methods("GET")
+
+	if app.WebhookSecrets != nil {
+		webhookHandler := webhook.Get(app.Config, app.Database, app.Logger)
+		webhookHandler.Ingester = app.Ingester
+		webhookHandler.Secrets = app.WebhookSecrets
+		router.HandleFunc("/ingest/webhook", webhookHandler.Ingest).Methods("POST", "OPTIONS")
+	}
+	if app.Config.EnableAdmin() {
+		adminHandler := admin.Get(app.Config, app.Database, app.Logger)
+		router.HandleFunc("/admin/integrity", adminHandler.Integrity).Methods("GET", "OPTIONS")
+		router.HandleFunc("/admin/duplicates", adminHandler.Duplicates).Methods("GET", "OPTIONS")
+		router.HandleFunc("/admin/retention", adminHandler.Retention).Methods("GET", "OPTIONS")
+		if app.Config.AdminToken() != "" {
+			router.HandleFunc("/admin/duplicates", adminHandler.DeleteDuplicates).Methods("DELETE")
+		}
+	}
 }
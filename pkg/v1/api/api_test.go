@@ -23,6 +23,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -31,6 +32,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
 	"github.com/eiffel-community/eiffel-goer/pkg/application"
 	"github.com/eiffel-community/eiffel-goer/test/mock_config"
 	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
@@ -57,41 +59,128 @@ func TestRoutes(t *testing.T) {
 	require.NoError(t, json.Unmarshal(activityJSON, &eventMap))
 
 	eventID := "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"
+	missingID := "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f"
+	var count int64 = 1
+	// Have to use 'gomock.Any()' for the context as mux adds values to the request context.
 	tests := []struct {
 		name       string
 		url        string
 		httpMethod string
+		body       string
+		expect     func(mockDB *mock_drivers.MockDatabase)
 		statusCode int
 	}{
-		{name: "EventsRead", httpMethod: http.MethodGet, url: "/v1/events/" + eventID, statusCode: http.StatusOK},
-		{name: "EventsReadAll", httpMethod: http.MethodGet, url: "/v1/events?meta.type=EiffelArtifactCreatedEvent", statusCode: http.StatusOK},
-		{name: "SearchUpstreamDownstream", httpMethod: http.MethodPost, url: "/v1/search/" + eventID, statusCode: http.StatusNotImplemented},
+		{
+			name: "EventsRead", httpMethod: http.MethodGet, url: "/v1/events/" + eventID, statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEventByID(gomock.Any(), eventID).Return(eventMap, nil)
+			},
+		},
+		{
+			name: "EventsReadAll", httpMethod: http.MethodGet, url: "/v1/events?meta.type=EiffelArtifactCreatedEvent", statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return(drivers.NewSliceStream([]drivers.EiffelEvent{eventMap}), count, nil)
+			},
+		},
+		{
+			name: "EventsLinkedBy", httpMethod: http.MethodGet, url: "/v1/events/" + eventID + "/linkedBy?type=CAUSE", statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetLinkingEvents(gomock.Any(), []string{eventID}, []string{"CAUSE"}).Return(nil, nil)
+			},
+		},
+		{
+			name: "EventsVersions", httpMethod: http.MethodGet, url: "/v1/events/" + missingID + "/versions", statusCode: http.StatusNotFound,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEventByID(gomock.Any(), missingID).Return(nil, drivers.ErrNotFound)
+			},
+		},
+		{
+			name: "EventsVerify", httpMethod: http.MethodGet, url: "/v1/events/" + missingID + "/verify", statusCode: http.StatusNotFound,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEventByID(gomock.Any(), missingID).Return(nil, drivers.ErrNotFound)
+			},
+		},
+		{
+			name: "EventsReadBatch", httpMethod: http.MethodPost, url: "/v1/events/batch", body: `["` + eventID + `"]`, statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEventsByIDs(gomock.Any(), []string{eventID}).Return([]drivers.EiffelEvent{eventMap}, nil)
+			},
+		},
+		{
+			name: "ActivitiesRead", httpMethod: http.MethodGet, url: "/v1/activities/" + eventID, statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEventByID(gomock.Any(), eventID).Return(eventMap, nil)
+				mockDB.EXPECT().GetLinkingEvents(gomock.Any(), []string{eventID}, []string{"ACTIVITY_EXECUTION"}).Return(nil, nil)
+			},
+		},
+		{
+			name: "ActivitiesReadAll", httpMethod: http.MethodGet, url: "/v1/activities?name=build", statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return(drivers.NewSliceStream([]drivers.EiffelEvent{eventMap}), int64(-1), nil)
+				mockDB.EXPECT().GetLinkingEvents(gomock.Any(), []string{eventMap.ID()}, []string{"ACTIVITY_EXECUTION"}).Return(nil, nil)
+			},
+		},
+		{
+			// The event isn't a test suite.
+			name: "TestSuitesSummary", httpMethod: http.MethodGet, url: "/v1/testsuites/" + eventID + "/summary", statusCode: http.StatusNotFound,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEventByID(gomock.Any(), eventID).Return(eventMap, nil)
+			},
+		},
+		{name: "ArtifactsReadAll", httpMethod: http.MethodGet, url: "/v1/artifacts", statusCode: http.StatusBadRequest},
+		{
+			// The event isn't an artifact.
+			name: "ArtifactsConfidence", httpMethod: http.MethodGet, url: "/v1/artifacts/" + eventID + "/confidence", statusCode: http.StatusNotFound,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEventByID(gomock.Any(), eventID).Return(eventMap, nil)
+			},
+		},
+		{name: "ChangesLookup", httpMethod: http.MethodGet, url: "/v1/changes/lookup", statusCode: http.StatusBadRequest},
+		{
+			name: "SearchUpstreamDownstream", httpMethod: http.MethodPost, url: "/v1/search/" + eventID, statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().UpstreamDownstreamSearch(gomock.Any(), eventID, gomock.Any()).Return(drivers.SearchResult{}, nil)
+			},
+		},
+		{name: "IngestWebhook", httpMethod: http.MethodPost, url: "/v1/ingest/webhook", body: "[]", statusCode: http.StatusUnauthorized},
+		{
+			// The integrity check reads all events twice.
+			name: "AdminIntegrity", httpMethod: http.MethodGet, url: "/v1/admin/integrity", statusCode: http.StatusOK,
+			expect: func(mockDB *mock_drivers.MockDatabase) {
+				mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return(drivers.NewSliceStream([]drivers.EiffelEvent{eventMap}), int64(-1), nil)
+				mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return(drivers.NewSliceStream([]drivers.EiffelEvent{eventMap}), int64(-1), nil)
+			},
+		},
+		{name: "AdminDuplicates", httpMethod: http.MethodGet, url: "/v1/admin/duplicates", statusCode: http.StatusNotImplemented},
+		{name: "AdminDeleteDuplicates", httpMethod: http.MethodDelete, url: "/v1/admin/duplicates", statusCode: http.StatusUnauthorized},
+		{name: "AdminRetention", httpMethod: http.MethodGet, url: "/v1/admin/retention", statusCode: http.StatusOK},
 	}
 
-	ctrl := gomock.NewController(t)
-	mockCfg := mock_config.NewMockConfig(ctrl)
-	mockDB := mock_drivers.NewMockDatabase(ctrl)
-
-	mockCfg.EXPECT().DBConnectionString().Return("").AnyTimes()
-	mockCfg.EXPECT().APIPort().Return(":8080").AnyTimes()
-	var count int64 = 1
-
-	// Have to use 'gomock.Any()' for the context as mux adds values to the request context.
-	mockDB.EXPECT().GetEventByID(gomock.Any(), eventID).Return(eventMap, nil)
-	mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return([]drivers.EiffelEvent{eventMap}, count, nil)
-	// Disabled as SearchUpstreamDownstream is not yet implemented.
-	// mockDB.EXPECT().UpstreamDownstreamSearch(gomock.Any(), "id").Return([]drivers.EiffelEvent{}, nil)
-
 	for _, testCase := range tests {
 		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCfg := mock_config.NewMockConfig(ctrl)
+			mockDB := mock_drivers.NewMockDatabase(ctrl)
+
+			mockCfg.EXPECT().DBConnectionString().Return("").AnyTimes()
+			mockCfg.EXPECT().APIPort().Return(":8080").AnyTimes()
+			mockCfg.EXPECT().EnableAdmin().Return(true).AnyTimes()
+			mockCfg.EXPECT().AdminToken().Return("s3cr3t").AnyTimes()
+			mockCfg.EXPECT().AllowedOrigins().Return(nil).AnyTimes()
+			mockCfg.EXPECT().RetentionPolicy().Return("").AnyTimes()
+			if testCase.expect != nil {
+				testCase.expect(mockDB)
+			}
+
 			ctx := context.Background()
 			app, err := application.Get(ctx, mockCfg, log.NewEntry(log.New()))
 			assert.NoError(t, err)
 			app.Database = mockDB
+			app.WebhookSecrets = ingest.Secrets{"jenkins": "s3cr3t"}
 			app.LoadV1Routes()
 
 			responseRecorder := httptest.NewRecorder()
-			request := httptest.NewRequest(testCase.httpMethod, testCase.url, nil)
+			request := httptest.NewRequest(testCase.httpMethod, testCase.url, strings.NewReader(testCase.body))
 
 			app.Router.ServeHTTP(responseRecorder, request)
 			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
@@ -0,0 +1,99 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// activities implements the /activities endpoints with the computed
+// timelines of activities, for e.g. build duration trend charts.
+package activities
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+}
+
+// Get a new handler for the activities endpoints.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
+	return &Handler{
+		cfg, db, logger,
+	}
+}
+
+// multiResponse is the response from the activities endpoint.
+type multiResponse struct {
+	PageNo   int32              `json:"pageNo"`
+	PageSize int32              `json:"pageSize"`
+	Items    []drivers.Activity `json:"items"`
+}
+
+// Read handles GET requests against the /activities/{id} endpoint.
+// To get the timeline of the activity triggered by an EiffelActivityTriggeredEvent.
+func (h *Handler) Read(w http.ResponseWriter, r *http.Request) {
+	activity, err := drivers.GetActivity(r.Context(), h.Database, mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+			return
+		}
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, activity)
+}
+
+// ReadAll handles GET requests against the /activities endpoint.
+// To get the timelines of the activities with a name and within a time range.
+func (h *Handler) ReadAll(w http.ResponseWriter, r *http.Request) {
+	request := requests.ActivitiesRequest{
+		PageNo:   1,
+		PageSize: 500,
+	}
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	if request.PageNo < 1 {
+		responses.RespondWithError(w, http.StatusBadRequest, "PageNo must be a positive integer")
+		return
+	}
+	if request.PageSize < 0 {
+		responses.RespondWithError(w, http.StatusBadRequest, "PageSize must be a positive integer")
+		return
+	}
+	activities, err := drivers.GetActivities(r.Context(), h.Database, request)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, multiResponse{request.PageNo, request.PageSize, activities})
+}
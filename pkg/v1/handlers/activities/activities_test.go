@@ -0,0 +1,89 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package activities
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+var (
+	triggered = test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", drivers.ActivityTriggered, 1000,
+		map[string]interface{}{"name": "build"})
+	started = test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.ActivityStarted, 1500, nil,
+		drivers.Link{Type: "ACTIVITY_EXECUTION", Target: triggered.ID()})
+)
+
+// Test that the activities/{id} endpoint responds with the activity's timeline.
+func TestRead(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(triggered, started), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		id         string
+		statusCode int
+		expected   string
+	}{
+		{name: "Found", id: triggered.ID(), statusCode: http.StatusOK, expected: `"queueTime":500`},
+		{name: "NotAnActivity", id: started.ID(), statusCode: http.StatusNotFound},
+		{name: "NotFound", id: "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", statusCode: http.StatusNotFound},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/activities/"+testCase.id, nil),
+				map[string]string{"id": testCase.id})
+			responseRecorder := httptest.NewRecorder()
+			handler.Read(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
+
+// Test that the activities endpoint validates its parameters and lists the matching activities.
+func TestReadAll(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(triggered, started), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+		expected   string
+	}{
+		{name: "Matching", query: "?name=build&from=1000&to=1000", statusCode: http.StatusOK, expected: `"pageNo":1,"pageSize":500,"items":[{"id":"` + triggered.ID()},
+		{name: "NotMatching", query: "?name=test", statusCode: http.StatusOK, expected: `"items":[]`},
+		{name: "InvalidTime", query: "?from=yesterday", statusCode: http.StatusBadRequest},
+		{name: "InvalidPageNo", query: "?pageNo=0", statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			handler.ReadAll(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/activities"+testCase.query, nil))
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// admin implements the /admin endpoints for maintaining the event
+// repository. They're only served when enabled in the configuration and
+// those deleting events only when an admin token is configured.
+package admin
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/schema"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/integrity"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+	"github.com/eiffel-community/eiffel-goer/internal/retention"
+)
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+}
+
+// Get a new handler for the admin endpoints.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
+	return &Handler{
+		cfg, db, logger,
+	}
+}
+
+// Integrity handles GET requests against the /admin/integrity endpoint.
+// To scan all events for dangling links, links to events of illegal types and duplicate event IDs.
+func (h *Handler) Integrity(w http.ResponseWriter, r *http.Request) {
+	request := requests.IntegrityRequest{Limit: integrity.DefaultMaxItems}
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	report, err := integrity.Check(r.Context(), h.Database, request.Limit)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, report)
+}
+
+// Duplicates handles GET requests against the /admin/duplicates endpoint.
+// To list the event IDs stored more than once.
+func (h *Handler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	h.deduplicate(w, r, false)
+}
+
+// DeleteDuplicates handles DELETE requests against the /admin/duplicates endpoint.
+// To delete the copies of events identical to the first stored one. The request
+// must carry the configured admin token as a bearer token.
+func (h *Handler) DeleteDuplicates(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		responses.RespondWithError(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+	h.deduplicate(w, r, true)
+}
+
+// authorized returns true if the request carries the configured admin token.
+// No request is authorized if there's no token.
+func (h *Handler) authorized(r *http.Request) bool {
+	token := h.Config.AdminToken()
+	given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// deduplicate reports the event IDs stored more than once, deleting the
+// identical copies if clean is true.
+func (h *Handler) deduplicate(w http.ResponseWriter, r *http.Request, clean bool) {
+	report, err := integrity.Deduplicate(r.Context(), h.Database, clean)
+	if err != nil {
+		if errors.Is(err, drivers.ErrReadOnly) {
+			responses.RespondWithError(w, http.StatusNotImplemented, err.Error())
+			return
+		}
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, report)
+}
+
+// Retention handles GET requests against the /admin/retention endpoint.
+// To report the events that the configured retention policy, or the one
+// given with the policy parameter, would remove now, without removing them.
+func (h *Handler) Retention(w http.ResponseWriter, r *http.Request) {
+	request := requests.RetentionRequest{Policy: h.Config.RetentionPolicy()}
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	policy, err := retention.ParsePolicy(request.Policy)
+	if err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	report, err := retention.Check(r.Context(), h.Database, policy, time.Now())
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, report)
+}
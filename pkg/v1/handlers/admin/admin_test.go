@@ -0,0 +1,142 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+// Test that the admin/integrity endpoint reports problems up to the requested limit.
+func TestIntegrity(t *testing.T) {
+	missing := "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f"
+	activity := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.ActivityTriggered, 1000, nil,
+		drivers.Link{Type: "CAUSE", Target: missing},
+		drivers.Link{Type: "FLOW_CONTEXT", Target: missing})
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(activity), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+		expected   string
+	}{
+		{name: "Default", query: "", statusCode: http.StatusOK, expected: `"danglingLinks":{"count":2,"items":[{"source":"` + activity.ID()},
+		{name: "Limited", query: "?limit=0", statusCode: http.StatusOK, expected: `"danglingLinks":{"count":2,"items":[]}`},
+		{name: "InvalidLimit", query: "?limit=all", statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			handler.Integrity(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/admin/integrity"+testCase.query, nil))
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
+
+// Test that the admin/duplicates endpoint only deletes copies on DELETE
+// with the admin token.
+func TestDuplicates(t *testing.T) {
+	event := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.ActivityTriggered, 1000, nil)
+	db := test.NewMemoryDatabase(event, event)
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().AdminToken().Return("s3cr3t").AnyTimes()
+	handler := Get(mockCfg, db, &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		statusCode    int
+		expected      string
+		events        int
+	}{
+		{name: "Report", method: http.MethodGet, statusCode: http.StatusOK, expected: `"cleaned":false,"deleted":0`, events: 2},
+		{name: "CleanWithoutToken", method: http.MethodDelete, statusCode: http.StatusUnauthorized, events: 2},
+		{name: "CleanWithWrongToken", method: http.MethodDelete, authorization: "Bearer guess", statusCode: http.StatusUnauthorized, events: 2},
+		{name: "Clean", method: http.MethodDelete, authorization: "Bearer s3cr3t", statusCode: http.StatusOK, expected: `"cleaned":true,"deleted":1`, events: 1},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(testCase.method, "/v1/admin/duplicates", nil)
+			request.Header.Set("Authorization", testCase.authorization)
+			responseRecorder := httptest.NewRecorder()
+			if testCase.method == http.MethodDelete {
+				handler.DeleteDuplicates(responseRecorder, request)
+			} else {
+				handler.Duplicates(responseRecorder, request)
+			}
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+			assert.Len(t, db.Events, testCase.events)
+		})
+	}
+
+	// No token authorizes anything when none is configured.
+	emptyCfg := mock_config.NewMockConfig(ctrl)
+	emptyCfg.EXPECT().AdminToken().Return("").AnyTimes()
+	request := httptest.NewRequest(http.MethodDelete, "/v1/admin/duplicates", nil)
+	request.Header.Set("Authorization", "Bearer ")
+	responseRecorder := httptest.NewRecorder()
+	Get(emptyCfg, db, &log.Entry{Logger: log.New()}).DeleteDuplicates(responseRecorder, request)
+	assert.Equal(t, http.StatusUnauthorized, responseRecorder.Code)
+
+	responseRecorder = httptest.NewRecorder()
+	handler = Get(mock_config.NewMockConfig(ctrl), mock_drivers.NewMockDatabase(ctrl), &log.Entry{Logger: log.New()})
+	handler.Duplicates(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/admin/duplicates", nil))
+	assert.Equal(t, http.StatusNotImplemented, responseRecorder.Code)
+}
+
+// Test that the admin/retention endpoint reports what the configured or given policy would remove.
+func TestRetention(t *testing.T) {
+	old := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.ActivityTriggered, 1000, nil)
+	db := test.NewMemoryDatabase(old)
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().RetentionPolicy().Return("EiffelActivityTriggeredEvent=forever").AnyTimes()
+	handler := Get(mockCfg, db, &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+		expected   string
+	}{
+		{name: "Configured", query: "", statusCode: http.StatusOK, expected: `{"dryRun":true,"rules":[{"type":"EiffelActivityTriggeredEvent","maxAge":"forever","expired":{}}],"expired":0,"deleted":0}`},
+		{name: "Given", query: "?policy=*%3D90d", statusCode: http.StatusOK, expected: `"maxAge":"90d","before":`},
+		{name: "Expired", query: "?policy=*%3D90d", statusCode: http.StatusOK, expected: `"expired":{"EiffelActivityTriggeredEvent":1}}],"expired":1,"deleted":0}`},
+		{name: "InvalidPolicy", query: "?policy=*", statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			handler.Retention(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/admin/retention"+testCase.query, nil))
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+	assert.Len(t, db.Events, 1)
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// artifacts implements the /artifacts endpoints with the lineages of
+// artifacts looked up by their identity and their confidence levels.
+package artifacts
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+}
+
+// Get a new handler for the artifacts endpoints.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
+	return &Handler{
+		cfg, db, logger,
+	}
+}
+
+// multiResponse is the response from the artifacts endpoint.
+type multiResponse struct {
+	Items []drivers.ArtifactLineage `json:"items"`
+}
+
+// ReadAll handles GET requests against the /artifacts endpoint.
+// To get the publications, confidence levels and source changes of the artifacts with an identity.
+func (h *Handler) ReadAll(w http.ResponseWriter, r *http.Request) {
+	var request requests.ArtifactsRequest
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	if request.Identity == "" {
+		responses.RespondWithError(w, http.StatusBadRequest, "An identity is required")
+		return
+	}
+	lineages, err := drivers.GetArtifactLineages(r.Context(), h.Database, request.Identity)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	if len(lineages) == 0 {
+		responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, multiResponse{lineages})
+}
+
+// Confidence handles GET requests against the /artifacts/{id}/confidence endpoint.
+// To get the latest confidence level of each name reported against an artifact and their overall status.
+func (h *Handler) Confidence(w http.ResponseWriter, r *http.Request) {
+	confidence, err := drivers.GetArtifactConfidence(r.Context(), h.Database, mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+			return
+		}
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, confidence)
+}
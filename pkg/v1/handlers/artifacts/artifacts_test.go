@@ -0,0 +1,89 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package artifacts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+// Test that the artifacts endpoint requires an identity and finds artifacts by it.
+func TestReadAll(t *testing.T) {
+	identity := "pkg:generic/goer@1.0.0"
+	artifact := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.ArtifactCreated, 1000,
+		map[string]interface{}{"identity": identity})
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(artifact), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+		expected   string
+	}{
+		{name: "Found", query: "?identity=" + url.QueryEscape(identity), statusCode: http.StatusOK, expected: `"items":[{"identity":"pkg:generic/goer@1.0.0"`},
+		{name: "NotFound", query: "?identity=" + url.QueryEscape("pkg:generic/goer@2.0.0"), statusCode: http.StatusNotFound},
+		{name: "NoIdentity", statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			handler.ReadAll(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/artifacts"+testCase.query, nil))
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
+
+// Test that the artifacts/{id}/confidence endpoint only reports the confidence of artifacts.
+func TestConfidence(t *testing.T) {
+	artifact := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.ArtifactCreated, 1000, nil)
+	level := test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", drivers.ConfidenceLevelModified, 2000,
+		map[string]interface{}{"name": "stable", "value": "SUCCESS"},
+		drivers.Link{Type: "SUBJECT", Target: artifact.ID()})
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(artifact, level), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		id         string
+		statusCode int
+		expected   string
+	}{
+		{name: "Found", id: artifact.ID(), statusCode: http.StatusOK, expected: `"status":"SUCCESS","levels":[{"name":"stable"`},
+		{name: "NotAnArtifact", id: level.ID(), statusCode: http.StatusNotFound},
+		{name: "NotFound", id: "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", statusCode: http.StatusNotFound},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/artifacts/"+testCase.id+"/confidence", nil),
+				map[string]string{"id": testCase.id})
+			responseRecorder := httptest.NewRecorder()
+			handler.Confidence(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// changes implements the /changes endpoints tracing source changes to
+// what they ended up in.
+package changes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/schema"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+}
+
+// Get a new handler for the changes endpoints.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
+	return &Handler{
+		cfg, db, logger,
+	}
+}
+
+// Lookup handles GET requests against the /changes/lookup endpoint.
+// To get the artifacts, compositions and test results downstream of the source changes
+// with a git commit SHA or Gerrit change ID.
+func (h *Handler) Lookup(w http.ResponseWriter, r *http.Request) {
+	request := requests.ChangesRequest{
+		Levels: drivers.DefaultTraversalLevels,
+		Limit:  drivers.DefaultTraversalLimit,
+	}
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	if request.Commit == "" && request.ChangeID == "" {
+		responses.RespondWithError(w, http.StatusBadRequest, "A commit or changeId is required")
+		return
+	}
+	linkTypes := drivers.DefaultChangeLinkTypes
+	if request.LinkTypes != "" {
+		linkTypes = nil
+		for _, linkType := range strings.Split(request.LinkTypes, ",") {
+			if linkType = strings.TrimSpace(linkType); linkType != "" {
+				linkTypes = append(linkTypes, linkType)
+			}
+		}
+	}
+
+	trace, err := drivers.TraceChanges(r.Context(), h.Database, request, linkTypes)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+			return
+		}
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, trace)
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package changes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+// Test that the changes/lookup endpoint requires an identifier and follows the requested link types.
+func TestLookup(t *testing.T) {
+	change := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.SourceChangeSubmitted, 1000,
+		map[string]interface{}{"gitIdentifier": map[string]interface{}{"commitId": "fe3c0a3"}})
+	artifact := test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", drivers.ArtifactCreated, 2000, nil,
+		drivers.Link{Type: "CAUSE", Target: change.ID()})
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(change, artifact), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+		expected   string
+	}{
+		{name: "DefaultLinkTypes", query: "?commit=fe3c0a3", statusCode: http.StatusOK, expected: `"artifacts":[{"data":{},"links":[{"target":"` + change.ID()},
+		{name: "OtherLinkTypes", query: "?commit=fe3c0a3&linkTypes=CONTEXT,ELEMENT", statusCode: http.StatusOK, expected: `"artifacts":[],`},
+		{name: "NotFound", query: "?changeId=I8473b95934b5732ac55d26311a706c9c2bde9940", statusCode: http.StatusNotFound},
+		{name: "NoIdentifier", query: "?linkTypes=CAUSE", statusCode: http.StatusBadRequest},
+		{name: "DefaultLimit", query: "?commit=fe3c0a3", statusCode: http.StatusOK, expected: `"truncated":false`},
+		{name: "Limited", query: "?commit=fe3c0a3&limit=1", statusCode: http.StatusOK, expected: `"truncated":true`},
+		{name: "InvalidLevels", query: "?commit=fe3c0a3&levels=all", statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			responseRecorder := httptest.NewRecorder()
+			handler.Lookup(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/changes/lookup"+testCase.query, nil))
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+// maxBatchSize is the largest number of event IDs accepted in a batch request.
+const maxBatchSize = 1000
+
+// batchResponse is the response from the events/batch endpoint.
+type batchResponse struct {
+	Items   []drivers.EiffelEvent `json:"items"`
+	Missing []string              `json:"missing"`
+}
+
+// ReadBatch handles POST requests against the /events/batch endpoint.
+// To get the events with the IDs in a JSON array in the request body, along with the IDs that weren't found.
+func (h *EventHandler) ReadBatch(w http.ResponseWriter, r *http.Request) {
+	var ids []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchSize*64)).Decode(&ids); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, "The request body must be a JSON array of event IDs")
+		return
+	}
+	if len(ids) > maxBatchSize {
+		responses.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d event IDs may be requested at once", maxBatchSize))
+		return
+	}
+	events, err := h.Database.GetEventsByIDs(r.Context(), ids)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	response := batchResponse{Items: events, Missing: []string{}}
+	if response.Items == nil {
+		response.Items = []drivers.EiffelEvent{}
+	}
+	found := make(map[string]struct{}, len(events))
+	for _, event := range events {
+		found[event.ID()] = struct{}{}
+	}
+	for _, id := range ids {
+		if _, ok := found[id]; !ok {
+			response.Missing = append(response.Missing, id)
+			// Report duplicated missing IDs once.
+			found[id] = struct{}{}
+		}
+	}
+	responses.RespondWithJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,97 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+// Test that the events/batch endpoint returns the found events and the missing IDs.
+func TestReadBatch(t *testing.T) {
+	eventMap := make(drivers.EiffelEvent)
+	require.NoError(t, json.Unmarshal(activityJSON, &eventMap))
+	missingID := "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"
+	tooMany := `["` + strings.Repeat(`a", "`, maxBatchSize) + `a"]`
+
+	tests := []struct {
+		name       string
+		body       string
+		ids        []string
+		events     []drivers.EiffelEvent
+		err        error
+		statusCode int
+		expected   string
+	}{
+		{
+			name:       "FoundAndMissing",
+			body:       fmt.Sprintf("[%q, %q, %q]", eventMap.ID(), missingID, missingID),
+			ids:        []string{eventMap.ID(), missingID, missingID},
+			events:     []drivers.EiffelEvent{eventMap},
+			statusCode: http.StatusOK,
+			expected:   fmt.Sprintf(`{"items": [%s], "missing": [%q]}`, activityJSON, missingID),
+		},
+		{
+			name:       "NoneFound",
+			body:       fmt.Sprintf("[%q]", missingID),
+			ids:        []string{missingID},
+			statusCode: http.StatusOK,
+			expected:   fmt.Sprintf(`{"items": [], "missing": [%q]}`, missingID),
+		},
+		{name: "NotAnArray", body: `{"ids": []}`, statusCode: http.StatusBadRequest},
+		{name: "TooMany", body: tooMany, statusCode: http.StatusBadRequest},
+		{
+			name:       "DatabaseError",
+			body:       fmt.Sprintf("[%q]", missingID),
+			ids:        []string{missingID},
+			err:        errors.New("database is down"),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCfg := mock_config.NewMockConfig(ctrl)
+			mockDB := mock_drivers.NewMockDatabase(ctrl)
+			if testCase.ids != nil {
+				mockDB.EXPECT().GetEventsByIDs(gomock.Any(), testCase.ids).Return(testCase.events, testCase.err)
+			}
+			app := Get(mockCfg, mockDB, &log.Entry{Logger: log.New()})
+
+			responseRecorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodPost, "/events/batch", strings.NewReader(testCase.body))
+			app.ReadBatch(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			if testCase.expected != "" {
+				assert.JSONEq(t, testCase.expected, responseRecorder.Body.String())
+			}
+		})
+	}
+}
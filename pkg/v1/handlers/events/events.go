@@ -16,9 +16,13 @@
 package events
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/schema"
@@ -29,18 +33,28 @@ import (
 	"github.com/eiffel-community/eiffel-goer/internal/query"
 	"github.com/eiffel-community/eiffel-goer/internal/requests"
 	"github.com/eiffel-community/eiffel-goer/internal/responses"
+	"github.com/eiffel-community/eiffel-goer/internal/signature"
+	"github.com/eiffel-community/eiffel-goer/internal/watch"
 )
 
 type EventHandler struct {
 	Config   config.Config
 	Database drivers.Database
 	Logger   *log.Entry
+	// Verifier verifies the signatures of events. It's nil if no keys
+	// are configured, in which case no signatures can be verified.
+	Verifier *signature.Verifier
+	// Hub follows the new events for the event streams.
+	Hub *watch.Hub
 }
 
 // Create a new handler for the event endpoint.
 func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *EventHandler {
 	return &EventHandler{
-		cfg, db, logger,
+		Config:   cfg,
+		Database: db,
+		Logger:   logger,
+		Hub:      watch.NewHub(db, logger),
 	}
 }
 
@@ -73,26 +87,114 @@ func getTags(tagName string, item interface{}) map[string]struct{} {
 	return tags
 }
 
+// multiResponse is the envelope of the response from the events endpoint.
+// The events themselves are streamed into an "items" array next to these fields.
 type multiResponse struct {
-	PageNo           int32                 `json:"pageNo"`
-	PageSize         int32                 `json:"pageSize"`
-	TotalNumberItems int64                 `json:"totalNumberItems"`
-	Items            []drivers.EiffelEvent `json:"items"`
+	PageNo           int32 `json:"pageNo"`
+	PageSize         int32 `json:"pageSize"`
+	TotalNumberItems int64 `json:"totalNumberItems"`
+}
+
+// eventItems adapts a drivers.EventStream to a responses.ItemStream.
+// The stream is expected to already be positioned at its first event.
+type eventItems struct {
+	stream  drivers.EventStream
+	started bool
+}
+
+// Next advances the stream to the next event.
+func (e *eventItems) Next(ctx context.Context) bool {
+	if !e.started {
+		e.started = true
+		return true
+	}
+	return e.stream.Next(ctx)
+}
+
+// Item returns the current event.
+func (e *eventItems) Item() interface{} {
+	return e.stream.Event()
+}
+
+// Err returns the error that stopped the stream, if any.
+func (e *eventItems) Err() error {
+	return e.stream.Err()
+}
+
+// defaultCSVColumns are the columns of CSV exports that don't specify any.
+var defaultCSVColumns = []string{"meta.id", "meta.type", "meta.time"}
+
+// csvRows adapts a drivers.EventStream to a responses.ItemStream of CSV rows
+// with the values of the given dotted field paths.
+type csvRows struct {
+	stream  drivers.EventStream
+	columns []string
+}
+
+// Next advances the stream to the next event.
+func (c *csvRows) Next(ctx context.Context) bool {
+	return c.stream.Next(ctx)
+}
+
+// Item returns the CSV row for the current event.
+func (c *csvRows) Item() interface{} {
+	event := c.stream.Event()
+	row := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		if value, ok := event.Field(column); ok {
+			row[i] = csvValue(value)
+		}
+	}
+	return row
+}
+
+// Err returns the error that stopped the stream, if any.
+func (c *csvRows) Err() error {
+	return c.stream.Err()
+}
+
+// csvValue formats a field value for a CSV cell. Scalars are written as-is
+// while objects and arrays are JSON encoded.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, int, int32, int64:
+		return fmt.Sprint(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// parseColumns parses a comma separated list of CSV columns, ignoring
+// whitespace around them and empty entries, or returns the default columns.
+func parseColumns(list string) []string {
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return defaultCSVColumns
+	}
+	return columns
 }
 
 // buildConditions takes a raw URL query, parses out all conditions and removes ignoreKeys.
 func buildConditions(rawQuery string, ignoreKeys map[string]struct{}) ([]query.Condition, error) {
-	if rawQuery == "" {
-		return nil, nil
-	}
-	res, err := query.Parse("nofile", []byte(rawQuery))
+	allConditions, err := query.ParseConditions(rawQuery)
 	if err != nil {
 		return nil, err
 	}
-	allConditions, ok := res.([]query.Condition)
-	if !ok {
-		return nil, fmt.Errorf("query parser unexpectedly returned a %T value from the query %q", res, rawQuery)
-	}
 	var conditions []query.Condition
 	for _, condition := range allConditions {
 		_, ok := ignoreKeys[condition.Field]
@@ -113,6 +215,7 @@ func (h *EventHandler) ReadAll(w http.ResponseWriter, r *http.Request) {
 		PageStartItem: 1,
 		Lazy:          false,
 		Readable:      false,
+		Count:         requests.CountExact,
 	}
 	decoder := schema.NewDecoder()
 	decoder.IgnoreUnknownKeys(true)
@@ -125,6 +228,10 @@ func (h *EventHandler) ReadAll(w http.ResponseWriter, r *http.Request) {
 		responses.RespondWithError(w, http.StatusBadRequest, "PageSize must be a positive integer")
 		return
 	}
+	if !request.ValidCount() {
+		responses.RespondWithError(w, http.StatusBadRequest, "Count must be one of exact, estimated and none")
+		return
+	}
 
 	ignoreKeys := getTags("schema", &request)
 	conditions, err := buildConditions(r.URL.RawQuery, ignoreKeys)
@@ -134,13 +241,33 @@ func (h *EventHandler) ReadAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	request.Conditions = conditions
-	events, totalNumberItems, err := h.Database.GetEvents(r.Context(), request)
+	format, err := responses.NegotiateFormat(r, responses.FormatJSON, responses.FormatNDJSON, responses.FormatCSV)
+	if err != nil {
+		responses.RespondWithError(w, http.StatusNotAcceptable, err.Error())
+		return
+	}
+	if format == responses.FormatJSON {
+		h.respondWithPage(w, r, request)
+	} else {
+		h.respondWithExport(w, r, request, format)
+	}
+}
+
+// respondWithPage responds with a single page of events in the regular JSON envelope.
+func (h *EventHandler) respondWithPage(w http.ResponseWriter, r *http.Request, request requests.MultipleEventsRequest) {
+	stream, totalNumberItems, err := h.getEvents(r.Context(), request)
 	if err != nil {
 		h.Logger.Error(err)
 		responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
 		return
 	}
-	if len(events) == 0 {
+	defer h.closeStream(r.Context(), stream)
+	// Read the first event before writing anything so we can still
+	// respond with an error if there aren't any events.
+	if !stream.Next(r.Context()) {
+		if err := stream.Err(); err != nil {
+			h.Logger.Error(err)
+		}
 		responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
 		return
 	}
@@ -148,7 +275,55 @@ func (h *EventHandler) ReadAll(w http.ResponseWriter, r *http.Request) {
 		request.PageNo,
 		request.PageSize,
 		totalNumberItems,
-		events,
 	}
-	responses.RespondWithJSON(w, http.StatusOK, response)
+	if err := responses.RespondWithJSONStream(r.Context(), w, http.StatusOK, response, "items", &eventItems{stream: stream}); err != nil {
+		h.Logger.Errorf("Error streaming events: %s", err)
+	}
+}
+
+// respondWithExport responds with all events matching the request, regardless
+// of paging, as NDJSON or CSV.
+func (h *EventHandler) respondWithExport(w http.ResponseWriter, r *http.Request, request requests.MultipleEventsRequest, format string) {
+	request.Unpaged = true
+	request.Count = requests.CountNone
+	stream, _, err := h.getEvents(r.Context(), request)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	defer h.closeStream(r.Context(), stream)
+	if format == responses.FormatCSV {
+		columns := parseColumns(request.Columns)
+		err = responses.RespondWithCSVStream(r.Context(), w, http.StatusOK, columns, &csvRows{stream: stream, columns: columns})
+	} else {
+		err = responses.RespondWithNDJSONStream(r.Context(), w, http.StatusOK, &eventItems{stream: stream, started: true})
+	}
+	if err != nil {
+		h.Logger.Errorf("Error exporting events: %s", err)
+	}
+}
+
+// getEvents gets the events matching the request from the database,
+// leaving out older versions if the request asks for the latest ones and
+// events that can't be verified if it asks for verified ones.
+func (h *EventHandler) getEvents(ctx context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
+	var filters []drivers.StreamFilter
+	if request.Latest {
+		filters = append(filters, drivers.LatestFilter(h.Database))
+	}
+	if request.Verified {
+		filters = append(filters, drivers.FilterStream(h.Verifier.Verified))
+	}
+	if len(filters) == 0 {
+		return h.Database.GetEvents(ctx, request)
+	}
+	return drivers.GetFilteredEvents(ctx, h.Database, request, filters...)
+}
+
+// closeStream closes an event stream and logs any error.
+func (h *EventHandler) closeStream(ctx context.Context, stream drivers.EventStream) {
+	if err := stream.Close(ctx); err != nil {
+		h.Logger.Errorf("Error closing event stream: %s", err)
+	}
 }
@@ -16,6 +16,7 @@
 package events
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -30,6 +31,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
 	"github.com/eiffel-community/eiffel-goer/test/mock_config"
 	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
 )
@@ -100,3 +102,98 @@ func TestEvents(t *testing.T) {
 		})
 	}
 }
+
+// Test that the count parameter of the events endpoint is validated and passed on to the database.
+func TestReadAllCount(t *testing.T) {
+	eventMap := make(drivers.EiffelEvent)
+	require.NoError(t, json.Unmarshal(activityJSON, &eventMap))
+
+	tests := []struct {
+		name       string
+		url        string
+		statusCode int
+		count      string
+		total      int64
+	}{
+		{name: "Default", url: "/events", statusCode: http.StatusOK, count: requests.CountExact, total: 1},
+		{name: "Estimated", url: "/events?count=estimated", statusCode: http.StatusOK, count: requests.CountEstimated, total: 1},
+		{name: "None", url: "/events?count=none", statusCode: http.StatusOK, count: requests.CountNone, total: -1},
+		{name: "Invalid", url: "/events?count=some", statusCode: http.StatusBadRequest},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCfg := mock_config.NewMockConfig(ctrl)
+			mockDB := mock_drivers.NewMockDatabase(ctrl)
+			if testCase.count != "" {
+				mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
+						assert.Equal(t, testCase.count, request.Count)
+						assert.Empty(t, request.Conditions)
+						return drivers.NewSliceStream([]drivers.EiffelEvent{eventMap}), testCase.total, nil
+					})
+			}
+			app := Get(mockCfg, mockDB, &log.Entry{})
+
+			responseRecorder := httptest.NewRecorder()
+			app.ReadAll(responseRecorder, httptest.NewRequest(http.MethodGet, testCase.url, nil))
+
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			if responseRecorder.Code == http.StatusOK {
+				var response multiResponse
+				require.NoError(t, json.Unmarshal(responseRecorder.Body.Bytes(), &response))
+				assert.Equal(t, testCase.total, response.TotalNumberItems)
+			}
+		})
+	}
+}
+
+// Test that the events endpoint exports all matching events as NDJSON or CSV.
+func TestReadAllExport(t *testing.T) {
+	eventMap := make(drivers.EiffelEvent)
+	require.NoError(t, json.Unmarshal(activityJSON, &eventMap))
+
+	tests := []struct {
+		name        string
+		url         string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{name: "NDJSON", url: "/events?meta.type=EiffelActivityTriggeredEvent", accept: "application/x-ndjson", contentType: "application/x-ndjson"},
+		{name: "CSVDefaultColumns", url: "/events?format=csv", contentType: "text/csv; charset=utf-8", body: "meta.id,meta.type,meta.time\ne04cf9d3-4d57-471e-bd65-f8fc20d21d84,EiffelActivityTriggeredEvent,1629449650361\n"},
+		{name: "CSVColumns", url: "/events?format=csv&columns=data.name,links,data.missing", contentType: "text/csv; charset=utf-8", body: "data.name,links,data.missing\nTest activity,[],\n"},
+		{name: "CSVColumnsWithSpaces", url: "/events?format=csv&columns=data.name,%20meta.type,,", contentType: "text/csv; charset=utf-8", body: "data.name,meta.type\nTest activity,EiffelActivityTriggeredEvent\n"},
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCfg := mock_config.NewMockConfig(ctrl)
+			mockDB := mock_drivers.NewMockDatabase(ctrl)
+			mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
+					assert.True(t, request.Unpaged)
+					assert.Equal(t, requests.CountNone, request.Count)
+					return drivers.NewSliceStream([]drivers.EiffelEvent{eventMap}), -1, nil
+				})
+			app := Get(mockCfg, mockDB, &log.Entry{})
+
+			responseRecorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, testCase.url, nil)
+			if testCase.accept != "" {
+				request.Header.Set("Accept", testCase.accept)
+			}
+			app.ReadAll(responseRecorder, request)
+
+			assert.Equal(t, http.StatusOK, responseRecorder.Code)
+			assert.Equal(t, testCase.contentType, responseRecorder.Header().Get("Content-Type"))
+			if testCase.body != "" {
+				assert.Equal(t, testCase.body, responseRecorder.Body.String())
+			} else {
+				assert.JSONEq(t, string(activityJSON), responseRecorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+// referrer is an event linking to the requested event, with the type of the link.
+// Events linking to the requested event with several links appear once per link.
+type referrer struct {
+	Type  string              `json:"type"`
+	Event drivers.EiffelEvent `json:"event"`
+}
+
+// linkedByResponse is the response from the events/{id}/linkedBy endpoint.
+type linkedByResponse struct {
+	Items []referrer `json:"items"`
+}
+
+// LinkedBy handles GET requests against the /events/{id}/linkedBy endpoint.
+// To get the events that link directly to an event, optionally only with some link types.
+func (h *EventHandler) LinkedBy(w http.ResponseWriter, r *http.Request) {
+	var request requests.LinkedByRequest
+	if err := schema.NewDecoder().Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	id := mux.Vars(r)["id"]
+	linkTypes := splitList(request.Type)
+	events, err := h.Database.GetLinkingEvents(r.Context(), []string{id}, linkTypes)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	wanted := make(map[string]struct{}, len(linkTypes))
+	for _, linkType := range linkTypes {
+		wanted[linkType] = struct{}{}
+	}
+	response := linkedByResponse{Items: []referrer{}}
+	for _, event := range events {
+		for _, link := range event.Links() {
+			if link.Target != id {
+				continue
+			}
+			if _, ok := wanted[link.Type]; len(wanted) > 0 && !ok {
+				continue
+			}
+			response.Items = append(response.Items, referrer{Type: link.Type, Event: event})
+		}
+	}
+	responses.RespondWithJSON(w, http.StatusOK, response)
+}
+
+// splitList splits a comma separated list, ignoring empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
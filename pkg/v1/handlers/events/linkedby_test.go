@@ -0,0 +1,104 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+// Test that the events/{id}/linkedBy endpoint lists each link to the event with its type.
+func TestLinkedBy(t *testing.T) {
+	targetID := "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"
+	otherID := "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f"
+	referrerEvent := drivers.EiffelEvent{
+		"meta": map[string]interface{}{"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84"},
+		"links": []interface{}{
+			map[string]interface{}{"type": "CAUSE", "target": targetID},
+			map[string]interface{}{"type": "CONTEXT", "target": targetID},
+			map[string]interface{}{"type": "CAUSE", "target": otherID},
+			map[string]interface{}{"type": "FLOW_CONTEXT", "target": targetID},
+		},
+	}
+	referrerJSON := `{"meta": {"id": "e04cf9d3-4d57-471e-bd65-f8fc20d21d84"}, "links": [
+		{"type": "CAUSE", "target": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"},
+		{"type": "CONTEXT", "target": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"},
+		{"type": "CAUSE", "target": "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f"},
+		{"type": "FLOW_CONTEXT", "target": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827"}
+	]}`
+
+	tests := []struct {
+		name       string
+		query      string
+		linkTypes  []string
+		err        error
+		statusCode int
+		expected   string
+	}{
+		{
+			name:       "AllTypes",
+			statusCode: http.StatusOK,
+			expected: fmt.Sprintf(`{"items": [{"type": "CAUSE", "event": %[1]s}, {"type": "CONTEXT", "event": %[1]s}, {"type": "FLOW_CONTEXT", "event": %[1]s}]}`,
+				referrerJSON),
+		},
+		{
+			name:       "SomeTypes",
+			query:      "?type=CONTEXT,CAUSE",
+			linkTypes:  []string{"CONTEXT", "CAUSE"},
+			statusCode: http.StatusOK,
+			expected:   fmt.Sprintf(`{"items": [{"type": "CAUSE", "event": %[1]s}, {"type": "CONTEXT", "event": %[1]s}]}`, referrerJSON),
+		},
+		{
+			name:       "DatabaseError",
+			err:        errors.New("database is down"),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockCfg := mock_config.NewMockConfig(ctrl)
+			mockDB := mock_drivers.NewMockDatabase(ctrl)
+			events := []drivers.EiffelEvent{referrerEvent}
+			if testCase.err != nil {
+				events = nil
+			}
+			mockDB.EXPECT().GetLinkingEvents(gomock.Any(), []string{targetID}, testCase.linkTypes).Return(events, testCase.err)
+			app := Get(mockCfg, mockDB, &log.Entry{Logger: log.New()})
+
+			responseRecorder := httptest.NewRecorder()
+			request := httptest.NewRequest(http.MethodGet, "/events/"+targetID+"/linkedBy"+testCase.query, nil)
+			request = mux.SetURLVars(request, map[string]string{"id": targetID})
+			app.LinkedBy(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			if testCase.expected != "" {
+				assert.JSONEq(t, testCase.expected, responseRecorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/schema"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+	"github.com/eiffel-community/eiffel-goer/internal/watch"
+)
+
+// keepAliveInterval is how often a comment is sent to idle event streams
+// to keep proxies and clients from closing the connection.
+const keepAliveInterval = 15 * time.Second
+
+// Stream handles GET requests against the /events/stream endpoint.
+// To push events matching the query to the client as server-sent events as they are stored.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	var request requests.StreamRequest
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	conditions, err := buildConditions(r.URL.RawQuery, getTags("schema", &request))
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = request.LastEventID
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	stream, err := h.Hub.Follow(ctx, conditions, lastEventID)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	sse, err := responses.StartServerSentEvents(w)
+	if err != nil {
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		_ = stream.Close(ctx)
+		return
+	}
+
+	// Read the stream in the background so we can send keepalives
+	// while waiting for events.
+	events := make(chan drivers.EiffelEvent)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(events)
+		for stream.Next(ctx) {
+			select {
+			case events <- stream.Event():
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := stream.Err(); errors.Is(err, watch.ErrSlowSubscriber) {
+			h.Logger.Debug("Disconnecting client that fell behind the new events")
+		} else if err != nil && ctx.Err() == nil {
+			h.Logger.Errorf("Error streaming events: %s", err)
+		}
+	}()
+	defer func() {
+		cancel()
+		<-done
+		// The request context is done by now, but the stream may still
+		// need to talk to the database to close.
+		h.closeStream(context.Background(), stream)
+	}()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := sse.Send(event.ID(), event); err != nil {
+				h.Logger.Debugf("Error sending event to client: %s", err)
+				return
+			}
+		case <-keepAlive.C:
+			if err := sse.KeepAlive(); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/watch"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+// Test that the events/stream endpoint resumes after the last event ID and
+// pushes the matching events followed by the hub.
+func TestStream(t *testing.T) {
+	watch.PollInterval = time.Millisecond
+	eventMap := make(drivers.EiffelEvent)
+	require.NoError(t, json.Unmarshal(activityJSON, &eventMap))
+	lastEvent := drivers.EiffelEvent{"meta": map[string]interface{}{"id": "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "time": int64(1629449650000)}}
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	otherType := drivers.EiffelEvent{"meta": map[string]interface{}{"id": "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", "type": "EiffelArtifactCreatedEvent", "time": now + 1000}}
+	live := drivers.EiffelEvent{"meta": map[string]interface{}{"id": "5b1e7c0a-8f3d-4e2a-b6c9-0d4f1a2e3b7c", "type": "EiffelActivityTriggeredEvent", "time": now + 1000}}
+
+	ctrl := gomock.NewController(t)
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockDB := mock_drivers.NewMockDatabase(ctrl)
+	mockDB.EXPECT().GetEventByID(gomock.Any(), lastEvent.ID()).Return(lastEvent, nil)
+	polls := [][]drivers.EiffelEvent{{otherType, live, eventMap}}
+	var mu sync.Mutex
+	mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
+			if len(request.Conditions) == 2 {
+				// The replay after the last event.
+				assert.Equal(t, []query.Condition{
+					{Field: "meta.time", Op: ">=", Value: "1629449650000", TypeConv: "int"},
+					{Field: "meta.type", Op: "=", Value: "EiffelActivityTriggeredEvent"},
+				}, request.Conditions)
+				return drivers.NewSliceStream([]drivers.EiffelEvent{lastEvent, eventMap}), -1, nil
+			}
+			// The hub's polls for all new events.
+			mu.Lock()
+			defer mu.Unlock()
+			var events []drivers.EiffelEvent
+			if len(polls) > 0 {
+				events, polls = polls[0], polls[1:]
+			}
+			return drivers.NewSliceStream(events), -1, nil
+		}).AnyTimes()
+	app := Get(mockCfg, mockDB, &log.Entry{Logger: log.New()})
+
+	server := httptest.NewServer(http.HandlerFunc(app.Stream))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"?meta.type=EiffelActivityTriggeredEvent", nil)
+	require.NoError(t, err)
+	request.Header.Set("Last-Event-ID", lastEvent.ID())
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	reader := bufio.NewReader(response.Body)
+	idLine, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "id: "+eventMap.ID()+"\n", idLine)
+	dataLine, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.JSONEq(t, string(activityJSON), dataLine[len("data: "):])
+
+	// The replayed event isn't sent again and events of other types are left out.
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "id: ") {
+			assert.Equal(t, "id: "+live.ID()+"\n", line)
+			break
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/signature"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+// signedEvents returns a handler with a verifier and a signed and an unsigned event.
+func signedEvents(t *testing.T) (*EventHandler, drivers.EiffelEvent, drivers.EiffelEvent) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signed := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "EiffelArtifactCreatedEvent", 1000, nil)
+	integrityProtection := map[string]interface{}{"alg": "EdDSA", "signature": ""}
+	signed["meta"].(map[string]interface{})["security"] = map[string]interface{}{"integrityProtection": integrityProtection}
+	message, err := signature.Message(signed)
+	require.NoError(t, err)
+	integrityProtection["signature"] = base64.StdEncoding.EncodeToString(ed25519.Sign(private, message))
+	unsigned := test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "EiffelArtifactCreatedEvent", 2000, nil)
+
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(signed, unsigned), &log.Entry{Logger: log.New()})
+	handler.Verifier = &signature.Verifier{Keys: []signature.Key{{ID: "builder", PublicKey: public}}}
+	return handler, signed, unsigned
+}
+
+// Test that the events/{id}/verify endpoint responds with the verification of the event.
+func TestVerify(t *testing.T) {
+	handler, signed, unsigned := signedEvents(t)
+	tests := []struct {
+		name       string
+		id         string
+		statusCode int
+		expected   string
+	}{
+		{name: "Signed", id: signed.ID(), statusCode: http.StatusOK, expected: `"status":"verified","alg":"EdDSA","key":"builder"`},
+		{name: "Unsigned", id: unsigned.ID(), statusCode: http.StatusOK, expected: `"status":"unsigned"`},
+		{name: "NotFound", id: "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", statusCode: http.StatusNotFound},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/events/"+testCase.id+"/verify", nil),
+				map[string]string{"id": testCase.id})
+			responseRecorder := httptest.NewRecorder()
+			handler.Verify(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
+
+// Test that the events endpoint only includes verified events with verified=true.
+func TestReadAllVerified(t *testing.T) {
+	handler, signed, unsigned := signedEvents(t)
+	responseRecorder := httptest.NewRecorder()
+	handler.ReadAll(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/events?verified=true", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `"totalNumberItems":1,"items":[`)
+	assert.Contains(t, responseRecorder.Body.String(), signed.ID())
+	assert.NotContains(t, responseRecorder.Body.String(), unsigned.ID())
+}
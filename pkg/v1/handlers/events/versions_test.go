@@ -0,0 +1,75 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package events
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+var (
+	oldVersion = test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "EiffelArtifactCreatedEvent", 1000, nil)
+	newVersion = test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "EiffelArtifactCreatedEvent", 2000, nil,
+		drivers.Link{Type: "PREVIOUS_VERSION", Target: oldVersion.ID()})
+)
+
+// Test that the events/{id}/versions endpoint responds with the version chain.
+func TestVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(oldVersion, newVersion), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		id         string
+		statusCode int
+		expected   string
+	}{
+		{name: "Found", id: oldVersion.ID(), statusCode: http.StatusOK, expected: fmt.Sprintf(`{"head":{"data":{},"links":[{"target":%q,"type":"PREVIOUS_VERSION"}],"meta":{"id":%q`, oldVersion.ID(), newVersion.ID())},
+		{name: "NotFound", id: "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", statusCode: http.StatusNotFound},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/events/"+testCase.id+"/versions", nil),
+				map[string]string{"id": testCase.id})
+			responseRecorder := httptest.NewRecorder()
+			handler.Versions(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
+
+// Test that the events endpoint only includes the latest versions with latest=true.
+func TestReadAllLatest(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(oldVersion, newVersion), &log.Entry{Logger: log.New()})
+	responseRecorder := httptest.NewRecorder()
+	handler.ReadAll(responseRecorder, httptest.NewRequest(http.MethodGet, "/v1/events?latest=true&meta.type=EiffelArtifactCreatedEvent", nil))
+	assert.Equal(t, http.StatusOK, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `"totalNumberItems":1,"items":[`)
+	assert.Contains(t, responseRecorder.Body.String(), newVersion.ID())
+	assert.NotContains(t, responseRecorder.Body.String(), `"id":"`+oldVersion.ID())
+}
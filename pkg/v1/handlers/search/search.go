@@ -16,15 +16,25 @@
 package search
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/eiffel-community/eiffel-goer/internal/config"
 	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/graph"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
 	"github.com/eiffel-community/eiffel-goer/internal/responses"
 )
 
+// maxBodySize is the largest search parameters body accepted.
+const maxBodySize = 64 * 1024
+
 type Handler struct {
 	Config   config.Config
 	Database drivers.Database
@@ -39,7 +49,57 @@ func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
 }
 
 // UpstreamDownstream handles POST requests against the /search/{id} endpoint.
-// To get upstream/downstream events for an event based on the searchParameters passed.
-func (h *Handler) UpstreamDownstream(w http.ResponseWriter, _ *http.Request) {
-	responses.RespondWithError(w, http.StatusNotImplemented, http.StatusText(http.StatusNotImplemented))
+// To get upstream/downstream events for an event based on the searchParameters passed,
+// either as JSON or rendered as a graph.
+func (h *Handler) UpstreamDownstream(w http.ResponseWriter, r *http.Request) {
+	request := requests.SearchRequest{
+		Limit:  -1,
+		Levels: -1,
+	}
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&request)
+	if errors.Is(err, io.EOF) {
+		// Without search parameters, follow all links in both directions.
+		request.DLT = []string{drivers.LinkTypeAll}
+		request.ULT = []string{drivers.LinkTypeAll}
+	} else if err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, "Invalid search parameters")
+		return
+	}
+	format, err := responses.NegotiateFormat(r, responses.FormatJSON, responses.FormatDOT,
+		responses.FormatMermaid, responses.FormatCytoscape)
+	if err != nil {
+		responses.RespondWithError(w, http.StatusNotAcceptable, err.Error())
+		return
+	}
+
+	result, err := h.Database.UpstreamDownstreamSearch(r.Context(), mux.Vars(r)["id"], request)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+			return
+		}
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+
+	if format == responses.FormatJSON {
+		responses.RespondWithJSON(w, http.StatusOK, result)
+		return
+	}
+	g := graph.New(append(result.Upstream, result.Downstream...))
+	switch format {
+	case responses.FormatDOT:
+		responses.RespondWithText(w, http.StatusOK, responses.ContentTypes[format], g.DOT())
+	case responses.FormatMermaid:
+		responses.RespondWithText(w, http.StatusOK, responses.ContentTypes[format], g.Mermaid())
+	case responses.FormatCytoscape:
+		responses.RespondWithJSON(w, http.StatusOK, g.Cytoscape())
+	}
 }
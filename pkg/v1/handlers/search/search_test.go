@@ -0,0 +1,97 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package search
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+// Test that search results are rendered in the negotiated format.
+func TestUpstreamDownstream(t *testing.T) {
+	artifact := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", "EiffelArtifactCreatedEvent", 1, nil)
+	published := test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", "EiffelArtifactPublishedEvent", 2, nil,
+		drivers.Link{Type: "ARTIFACT", Target: artifact.ID()})
+	db := test.NewMemoryDatabase(artifact, published)
+
+	tests := []struct {
+		name        string
+		id          string
+		query       string
+		accept      string
+		body        string
+		statusCode  int
+		contentType string
+		contains    string
+	}{
+		{
+			name: "JSON", id: artifact.ID(), statusCode: http.StatusOK, contentType: "application/json",
+			contains: fmt.Sprintf(`"downstreamLinkObjects":[{"data":{},"links":[],"meta":{"id":"%s"`, artifact.ID()),
+		},
+		{
+			name: "DOT", id: artifact.ID(), accept: "text/vnd.graphviz", statusCode: http.StatusOK, contentType: "text/vnd.graphviz",
+			contains: fmt.Sprintf(`"%s" -> "%s" [label="ARTIFACT"];`, published.ID(), artifact.ID()),
+		},
+		{
+			name: "Mermaid", id: artifact.ID(), query: "?format=mermaid", statusCode: http.StatusOK, contentType: "text/vnd.mermaid",
+			contains: "n1 -->|ARTIFACT| n0",
+		},
+		{
+			name: "Cytoscape", id: artifact.ID(), query: "?format=cytoscape", statusCode: http.StatusOK, contentType: "application/json",
+			contains: fmt.Sprintf(`"source":"%s"`, published.ID()),
+		},
+		{
+			name: "UpstreamOnly", id: artifact.ID(), query: "?format=mermaid", body: `{"ult": ["ALL"]}`,
+			statusCode: http.StatusOK, contentType: "text/vnd.mermaid", contains: "flowchart TD\n  n0[\"EiffelArtifactCreatedEvent\"]\n",
+		},
+		{name: "NotAcceptable", id: artifact.ID(), accept: "image/png", statusCode: http.StatusNotAcceptable},
+		{name: "InvalidBody", id: artifact.ID(), body: `{"dlt": "ALL"}`, statusCode: http.StatusBadRequest},
+		{name: "InvalidLimit", id: artifact.ID(), query: "?limit=many", statusCode: http.StatusBadRequest},
+		{name: "NotFound", id: "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", statusCode: http.StatusNotFound},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			handler := Get(mock_config.NewMockConfig(ctrl), db, &log.Entry{Logger: log.New()})
+			request := httptest.NewRequest(http.MethodPost, "/v1/search/"+testCase.id+testCase.query, strings.NewReader(testCase.body))
+			if testCase.accept != "" {
+				request.Header.Set("Accept", testCase.accept)
+			}
+			request = mux.SetURLVars(request, map[string]string{"id": testCase.id})
+			responseRecorder := httptest.NewRecorder()
+			handler.UpstreamDownstream(responseRecorder, request)
+
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			if testCase.contentType != "" {
+				assert.True(t, strings.HasPrefix(responseRecorder.Header().Get("Content-Type"), testCase.contentType),
+					responseRecorder.Header().Get("Content-Type"))
+			}
+			assert.Contains(t, responseRecorder.Body.String(), testCase.contains)
+		})
+	}
+}
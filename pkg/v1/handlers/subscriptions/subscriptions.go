@@ -0,0 +1,347 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// subscriptions implements the /ws endpoint where clients subscribe to new
+// events over a WebSocket connection.
+//
+// Clients send JSON messages to add and remove subscriptions:
+//
+//	{"type": "subscribe", "id": "activities", "filter": "meta.type=EiffelActivityTriggeredEvent"}
+//	{"type": "unsubscribe", "id": "activities"}
+//
+// The filter uses the same query language as the /events endpoint, URL encoded
+// like in a query string, and an empty filter matches all events. The server
+// sends every new event matching a subscription in a message with the
+// subscription's ID, and reports problems with error messages:
+//
+//	{"type": "event", "id": "activities", "event": {...}}
+//	{"type": "error", "id": "activities", "error": "..."}
+//
+// All connections share one watch.Hub following the events stored in the
+// database. A client that falls more than the hub's buffer size behind the
+// new events is disconnected, like clients that stop receiving messages
+// altogether.
+//
+// Browsers may only connect from pages served by Goer itself unless their
+// origins are configured as allowed.
+package subscriptions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/watch"
+)
+
+const (
+	// Message types in the protocol.
+	typeSubscribe   = "subscribe"
+	typeUnsubscribe = "unsubscribe"
+	typeEvent       = "event"
+	typeError       = "error"
+
+	// maxMessageSize is the largest message accepted from a client.
+	maxMessageSize = 64 * 1024
+	// maxSubscriptions is the largest number of subscriptions per connection.
+	maxSubscriptions = 100
+	// sendBufferSize is how many messages may be queued for a client
+	// before no more events are read for it.
+	sendBufferSize = 64
+	// writeWait is how long a client gets to receive a message before it's disconnected.
+	writeWait = 10 * time.Second
+	// pongWait is how long a client may stay silent before it's disconnected.
+	pongWait = 60 * time.Second
+	// pingPeriod is how often the client is pinged. It must be less than pongWait.
+	pingPeriod = pongWait * 9 / 10
+)
+
+// clientMessage is a message from the client.
+type clientMessage struct {
+	Type   string `json:"type"`
+	ID     string `json:"id"`
+	Filter string `json:"filter"`
+}
+
+// serverMessage is a message to the client.
+type serverMessage struct {
+	Type  string              `json:"type"`
+	ID    string              `json:"id,omitempty"`
+	Event drivers.EiffelEvent `json:"event,omitempty"`
+	Error string              `json:"error,omitempty"`
+}
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+	// Hub follows the new events for all connections.
+	Hub      *watch.Hub
+	upgrader websocket.Upgrader
+}
+
+// Get a new handler for the subscriptions endpoint.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
+	handler := &Handler{
+		Config:   cfg,
+		Database: db,
+		Logger:   logger,
+		Hub:      watch.NewHub(db, logger),
+	}
+	if origins := cfg.AllowedOrigins(); len(origins) > 0 {
+		handler.upgrader.CheckOrigin = checkOrigin(origins)
+	}
+	return handler
+}
+
+// checkOrigin returns a function accepting requests from the origins, where
+// * accepts any origin, and requests without origins, which aren't from
+// browsers.
+func checkOrigin(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		for _, allowed := range origins {
+			if allowed == "*" || strings.EqualFold(allowed, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Connect handles GET requests against the /ws endpoint.
+// To upgrade the connection to a WebSocket and serve subscriptions on it until the client disconnects.
+func (h *Handler) Connect(w http.ResponseWriter, r *http.Request) {
+	// The upgrader responds to the client on errors.
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		h.Logger.Debugf("Error upgrading to a WebSocket connection: %s", err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &connection{
+		conn:          conn,
+		hub:           h.Hub,
+		logger:        h.Logger.WithField("remote", r.RemoteAddr),
+		ctx:           ctx,
+		cancel:        cancel,
+		send:          make(chan serverMessage, sendBufferSize),
+		subscriptions: map[string][]query.Condition{},
+	}
+	c.serve()
+}
+
+// connection is a client connected to the /ws endpoint.
+type connection struct {
+	conn   *websocket.Conn
+	hub    *watch.Hub
+	logger *log.Entry
+
+	// ctx is canceled when the connection is closing.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// send has the messages waiting to be written to the client.
+	send chan serverMessage
+	// wg tracks the goroutines writing to the client and following events.
+	wg sync.WaitGroup
+
+	mu sync.Mutex
+	// subscriptions maps the subscription IDs to their conditions.
+	subscriptions map[string][]query.Condition
+	// subscriber receives the events stored in the database,
+	// from when the first subscription was made.
+	subscriber *watch.Subscriber
+}
+
+// serve reads messages from the client until the connection is closed
+// and then stops the connection's goroutines.
+func (c *connection) serve() {
+	c.wg.Add(1)
+	go c.writeMessages()
+	defer func() {
+		c.cancel()
+		c.wg.Wait()
+		c.mu.Lock()
+		if c.subscriber != nil {
+			c.subscriber.Close()
+		}
+		c.mu.Unlock()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			c.logger.Debugf("WebSocket connection closed: %s", err)
+			return
+		}
+		var message clientMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			c.queue(serverMessage{Type: typeError, Error: fmt.Sprintf("invalid message: %s", err)})
+			continue
+		}
+		if err := c.handle(message); err != nil {
+			c.queue(serverMessage{Type: typeError, ID: message.ID, Error: err.Error()})
+		}
+	}
+}
+
+// handle a message from the client.
+func (c *connection) handle(message clientMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch message.Type {
+	case typeSubscribe:
+		if message.ID == "" {
+			return errors.New("subscriptions must have an ID")
+		}
+		if _, exists := c.subscriptions[message.ID]; exists {
+			return fmt.Errorf("already subscribed with the ID %q", message.ID)
+		}
+		if len(c.subscriptions) >= maxSubscriptions {
+			return fmt.Errorf("at most %d subscriptions are allowed per connection", maxSubscriptions)
+		}
+		conditions, err := query.ParseConditions(message.Filter)
+		if err != nil {
+			return fmt.Errorf("invalid filter: %w", err)
+		}
+		if c.subscriber == nil {
+			subscriber, err := c.hub.Subscribe()
+			if err != nil {
+				c.logger.Errorf("Error following events: %s", err)
+				return errors.New("can't follow events right now")
+			}
+			c.subscriber = subscriber
+			c.wg.Add(1)
+			go c.dispatchEvents(subscriber)
+		}
+		c.subscriptions[message.ID] = conditions
+	case typeUnsubscribe:
+		if _, exists := c.subscriptions[message.ID]; !exists {
+			return fmt.Errorf("no subscription with the ID %q", message.ID)
+		}
+		delete(c.subscriptions, message.ID)
+	default:
+		return fmt.Errorf("unknown message type %q", message.Type)
+	}
+	return nil
+}
+
+// dispatchEvents queues a message for every subscription matching each new event.
+// It blocks while the send buffer is full, letting the hub buffer the events,
+// and disconnects the client if the hub drops it for falling behind.
+func (c *connection) dispatchEvents(subscriber *watch.Subscriber) {
+	defer c.wg.Done()
+	for {
+		var event drivers.EiffelEvent
+		var ok bool
+		select {
+		case event, ok = <-subscriber.Events():
+		case <-c.ctx.Done():
+			return
+		}
+		if !ok {
+			break
+		}
+		for _, id := range c.matchingSubscriptions(event) {
+			if !c.queue(serverMessage{Type: typeEvent, ID: id, Event: event}) {
+				return
+			}
+		}
+	}
+	if errors.Is(subscriber.Err(), watch.ErrSlowSubscriber) {
+		c.logger.Debug("Disconnecting client that fell behind the new events")
+		c.cancel()
+		// Unblock the reader.
+		c.conn.Close()
+		return
+	}
+	if subscriber.Err() != nil {
+		c.queue(serverMessage{Type: typeError, Error: "stopped following events because of an internal error"})
+	}
+}
+
+// matchingSubscriptions returns the IDs of the subscriptions matching the event.
+func (c *connection) matchingSubscriptions(event drivers.EiffelEvent) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id, conditions := range c.subscriptions {
+		if event.Matches(conditions) {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// queue a message for the client, waiting for room in the send buffer.
+// Returns false if the connection closed before the message could be queued.
+func (c *connection) queue(message serverMessage) bool {
+	select {
+	case c.send <- message:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+// writeMessages writes the queued messages to the client and keeps pinging it.
+// A client that doesn't receive a message in time is disconnected.
+func (c *connection) writeMessages() {
+	defer c.wg.Done()
+	ping := time.NewTicker(pingPeriod)
+	defer ping.Stop()
+	for {
+		var err error
+		select {
+		case message := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err = c.conn.WriteJSON(message)
+		case <-ping.C:
+			err = c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+		case <-c.ctx.Done():
+			_ = c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeWait))
+			return
+		}
+		if err != nil {
+			c.logger.Debugf("Error writing to WebSocket, disconnecting: %s", err)
+			c.cancel()
+			// Unblock the reader.
+			c.conn.Close()
+			return
+		}
+	}
+}
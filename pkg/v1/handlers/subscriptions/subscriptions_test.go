@@ -0,0 +1,160 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package subscriptions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/watch"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+	"github.com/eiffel-community/eiffel-goer/test/mock_drivers"
+)
+
+var (
+	activity = drivers.EiffelEvent{"meta": map[string]interface{}{
+		"id":   "e04cf9d3-4d57-471e-bd65-f8fc20d21d84",
+		"time": float64(1629449650361),
+		"type": "EiffelActivityTriggeredEvent",
+	}}
+	artifact = drivers.EiffelEvent{"meta": map[string]interface{}{
+		"id":   "3fabaa6b-5343-4d74-8af9-dc2e4c1f2827",
+		"time": float64(1629449650362),
+		"type": "EiffelArtifactCreatedEvent",
+	}}
+)
+
+// newConfig returns a config allowing the origins.
+func newConfig(ctrl *gomock.Controller, origins []string) *mock_config.MockConfig {
+	mockCfg := mock_config.NewMockConfig(ctrl)
+	mockCfg.EXPECT().AllowedOrigins().Return(origins).AnyTimes()
+	return mockCfg
+}
+
+// dial connects a WebSocket client to a test server running the handler.
+func dial(t *testing.T, handler *Handler) *websocket.Conn {
+	server := httptest.NewServer(http.HandlerFunc(handler.Connect))
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	return conn
+}
+
+// Test that the protocol errors are reported to the client without closing the connection.
+func TestConnectErrors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	handler := Get(newConfig(ctrl, nil), mock_drivers.NewMockDatabase(ctrl), &log.Entry{Logger: log.New()})
+	conn := dial(t, handler)
+
+	tests := []struct {
+		name     string
+		message  string
+		expected serverMessage
+	}{
+		{"InvalidJSON", `{"type":`, serverMessage{Type: typeError, Error: "invalid message: unexpected end of JSON input"}},
+		{"UnknownType", `{"type":"resubscribe","id":"a"}`, serverMessage{Type: typeError, ID: "a", Error: `unknown message type "resubscribe"`}},
+		{"MissingID", `{"type":"subscribe"}`, serverMessage{Type: typeError, Error: "subscriptions must have an ID"}},
+		{"InvalidFilter", `{"type":"subscribe","id":"a","filter":"=value"}`, serverMessage{Type: typeError, ID: "a"}},
+		{"UnknownSubscription", `{"type":"unsubscribe","id":"a"}`, serverMessage{Type: typeError, ID: "a", Error: `no subscription with the ID "a"`}},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(testCase.message)))
+			var message serverMessage
+			require.NoError(t, conn.ReadJSON(&message))
+			if testCase.expected.Error == "" {
+				assert.True(t, strings.HasPrefix(message.Error, "invalid filter: "), message.Error)
+				message.Error = ""
+			}
+			assert.Equal(t, testCase.expected, message)
+		})
+	}
+}
+
+// Test that new events are sent to the subscriptions they match.
+func TestConnectSubscribe(t *testing.T) {
+	watch.PollInterval = time.Millisecond
+	ctrl := gomock.NewController(t)
+	mockDB := mock_drivers.NewMockDatabase(ctrl)
+	first := mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return(
+		drivers.NewSliceStream([]drivers.EiffelEvent{activity, artifact}), int64(-1), nil)
+	mockDB.EXPECT().GetEvents(gomock.Any(), gomock.Any()).Return(
+		drivers.NewSliceStream(nil), int64(-1), nil).After(first).AnyTimes()
+	handler := Get(newConfig(ctrl, nil), mockDB, &log.Entry{Logger: log.New()})
+	conn := dial(t, handler)
+
+	require.NoError(t, conn.WriteJSON(clientMessage{
+		Type:   typeSubscribe,
+		ID:     "activities",
+		Filter: "meta.type=EiffelActivityTriggeredEvent",
+	}))
+	var message serverMessage
+	require.NoError(t, conn.ReadJSON(&message))
+	assert.Equal(t, typeEvent, message.Type)
+	assert.Equal(t, "activities", message.ID)
+	assert.Equal(t, activity.ID(), message.Event.ID())
+
+	require.NoError(t, conn.WriteJSON(clientMessage{Type: typeSubscribe, ID: "activities"}))
+	var errorMessage serverMessage
+	require.NoError(t, conn.ReadJSON(&errorMessage))
+	assert.Equal(t, serverMessage{Type: typeError, ID: "activities", Error: `already subscribed with the ID "activities"`}, errorMessage)
+}
+
+// Test that only the allowed origins may connect.
+func TestConnectOrigin(t *testing.T) {
+	tests := []struct {
+		name     string
+		allowed  []string
+		origin   string
+		expected bool
+	}{
+		{"NoOrigin", []string{"https://ui.example.com"}, "", true},
+		{"Allowed", []string{"https://ui.example.com"}, "https://UI.example.com", true},
+		{"NotAllowed", []string{"https://ui.example.com"}, "https://evil.example.com", false},
+		{"Any", []string{"*"}, "https://evil.example.com", true},
+		{"DefaultSameHost", nil, "https://evil.example.com", false},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			handler := Get(newConfig(ctrl, testCase.allowed), mock_drivers.NewMockDatabase(ctrl), &log.Entry{Logger: log.New()})
+			server := httptest.NewServer(http.HandlerFunc(handler.Connect))
+			defer server.Close()
+
+			header := http.Header{}
+			if testCase.origin != "" {
+				header.Set("Origin", testCase.origin)
+			}
+			conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+			if conn != nil {
+				conn.Close()
+			}
+			assert.Equal(t, testCase.expected, err == nil, err)
+		})
+	}
+}
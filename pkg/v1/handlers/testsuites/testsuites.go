@@ -0,0 +1,72 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// testsuites implements the /testsuites endpoints with computed summaries
+// of test suite executions.
+package testsuites
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/schema"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+}
+
+// Get a new handler for the test suites endpoints.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
+	return &Handler{
+		cfg, db, logger,
+	}
+}
+
+// Summary handles GET requests against the /testsuites/{id}/summary endpoint.
+// To get the verdicts and durations of the test cases in a test suite execution.
+func (h *Handler) Summary(w http.ResponseWriter, r *http.Request) {
+	request := requests.TestSuiteSummaryRequest{
+		Levels: drivers.DefaultTraversalLevels,
+		Limit:  drivers.DefaultTraversalLimit,
+	}
+	decoder := schema.NewDecoder()
+	decoder.IgnoreUnknownKeys(true)
+	if err := decoder.Decode(&request, r.URL.Query()); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	summary, err := drivers.GetTestSuiteSummary(r.Context(), h.Database, mux.Vars(r)["id"], request)
+	if err != nil {
+		if errors.Is(err, drivers.ErrNotFound) {
+			responses.RespondWithError(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+			return
+		}
+		h.Logger.Error(err)
+		responses.RespondWithError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	responses.RespondWithJSON(w, http.StatusOK, summary)
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package testsuites
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+// Test that the testsuites/{id}/summary endpoint only summarizes test suites.
+func TestSummary(t *testing.T) {
+	suite := test.NewEvent("e04cf9d3-4d57-471e-bd65-f8fc20d21d84", drivers.TestSuiteStarted, 1000,
+		map[string]interface{}{"name": "smoke"})
+	testCase := test.NewEvent("3fabaa6b-5343-4d74-8af9-dc2e4c1f2827", drivers.TestCaseTriggered, 1100,
+		map[string]interface{}{"testCase": map[string]interface{}{"id": "boot"}},
+		drivers.Link{Type: "CONTEXT", Target: suite.ID()})
+	ctrl := gomock.NewController(t)
+	handler := Get(mock_config.NewMockConfig(ctrl), test.NewMemoryDatabase(suite, testCase), &log.Entry{Logger: log.New()})
+	tests := []struct {
+		name       string
+		id         string
+		query      string
+		statusCode int
+		expected   string
+	}{
+		{name: "Found", id: suite.ID(), statusCode: http.StatusOK, expected: `"counts":{"total":1,"passed":0,"failed":0,"inconclusive":0,"canceled":0,"unfinished":1}`},
+		{name: "Limited", id: suite.ID(), query: "?limit=1", statusCode: http.StatusOK, expected: `"truncated":true`},
+		{name: "InvalidLimit", id: suite.ID(), query: "?limit=all", statusCode: http.StatusBadRequest},
+		{name: "NotATestSuite", id: testCase.ID(), statusCode: http.StatusNotFound},
+		{name: "NotFound", id: "9d2f6b3c-1d8e-4f6c-9a51-2a3c4b5d6e7f", statusCode: http.StatusNotFound},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := mux.SetURLVars(httptest.NewRequest(http.MethodGet, "/v1/testsuites/"+testCase.id+"/summary"+testCase.query, nil),
+				map[string]string{"id": testCase.id})
+			responseRecorder := httptest.NewRecorder()
+			handler.Summary(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// webhook implements the /ingest/webhook endpoint for producers that send
+// events over HTTP. It's only served when producer secrets are configured.
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/eiffel-community/eiffel-goer/internal/config"
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/internal/responses"
+)
+
+const (
+	// ProducerHeader names the producer sending a request.
+	ProducerHeader = "X-Goer-Producer"
+	// TimestampHeader holds the time of a request in seconds since the Unix
+	// epoch. It's signed along with the body and requests more than
+	// ingest.MaxClockSkew from the current time are refused.
+	TimestampHeader = "X-Goer-Timestamp"
+	// SignatureHeader holds the hex encoded HMAC-SHA256 of the timestamp and
+	// the request body joined by a period with the producer's secret,
+	// optionally prefixed with sha256=.
+	SignatureHeader = "X-Goer-Signature"
+	// MaxBodySize is the maximum size in bytes of a batch of events.
+	MaxBodySize = 16 << 20
+)
+
+const (
+	// resultRejected means that the event was invalid or rejected by the
+	// duplicate policy or signature verification. Sending it again is pointless.
+	resultRejected = "rejected"
+	// resultFailed means that the event couldn't be stored and may be sent again.
+	resultFailed = "failed"
+)
+
+// EventResult is what happened to one of the events in a batch.
+type EventResult struct {
+	ID     string `json:"id,omitempty"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Response holds the results of the events in a batch, in the same order.
+type Response struct {
+	Results []EventResult `json:"results"`
+}
+
+type Handler struct {
+	Config   config.Config
+	Database drivers.Database
+	Logger   *log.Entry
+	Ingester *ingest.Ingester
+	Secrets  ingest.Secrets
+}
+
+// Get a new handler for the webhook endpoint.
+func Get(cfg config.Config, db drivers.Database, logger *log.Entry) *Handler {
+	return &Handler{Config: cfg, Database: db, Logger: logger}
+}
+
+// Ingest handles POST requests against the /ingest/webhook endpoint.
+// To ingest a JSON array of events signed with the producer's secret.
+// The response is 500 Internal Server Error if any of the events couldn't
+// be stored, in which case the batch can be sent again since events that
+// were stored are detected as duplicates.
+func (h *Handler) Ingest(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+	if err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+	if len(body) > MaxBodySize {
+		responses.RespondWithError(w, http.StatusRequestEntityTooLarge, http.StatusText(http.StatusRequestEntityTooLarge))
+		return
+	}
+	producer := r.Header.Get(ProducerHeader)
+	timestamp := r.Header.Get(TimestampHeader)
+	if err := h.Secrets.Verify(producer, timestamp, body, r.Header.Get(SignatureHeader), time.Now()); err != nil {
+		responses.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	var events []json.RawMessage
+	if err := json.Unmarshal(body, &events); err != nil {
+		responses.RespondWithError(w, http.StatusBadRequest, "the request body must be a JSON array of events")
+		return
+	}
+
+	logger := h.Logger.WithField("producer", producer)
+	response := Response{Results: make([]EventResult, len(events))}
+	code := http.StatusOK
+	for i, data := range events {
+		response.Results[i] = h.ingest(r.Context(), logger, data)
+		if response.Results[i].Result == resultFailed {
+			code = http.StatusInternalServerError
+		}
+	}
+	responses.RespondWithJSON(w, code, response)
+}
+
+// ingest ingests a single event of a batch.
+func (h *Handler) ingest(ctx context.Context, logger *log.Entry, data []byte) EventResult {
+	result := EventResult{ID: eventID(data)}
+	stored, err := h.Ingester.IngestJSON(ctx, data)
+	switch {
+	case err == nil:
+		result.Result = string(stored)
+	case ingest.Rejected(err):
+		logger.WithField("id", result.ID).Warningf("Rejected event: %s", err)
+		result.Result = resultRejected
+		result.Error = err.Error()
+	default:
+		logger.WithField("id", result.ID).Error(err)
+		result.Result = resultFailed
+		result.Error = http.StatusText(http.StatusInternalServerError)
+	}
+	return result
+}
+
+// eventID returns the ID of an event that may be invalid, or an empty string.
+func eventID(data []byte) string {
+	var event struct {
+		Meta struct {
+			ID string `json:"id"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		return ""
+	}
+	return event.Meta.ID
+}
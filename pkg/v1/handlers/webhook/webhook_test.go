@@ -0,0 +1,140 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+	"github.com/eiffel-community/eiffel-goer/test"
+	"github.com/eiffel-community/eiffel-goer/test/mock_config"
+)
+
+const (
+	storedEvent = `{"meta": {"id": "71000000-0000-4000-8000-000000000001", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650361}, "data": {"name": "build"}, "links": []}`
+	newEvent    = `{"meta": {"id": "71000000-0000-4000-8000-000000000002", "type": "EiffelActivityTriggeredEvent", "version": "4.0.0", "time": 1629449650362}, "data": {"name": "test"}, "links": []}`
+	invalid     = `{"meta": {"id": "71000000-0000-4000-8000-000000000003", "type": "EiffelUnknownEvent", "version": "1.0.0"}}`
+)
+
+// failingWriter fails to store events.
+type failingWriter struct {
+	*test.MemoryDatabase
+}
+
+func (w failingWriter) InsertEvent(context.Context, drivers.EiffelEvent) error {
+	return errors.New("the database is down")
+}
+
+// newHandler returns a handler for the database with storedEvent already stored.
+func newHandler(t *testing.T, db *test.MemoryDatabase, writer drivers.Writer) *Handler {
+	t.Helper()
+	event, err := ingest.Decode([]byte(storedEvent))
+	assert.NoError(t, err)
+	assert.NoError(t, db.InsertEvent(context.Background(), event))
+	logger := &log.Entry{Logger: log.New()}
+	handler := Get(mock_config.NewMockConfig(gomock.NewController(t)), db, logger)
+	handler.Ingester = &ingest.Ingester{Database: db, Writer: writer, Policy: ingest.PolicyIgnoreIdentical, Logger: logger}
+	handler.Secrets = ingest.Secrets{"jenkins": "s3cr3t"}
+	return handler
+}
+
+// Test that the ingest/webhook endpoint authenticates the producer and
+// responds with the results of the events in the batch.
+func TestIngest(t *testing.T) {
+	batch := "[" + storedEvent + "," + newEvent + "," + invalid + "]"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-ingest.MaxClockSkew-time.Minute).Unix(), 10)
+	tests := []struct {
+		name       string
+		producer   string
+		timestamp  string
+		body       string
+		signature  string
+		statusCode int
+		expected   string
+	}{
+		{
+			name: "Batch", producer: "jenkins", timestamp: now, body: batch, signature: ingest.Sign("s3cr3t", now, []byte(batch)), statusCode: http.StatusOK,
+			expected: `{"results":[{"id":"71000000-0000-4000-8000-000000000001","result":"duplicate"},` +
+				`{"id":"71000000-0000-4000-8000-000000000002","result":"stored"},` +
+				`{"id":"71000000-0000-4000-8000-000000000003","result":"rejected","error":"invalid event`,
+		},
+		{name: "Empty", producer: "jenkins", timestamp: now, body: "[]", signature: ingest.Sign("s3cr3t", now, []byte("[]")), statusCode: http.StatusOK, expected: `{"results":[]}`},
+		{name: "WrongSecret", producer: "jenkins", timestamp: now, body: batch, signature: ingest.Sign("guess", now, []byte(batch)), statusCode: http.StatusUnauthorized},
+		{name: "UnknownProducer", producer: "travis", timestamp: now, body: batch, signature: ingest.Sign("s3cr3t", now, []byte(batch)), statusCode: http.StatusUnauthorized},
+		{name: "Replayed", producer: "jenkins", timestamp: stale, body: batch, signature: ingest.Sign("s3cr3t", stale, []byte(batch)), statusCode: http.StatusUnauthorized},
+		{name: "NoTimestamp", producer: "jenkins", body: batch, signature: ingest.Sign("s3cr3t", "", []byte(batch)), statusCode: http.StatusUnauthorized},
+		{name: "NotAnArray", producer: "jenkins", timestamp: now, body: newEvent, signature: ingest.Sign("s3cr3t", now, []byte(newEvent)), statusCode: http.StatusBadRequest},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			db := test.NewMemoryDatabase()
+			handler := newHandler(t, db, db)
+			request := httptest.NewRequest(http.MethodPost, "/v1/ingest/webhook", strings.NewReader(testCase.body))
+			request.Header.Set(ProducerHeader, testCase.producer)
+			request.Header.Set(TimestampHeader, testCase.timestamp)
+			request.Header.Set(SignatureHeader, "sha256="+testCase.signature)
+			responseRecorder := httptest.NewRecorder()
+			handler.Ingest(responseRecorder, request)
+			assert.Equal(t, testCase.statusCode, responseRecorder.Code)
+			assert.Contains(t, responseRecorder.Body.String(), testCase.expected)
+		})
+	}
+}
+
+// Test that the response is an error, still with the results, if an event can't be stored.
+func TestIngestFailure(t *testing.T) {
+	batch := "[" + storedEvent + "," + newEvent + "]"
+	db := test.NewMemoryDatabase()
+	handler := newHandler(t, db, failingWriter{db})
+	request := httptest.NewRequest(http.MethodPost, "/v1/ingest/webhook", strings.NewReader(batch))
+	request.Header.Set(ProducerHeader, "jenkins")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set(TimestampHeader, now)
+	request.Header.Set(SignatureHeader, ingest.Sign("s3cr3t", now, []byte(batch)))
+	responseRecorder := httptest.NewRecorder()
+	handler.Ingest(responseRecorder, request)
+	assert.Equal(t, http.StatusInternalServerError, responseRecorder.Code)
+	assert.Contains(t, responseRecorder.Body.String(), `"result":"duplicate"},{"id":"71000000-0000-4000-8000-000000000002","result":"failed"`)
+}
+
+// Test that batches larger than the maximum size are refused.
+func TestIngestTooLarge(t *testing.T) {
+	db := test.NewMemoryDatabase()
+	handler := newHandler(t, db, db)
+	body := bytes.Repeat([]byte(" "), MaxBodySize+1)
+	request := httptest.NewRequest(http.MethodPost, "/v1/ingest/webhook", bytes.NewReader(body))
+	request.Header.Set(ProducerHeader, "jenkins")
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set(TimestampHeader, now)
+	request.Header.Set(SignatureHeader, ingest.Sign("s3cr3t", now, body))
+	responseRecorder := httptest.NewRecorder()
+	handler.Ingest(responseRecorder, request)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, responseRecorder.Code)
+}
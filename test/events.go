@@ -0,0 +1,44 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package test
+
+import "github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+
+// NewEvent returns an event with the given meta fields, data and links, shaped
+// like events decoded from JSON. The data may be nil.
+func NewEvent(id string, eventType string, time int64, data map[string]interface{}, links ...drivers.Link) drivers.EiffelEvent {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	linkList := make([]interface{}, 0, len(links))
+	for _, link := range links {
+		l := map[string]interface{}{"type": link.Type, "target": link.Target}
+		if link.DomainID != "" {
+			l["domainId"] = link.DomainID
+		}
+		linkList = append(linkList, l)
+	}
+	return drivers.EiffelEvent{
+		"meta": map[string]interface{}{
+			"id":      id,
+			"type":    eventType,
+			"version": "3.0.0",
+			"time":    float64(time),
+		},
+		"data":  data,
+		"links": linkList,
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package test
+
+import (
+	"context"
+	"sync"
+
+	"github.com/eiffel-community/eiffel-goer/internal/ingest"
+)
+
+// MemoryBroker is an ingest.Broker delivering messages published to it in
+// memory, standing in for a message broker when testing consumers.
+type MemoryBroker struct {
+	mu         sync.Mutex
+	pending    [][]byte
+	acked      [][]byte
+	rejected   [][]byte
+	connects   int
+	disconnect chan struct{}
+	// published is signaled when there are new pending messages.
+	published chan struct{}
+}
+
+// NewMemoryBroker returns a MemoryBroker without messages.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{published: make(chan struct{}, 1)}
+}
+
+// Publish a message.
+func (b *MemoryBroker) Publish(body []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, body)
+	b.signal()
+}
+
+// signal that there are pending messages. Must be called with the lock held.
+func (b *MemoryBroker) signal() {
+	select {
+	case b.published <- struct{}{}:
+	default:
+	}
+}
+
+// next pops the next pending message.
+func (b *MemoryBroker) next() ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.pending) == 0 {
+		return nil, false
+	}
+	body := b.pending[0]
+	b.pending = b.pending[1:]
+	return body, true
+}
+
+// requeue puts a message back first in line.
+func (b *MemoryBroker) requeue(body []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append([][]byte{body}, b.pending...)
+	b.signal()
+}
+
+// Consume delivers the pending messages, and those published later, one at a time.
+func (b *MemoryBroker) Consume(ctx context.Context) (<-chan ingest.Delivery, error) {
+	b.mu.Lock()
+	b.connects++
+	disconnect := make(chan struct{})
+	b.disconnect = disconnect
+	b.mu.Unlock()
+
+	deliveries := make(chan ingest.Delivery)
+	go func() {
+		defer close(deliveries)
+		for {
+			body, ok := b.next()
+			if !ok {
+				select {
+				case <-b.published:
+					continue
+				case <-ctx.Done():
+					return
+				case <-disconnect:
+					return
+				}
+			}
+			select {
+			case deliveries <- &memoryDelivery{broker: b, body: body}:
+			case <-ctx.Done():
+				b.requeue(body)
+				return
+			case <-disconnect:
+				b.requeue(body)
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+// Disconnect closes the current connection like a broker going down would.
+func (b *MemoryBroker) Disconnect() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.disconnect != nil {
+		close(b.disconnect)
+		b.disconnect = nil
+	}
+}
+
+// Acked returns the acknowledged messages.
+func (b *MemoryBroker) Acked() [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]byte(nil), b.acked...)
+}
+
+// Rejected returns the rejected, i.e. dead-lettered, messages.
+func (b *MemoryBroker) Rejected() [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([][]byte(nil), b.rejected...)
+}
+
+// Connects returns the number of times Consume has been called.
+func (b *MemoryBroker) Connects() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.connects
+}
+
+// memoryDelivery is a message delivered by a MemoryBroker.
+type memoryDelivery struct {
+	broker *MemoryBroker
+	body   []byte
+}
+
+func (d *memoryDelivery) Body() []byte {
+	return d.body
+}
+
+func (d *memoryDelivery) Ack() error {
+	d.broker.mu.Lock()
+	defer d.broker.mu.Unlock()
+	d.broker.acked = append(d.broker.acked, d.body)
+	return nil
+}
+
+func (d *memoryDelivery) Reject() error {
+	d.broker.mu.Lock()
+	defer d.broker.mu.Unlock()
+	d.broker.rejected = append(d.broker.rejected, d.body)
+	return nil
+}
+
+func (d *memoryDelivery) Requeue() error {
+	d.broker.requeue(d.body)
+	return nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2021 Axis Communications AB.
+//
+// For a full list of individual contributors, please see the commit history.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//	http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/eiffel-community/eiffel-goer/internal/database/drivers"
+	"github.com/eiffel-community/eiffel-goer/internal/query"
+	"github.com/eiffel-community/eiffel-goer/internal/requests"
+)
+
+// MemoryDatabase is a drivers.Database keeping its events in memory, for
+// testing code that makes many or varying database calls, like link
+// traversals, where setting up mock expectations would be impractical.
+type MemoryDatabase struct {
+	Events []drivers.EiffelEvent
+	// Conflicts are the events recorded with InsertConflict.
+	Conflicts []drivers.EiffelEvent
+}
+
+// NewMemoryDatabase returns a MemoryDatabase with the events.
+func NewMemoryDatabase(events ...drivers.EiffelEvent) *MemoryDatabase {
+	return &MemoryDatabase{Events: events}
+}
+
+// GetEvents gets a page of the events matching the request's conditions, in insertion order.
+func (m *MemoryDatabase) GetEvents(_ context.Context, request requests.MultipleEventsRequest) (drivers.EventStream, int64, error) {
+	var matching []drivers.EiffelEvent
+	for _, event := range m.Events {
+		if event.Matches(request.Conditions) {
+			matching = append(matching, event)
+		}
+	}
+	total := int64(len(matching))
+	if request.Count == requests.CountNone {
+		total = -1
+	}
+	if !request.Unpaged {
+		start := int((request.PageNo - 1) * request.PageSize)
+		end := start + int(request.PageSize)
+		if start > len(matching) {
+			start = len(matching)
+		}
+		if end > len(matching) {
+			end = len(matching)
+		}
+		matching = matching[start:end]
+	}
+	return drivers.NewSliceStream(matching), total, nil
+}
+
+// UpstreamDownstreamSearch searches for events upstream and/or downstream of event by ID.
+func (m *MemoryDatabase) UpstreamDownstreamSearch(ctx context.Context, id string, request requests.SearchRequest) (drivers.SearchResult, error) {
+	return drivers.UpstreamDownstreamSearch(ctx, m, id, request)
+}
+
+// GetEventByID gets an event by ID.
+func (m *MemoryDatabase) GetEventByID(_ context.Context, id string) (drivers.EiffelEvent, error) {
+	for _, event := range m.Events {
+		if event.ID() == id {
+			return event, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", drivers.ErrNotFound, id)
+}
+
+// GetEventsByIDs gets the events with any of the IDs, in the order of the IDs.
+func (m *MemoryDatabase) GetEventsByIDs(ctx context.Context, ids []string) ([]drivers.EiffelEvent, error) {
+	var events []drivers.EiffelEvent
+	seen := map[string]struct{}{}
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		if event, err := m.GetEventByID(ctx, id); err == nil {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// GetLinkingEvents gets the events linking to any of the targets with any of the link types.
+func (m *MemoryDatabase) GetLinkingEvents(_ context.Context, targetIDs []string, linkTypes []string) ([]drivers.EiffelEvent, error) {
+	targets := map[string]struct{}{}
+	for _, id := range targetIDs {
+		targets[id] = struct{}{}
+	}
+	types := map[string]struct{}{}
+	for _, linkType := range linkTypes {
+		types[linkType] = struct{}{}
+	}
+	var events []drivers.EiffelEvent
+	for _, event := range m.Events {
+		for _, link := range event.Links() {
+			_, isTarget := targets[link.Target]
+			_, isType := types[link.Type]
+			if isTarget && (len(types) == 0 || isType) {
+				events = append(events, event)
+				break
+			}
+		}
+	}
+	return events, nil
+}
+
+// InsertEvent appends an event.
+func (m *MemoryDatabase) InsertEvent(_ context.Context, event drivers.EiffelEvent) error {
+	m.Events = append(m.Events, event)
+	return nil
+}
+
+// GetStoredEvents gets every copy of the event with the ID, referring to
+// the copies by their index.
+func (m *MemoryDatabase) GetStoredEvents(_ context.Context, id string) ([]drivers.StoredEvent, error) {
+	var stored []drivers.StoredEvent
+	for i, event := range m.Events {
+		if event.ID() == id {
+			stored = append(stored, drivers.StoredEvent{Event: event, Location: "memory", Ref: i})
+		}
+	}
+	return stored, nil
+}
+
+// DeleteStoredEvents deletes copies returned by GetStoredEvents, which
+// are no longer valid after the events have been changed.
+func (m *MemoryDatabase) DeleteStoredEvents(_ context.Context, stored []drivers.StoredEvent) error {
+	deleted := map[int]struct{}{}
+	for _, event := range stored {
+		deleted[event.Ref.(int)] = struct{}{}
+	}
+	var events []drivers.EiffelEvent
+	for i, event := range m.Events {
+		if _, ok := deleted[i]; !ok {
+			events = append(events, event)
+		}
+	}
+	m.Events = events
+	return nil
+}
+
+// DeleteEvents deletes the events with the IDs that match the conditions.
+func (m *MemoryDatabase) DeleteEvents(_ context.Context, ids []string, conditions []query.Condition) (int, error) {
+	deleted := map[string]struct{}{}
+	for _, id := range ids {
+		deleted[id] = struct{}{}
+	}
+	var events []drivers.EiffelEvent
+	for _, event := range m.Events {
+		if _, ok := deleted[event.ID()]; !ok || !event.Matches(conditions) {
+			events = append(events, event)
+		}
+	}
+	count := len(m.Events) - len(events)
+	m.Events = events
+	return count, nil
+}
+
+// InsertConflict records a conflicting event.
+func (m *MemoryDatabase) InsertConflict(_ context.Context, event drivers.EiffelEvent) error {
+	m.Conflicts = append(m.Conflicts, event)
+	return nil
+}
+
+// GetConflicts gets the conflicting events, referring to them by their index.
+func (m *MemoryDatabase) GetConflicts(context.Context) ([]drivers.StoredEvent, error) {
+	var conflicts []drivers.StoredEvent
+	for i, event := range m.Conflicts {
+		conflicts = append(conflicts, drivers.StoredEvent{Event: event, Location: "conflicts", Ref: i})
+	}
+	return conflicts, nil
+}
+
+// Close does nothing.
+func (m *MemoryDatabase) Close(context.Context) error {
+	return nil
+}